@@ -1,9 +1,6 @@
 package dtos
 
-import (
-	sub_model "sheduling-server/models/sub_models"
-	"time"
-)
+import "time"
 
 // DTOS FOR AUTH USER THINGS
 
@@ -24,14 +21,21 @@ type Update_AuthUser_Input struct {
 
 // sends detailed information about the AuthUser (NEVER includes password)
 type GetByID_AuthUser_Output struct {
-	ID          string                `json:"id"`
-	VolunteerID string                `json:"volunteerId"`
-	Username    string                `json:"username"`
-	AccessLevel int                   `json:"accessLevel"`
-	ThirdAuth   *sub_model.OAuthToken `json:"thirdAuth,omitempty"`
-	CreatedAt   time.Time             `json:"createdAt"`
-	LastUpdated time.Time             `json:"lastUpdated"`
-	IsDisabled  bool                  `json:"isDisabled"`
+	ID            string                `json:"id"`
+	VolunteerID   string                `json:"volunteerId"`
+	Username      string                `json:"username"`
+	AccessLevel   int                   `json:"accessLevel"`
+	OAuthAccounts []OAuthAccount_Output `json:"oauthAccounts,omitempty"`
+	CreatedAt     time.Time             `json:"createdAt"`
+	LastUpdated   time.Time             `json:"lastUpdated"`
+	IsDisabled    bool                  `json:"isDisabled"`
+}
+
+// linked OAuth account info (NEVER includes access/refresh tokens)
+type OAuthAccount_Output struct {
+	Provider string    `json:"provider"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linkedAt"`
 }
 
 // sanitized list of all users (no passwords, minimal info)
@@ -10,6 +10,44 @@ type BatchImportPreviewResponse struct {
 	TotalVolunteers  int                 `json:"totalVolunteers"`
 	TotalDepartments int                 `json:"totalDepartments"`
 	SessionID        string              `json:"sessionId"` // For tracking this import session
+	Mode             string              `json:"mode"`
+	DepartmentDiffs  []DepartmentDiff    `json:"departmentDiffs,omitempty"` // merge mode only
+	// Profiles from mapped profile columns, applied to the volunteers the import creates
+	VolunteerProfiles []VolunteerProfilePreview `json:"volunteerProfiles,omitempty"`
+}
+
+// VolunteerProfilePreview is the profile read for a volunteer from the file
+type VolunteerProfilePreview struct {
+	Name      string   `json:"name"`
+	Email     string   `json:"email,omitempty"`
+	Phone     string   `json:"phone,omitempty"`
+	StudentID string   `json:"studentId,omitempty"`
+	YearLevel string   `json:"yearLevel,omitempty"`
+	Section   string   `json:"section,omitempty"`
+	Skills    []string `json:"skills,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	RowIndex  int      `json:"rowIndex"`
+	// Existing volunteer with the same student ID, reused instead of creating one
+	MatchedVolunteer *MatchedName `json:"matchedVolunteer,omitempty"`
+}
+
+// DepartmentDiff shows how a merge import changes a department
+// Names that match one existing volunteer are assumed to be reused
+type DepartmentDiff struct {
+	DepartmentName string         `json:"departmentName"`
+	DepartmentID   string         `json:"departmentId,omitempty"` // empty if the department will be created
+	Added          []MemberChange `json:"added"`
+	Promoted       []MatchedName  `json:"promoted"` // members becoming head
+	Demoted        []MatchedName  `json:"demoted"`  // heads becoming members
+	Missing        []MatchedName  `json:"missing"`  // not in the file, removed only with removeMissing
+	Unchanged      int            `json:"unchanged"`
+}
+
+// MemberChange is a volunteer joining a department
+type MemberChange struct {
+	Name           string `json:"name"`
+	VolunteerID    string `json:"volunteerId,omitempty"` // empty if the volunteer will be created
+	MembershipType string `json:"membershipType"`
 }
 
 // DepartmentPreview shows what will be created for each department
@@ -20,14 +58,34 @@ type DepartmentPreview struct {
 	ColumnIndex    int      `json:"columnIndex"`
 	HeadID         string   `json:"headId,omitempty"`    // Set after conflict resolution
 	MemberIDs      []string `json:"memberIds,omitempty"` // Set after conflict resolution
+	// Rows of the head and members, only set for mapped layouts where they aren't fixed
+	HeadRowIndex     *int  `json:"headRowIndex,omitempty"`
+	MemberRowIndexes []int `json:"memberRowIndexes,omitempty"`
+}
+
+// HeadRow returns the row index of the head, row 2 (index 1) in the column layout
+func (d DepartmentPreview) HeadRow() int {
+	if d.HeadRowIndex != nil {
+		return *d.HeadRowIndex
+	}
+	return 1
+}
+
+// MemberRow returns the row index of a member, members start from row 3 (index 2) in the column layout
+func (d DepartmentPreview) MemberRow(idx int) int {
+	if idx < len(d.MemberRowIndexes) {
+		return d.MemberRowIndexes[idx]
+	}
+	return idx + 2
 }
 
 // VolunteerConflict represents a conflict that needs user resolution
 type VolunteerConflict struct {
-	VolunteerName     string                 `json:"volunteerName"`
-	ConflictType      ConflictType           `json:"conflictType"`                // "DUPLICATE_IN_IMPORT" or "EXISTING_IN_DB"
-	Occurrences       []ConflictOccurrence   `json:"occurrences"`                 // Where this volunteer appears
-	ExistingVolunteer *ExistingVolunteerInfo `json:"existingVolunteer,omitempty"` // If exists in DB
+	VolunteerName     string                  `json:"volunteerName"`
+	ConflictType      ConflictType            `json:"conflictType"`                // "DUPLICATE_IN_IMPORT" or "EXISTING_IN_DB"
+	Occurrences       []ConflictOccurrence    `json:"occurrences"`                 // Where this volunteer appears
+	ExistingVolunteer *ExistingVolunteerInfo  `json:"existingVolunteer,omitempty"` // If exists in DB
+	Candidates        []ExistingVolunteerInfo `json:"candidates,omitempty"`        // If several volunteers in DB share the name or have a similar one
 }
 
 // ConflictType enum
@@ -36,11 +94,15 @@ type ConflictType string
 const (
 	ConflictTypeDuplicateInImport ConflictType = "DUPLICATE_IN_IMPORT" // Same name appears in multiple columns
 	ConflictTypeExistingInDB      ConflictType = "EXISTING_IN_DB"      // Volunteer already exists in database
+	ConflictTypeNotInDB           ConflictType = "NOT_IN_DB"           // Event roster names a volunteer that doesn't exist
+	ConflictTypeAmbiguousInDB     ConflictType = "AMBIGUOUS_IN_DB"     // Event roster name matches several volunteers
+	ConflictTypePossibleMatch     ConflictType = "POSSIBLE_MATCH"      // Name is similar to existing volunteers, see candidates
 )
 
 // ConflictOccurrence shows where a volunteer appears in the import
 type ConflictOccurrence struct {
 	DepartmentName string `json:"departmentName"`
+	EventName      string `json:"eventName,omitempty"`
 	ColumnIndex    int    `json:"columnIndex"`
 	RowIndex       int    `json:"rowIndex"`
 	IsHead         bool   `json:"isHead"`
@@ -51,7 +113,8 @@ type ExistingVolunteerInfo struct {
 	ID               string    `json:"id"`
 	Name             string    `json:"name"`
 	CreatedAt        time.Time `json:"createdAt"`
-	CurrentDeptCount int       `json:"currentDeptCount"` // How many departments they're already in
+	CurrentDeptCount int       `json:"currentDeptCount"`     // How many departments they're already in
+	Confidence       float64   `json:"confidence,omitempty"` // Name similarity from 0 to 1, set on possible matches
 }
 
 // ValidationError represents a validation error in the Excel file
@@ -61,6 +124,7 @@ type ValidationError struct {
 	ColumnIndex    int                 `json:"columnIndex"`
 	RowIndex       int                 `json:"rowIndex,omitempty"`
 	DepartmentName string              `json:"departmentName,omitempty"`
+	EventName      string              `json:"eventName,omitempty"`
 }
 
 // ValidationErrorType enum
@@ -72,12 +136,73 @@ const (
 	ErrorTypeEmptyVolunteerName  ValidationErrorType = "EMPTY_VOLUNTEER_NAME"
 	ErrorTypeDuplicateInColumn   ValidationErrorType = "DUPLICATE_IN_COLUMN"
 	ErrorTypeInvalidFileFormat   ValidationErrorType = "INVALID_FILE_FORMAT"
+	ErrorTypeMissingColumn       ValidationErrorType = "MISSING_COLUMN"
+	ErrorTypeEmptyEventName      ValidationErrorType = "EMPTY_EVENT_NAME"
+	ErrorTypeInvalidDateTime     ValidationErrorType = "INVALID_DATE_TIME"
+	ErrorTypeUnknownDepartment   ValidationErrorType = "UNKNOWN_DEPARTMENT"
+	ErrorTypeDuplicateEvent      ValidationErrorType = "DUPLICATE_EVENT"
+	ErrorTypeMultipleHeads       ValidationErrorType = "MULTIPLE_HEADS"
+	ErrorTypeInvalidEmail        ValidationErrorType = "INVALID_EMAIL"
+	ErrorTypeInvalidPhone        ValidationErrorType = "INVALID_PHONE"
+	ErrorTypeDuplicateStudentID  ValidationErrorType = "DUPLICATE_STUDENT_ID"
+	ErrorTypeConflictingProfile  ValidationErrorType = "CONFLICTING_PROFILE"
+	ErrorTypeDisabledStudentID   ValidationErrorType = "DISABLED_STUDENT_ID"
 )
 
+// EventImportPreviewResponse contains parsed events, roster conflicts, and validation errors
+type EventImportPreviewResponse struct {
+	Events           []EventPreview      `json:"events"`
+	Conflicts        []VolunteerConflict `json:"conflicts"`
+	ValidationErrors []ValidationError   `json:"validationErrors"`
+	TotalEvents      int                 `json:"totalEvents"`
+	TotalVolunteers  int                 `json:"totalVolunteers"`
+	SessionID        string              `json:"sessionId"`
+}
+
+// EventPreview shows what will be created for each event row
+type EventPreview struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	TimeAndDate time.Time     `json:"timeAndDate"`
+	Location    string        `json:"location"`
+	Departments []MatchedName `json:"departments"`
+	Volunteers  []MatchedName `json:"volunteers"`
+	RowIndex    int           `json:"rowIndex"`
+}
+
+// MatchedName is a name from the file and the record it matched, ID is empty if it needs a resolution
+type MatchedName struct {
+	Name string `json:"name"`
+	ID   string `json:"id,omitempty"`
+}
+
+// ColumnMapping tells the preview which columns hold which field, sent as JSON in the "mapping" form field
+// Department imports with a mapping read one volunteer per row instead of one department per column
+type ColumnMapping struct {
+	HeaderRows *int           `json:"headerRows,omitempty"` // rows above the data, defaults to 1
+	Columns    map[string]int `json:"columns"`              // field -> zero-based column index
+}
+
+// BatchImportInspectResponse describes an uploaded file for the sheet and column mapping step
+type BatchImportInspectResponse struct {
+	FileName      string              `json:"fileName"`
+	Format        string              `json:"format"`              // "excel" or "csv"
+	Encoding      string              `json:"encoding,omitempty"`  // detected, text files only
+	Delimiter     string              `json:"delimiter,omitempty"` // detected, text files only
+	Sheets        []string            `json:"sheets,omitempty"`    // workbooks only
+	Sheet         string              `json:"sheet,omitempty"`     // sheet that was read
+	RowCount      int                 `json:"rowCount"`
+	ColumnCount   int                 `json:"columnCount"`
+	SampleRows    [][]string          `json:"sampleRows"`
+	MappingFields map[string][]string `json:"mappingFields"` // import type -> fields a mapping can use
+}
+
 // BatchImportExecuteRequest contains user's conflict resolutions and executes the import
 type BatchImportExecuteRequest struct {
 	SessionID   string               `json:"sessionId" binding:"required"`
 	Resolutions []ConflictResolution `json:"resolutions" binding:"required"`
+	// Merge mode only, removes members of existing departments that aren't in the file
+	RemoveMissing bool `json:"removeMissing"`
 }
 
 // ConflictResolution contains user's decision for a specific volunteer conflict
@@ -94,24 +219,45 @@ const (
 	DecisionCreateOne      ResolutionDecision = "CREATE_ONE"      // Create one volunteer, use in all departments
 	DecisionCreateMultiple ResolutionDecision = "CREATE_MULTIPLE" // Create separate volunteer for each occurrence
 	DecisionReuseExisting  ResolutionDecision = "REUSE_EXISTING"  // Use existing volunteer from DB
+	DecisionSkip           ResolutionDecision = "SKIP"            // Leave the volunteer off the event roster (events only)
 )
 
 // BatchImportExecuteResponse contains the result of the import execution
 type BatchImportExecuteResponse struct {
-	Success              bool     `json:"success"`
-	DepartmentsCreated   int      `json:"departmentsCreated"`
-	VolunteersCreated    int      `json:"volunteersCreated"`
-	VolunteersReused     int      `json:"volunteersReused"`
-	ErrorMessage         string   `json:"errorMessage,omitempty"`
-	CreatedDepartmentIDs []string `json:"createdDepartmentIds,omitempty"`
-	CreatedVolunteerIDs  []string `json:"createdVolunteerIds,omitempty"`
-}
-
-// ImportSession stores the parsed data for a preview session
-type ImportSession struct {
-	SessionID   string
-	Departments []DepartmentPreview
-	RawData     map[string]interface{} // Store any necessary raw data
-	CreatedAt   time.Time
-	ExpiresAt   time.Time
+	Success              bool                 `json:"success"`
+	DepartmentsCreated   int                  `json:"departmentsCreated"`
+	VolunteersCreated    int                  `json:"volunteersCreated"`
+	VolunteersReused     int                  `json:"volunteersReused"`
+	ErrorMessage         string               `json:"errorMessage,omitempty"`
+	CreatedDepartmentIDs []string             `json:"createdDepartmentIds,omitempty"`
+	CreatedVolunteerIDs  []string             `json:"createdVolunteerIds,omitempty"`
+	EventsCreated        int                  `json:"eventsCreated,omitempty"`
+	DepartmentsMerged    int                  `json:"departmentsMerged,omitempty"`
+	MembersAdded         int                  `json:"membersAdded,omitempty"`
+	MembersRemoved       int                  `json:"membersRemoved,omitempty"`
+	MemberTypesChanged   int                  `json:"memberTypesChanged,omitempty"`
+	CreatedEventIDs      []string             `json:"createdEventIds,omitempty"`
+	RolledBack           *BatchImportRollback `json:"rolledBack,omitempty"`
+}
+
+// BatchImportRollback lists records that were soft-deleted after a partial import failure
+type BatchImportRollback struct {
+	VolunteerIDs  []string `json:"volunteerIds"`
+	DepartmentIDs []string `json:"departmentIds"`
+	EventIDs      []string `json:"eventIds,omitempty"`
+	Errors        []string `json:"errors,omitempty"` // records that could not be rolled back
+}
+
+// ImportSessionSummary describes a pending import the admin can resume
+type ImportSessionSummary struct {
+	SessionID        string    `json:"sessionId"`
+	FileName         string    `json:"fileName"`
+	Kind             string    `json:"kind"`
+	Mode             string    `json:"mode,omitempty"`
+	Status           string    `json:"status"`
+	TotalDepartments int       `json:"totalDepartments"`
+	TotalEvents      int       `json:"totalEvents"`
+	TotalVolunteers  int       `json:"totalVolunteers"`
+	CreatedAt        time.Time `json:"createdAt"`
+	ExpiresAt        time.Time `json:"expiresAt"`
 }
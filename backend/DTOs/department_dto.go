@@ -48,3 +48,17 @@ type AddMember_Input struct {
 type UpdateMemberType_Input struct {
 	MembershipType string `json:"membershipType" binding:"required"`
 }
+
+// query parameters for listing departments, all optional
+// without limit or cursor the whole filtered list is returned as before
+type List_Departments_Query struct {
+	Search      string `form:"q" binding:"max=100"`
+	Prefix      string `form:"prefix" binding:"max=100"`
+	VolunteerID string `form:"volunteerId"` // departments the volunteer is a member of
+	Disabled    *bool  `form:"disabled"`
+	CreatedFrom string `form:"createdFrom"`
+	CreatedTo   string `form:"createdTo"`
+	Sort        string `form:"sort"` // name or createdAt, "-" in front for descending
+	Cursor      string `form:"cursor"`
+	Limit       int    `form:"limit" binding:"min=0,max=200"`
+}
@@ -130,3 +130,30 @@ type DepartmentStatusCount struct {
 	Excused      int `json:"excused"`
 	Absent       int `json:"absent"`
 }
+
+// query parameters for listing events, all optional
+// without limit or cursor the whole filtered list is returned as before
+type List_Events_Query struct {
+	Search       string `form:"q" binding:"max=100"`
+	Prefix       string `form:"prefix" binding:"max=100"`
+	DepartmentID string `form:"departmentId"` // events the department is assigned to
+	VolunteerID  string `form:"volunteerId"`  // events the volunteer is scheduled for
+	Disabled     *bool  `form:"disabled"`
+	From         string `form:"from"` // on or after
+	To           string `form:"to"`   // before
+	Sort         string `form:"sort"` // timeAndDate, name or createdAt, "-" in front for descending
+	Cursor       string `form:"cursor"`
+	Limit        int    `form:"limit" binding:"min=0,max=200"`
+}
+
+// message on the live attendance board of an event
+// "snapshot" has every status the viewer may see, "status" one change to a volunteer, "ping" keeps the connection open
+type AttendanceBoard_Message struct {
+	Type        string                     `json:"type"`
+	EventID     string                     `json:"eventId"`
+	Statuses    []sub_model.ScheduleStatus `json:"statuses,omitempty"`
+	VolunteerID string                     `json:"volunteerId,omitempty"`
+	Action      string                     `json:"action,omitempty"` // ADDED, UPDATED or REMOVED
+	Status      *sub_model.ScheduleStatus  `json:"status,omitempty"`
+	At          time.Time                  `json:"at"`
+}
@@ -0,0 +1,29 @@
+package dtos
+
+import "time"
+
+// DTOS FOR INVITES
+
+// for issuing an invite - the invited email creates its account on first OAuth login
+type Create_Invite_Input struct {
+	Email          string `json:"email" binding:"required,email"`
+	VolunteerID    string `json:"volunteerId" binding:"required"`
+	AccessLevel    int    `json:"accessLevel" binding:"required,oneof=1 2"`
+	ExpiresInHours int    `json:"expiresInHours,omitempty" binding:"omitempty,min=1,max=720"` // defaults to 72
+}
+
+// invite details, status is "expired" for pending invites past their expiry
+type Invite_Output struct {
+	ID          string     `json:"id"`
+	Email       string     `json:"email"`
+	VolunteerID string     `json:"volunteerId"`
+	AccessLevel int        `json:"accessLevel"`
+	Status      string     `json:"status"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RedeemedBy  string     `json:"redeemedBy,omitempty"`
+	RedeemedAt  *time.Time `json:"redeemedAt,omitempty"`
+	RevokedBy   string     `json:"revokedBy,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+}
@@ -0,0 +1,9 @@
+package dtos
+
+// one page of a list, pass nextCursor as the cursor parameter to get the next page
+// nextCursor is empty on the last page
+type Page_Output struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Limit      int         `json:"limit"`
+}
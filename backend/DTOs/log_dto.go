@@ -1,6 +1,7 @@
 package dtos
 
 import (
+	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
 	"time"
 )
@@ -8,19 +9,33 @@ import (
 // LogQueryInput represents the query parameters for fetching logs
 type LogQueryInput struct {
 	Limit           int               `form:"limit"`
-	Offset          int               `form:"offset"`
+	Offset          int               `form:"offset"` // ignored when a cursor is given
+	Cursor          string            `form:"cursor"` // nextCursor of the previous page
 	LogType         sub_model.LogType `form:"logType"`
 	UserID          string            `form:"userId"`
-	StartDate       string            `form:"startDate"` // RFC3339 format
-	EndDate         string            `form:"endDate"`   // RFC3339 format
+	StartDate       string            `form:"startDate"` // RFC3339 or YYYY-MM-DD
+	EndDate         string            `form:"endDate"`   // RFC3339 or YYYY-MM-DD
 	VolunteerID     string            `form:"volunteerId"`
 	EventID         string            `form:"eventId"`
 	DepartmentID    string            `form:"departmentId"`
+	TargetUserID    string            `form:"targetUserId"`
 	Category        string            `form:"category"`
 	Severity        string            `form:"severity"`
 	IncludeArchived bool              `form:"includeArchived"`
 }
 
+// LogStreamQuery represents the filters for streaming new logs, the same as for listing them
+type LogStreamQuery struct {
+	LogType      sub_model.LogType `form:"logType"`
+	UserID       string            `form:"userId"`
+	VolunteerID  string            `form:"volunteerId"`
+	EventID      string            `form:"eventId"`
+	DepartmentID string            `form:"departmentId"`
+	TargetUserID string            `form:"targetUserId"`
+	Category     string            `form:"category"`
+	Severity     string            `form:"severity"`
+}
+
 // LogResponse represents a single log entry in responses
 type LogResponse struct {
 	ID           string                 `json:"id"`
@@ -36,8 +51,9 @@ type LogResponse struct {
 
 // LogListResponse represents the response for listing logs
 type LogListResponse struct {
-	Logs  interface{} `json:"logs"`
-	Total int         `json:"total"`
+	Logs       interface{} `json:"logs"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"nextCursor,omitempty"` // empty on the last page
 }
 
 // ArchiveLogsRequest represents the request body for archiving logs
@@ -51,6 +67,53 @@ type ArchiveLogsResponse struct {
 	Message       string `json:"message"`
 }
 
+// PurgeLogsRequest represents the request body for moving archived logs to cold storage
+type PurgeLogsRequest struct {
+	ArchivedBefore string `json:"archivedBefore" binding:"required"` // RFC3339 or YYYY-MM-DD, logs archived before this are purged
+}
+
+// PurgeLogsResponse represents the bundles written by a purge
+type PurgeLogsResponse struct {
+	PurgedCount int                 `json:"purgedCount"`
+	Bundles     []*models.LogBundle `json:"bundles"`
+	Message     string              `json:"message"`
+}
+
+// LogBundleListResponse represents the list of cold storage bundles
+type LogBundleListResponse struct {
+	Bundles []*models.LogBundle `json:"bundles"`
+}
+
+// RestoreLogBundleResponse represents the result of importing a bundle back
+type RestoreLogBundleResponse struct {
+	RestoredCount int               `json:"restoredCount"`
+	Bundle        *models.LogBundle `json:"bundle"`
+	Message       string            `json:"message"`
+}
+
+// CreateRetentionRuleRequest represents the request body for a retention rule
+// Category, type and severity are optional, leaving all of them out sets the default retention
+type CreateRetentionRuleRequest struct {
+	Category      string            `json:"category"`
+	Type          sub_model.LogType `json:"type"`
+	Severity      string            `json:"severity" binding:"omitempty,oneof=INFO WARNING ERROR"`
+	RetentionDays int               `json:"retentionDays" binding:"required,min=1,max=36500"`
+	Description   string            `json:"description" binding:"max=500"`
+}
+
+// UpdateRetentionRuleRequest represents the request body for changing a retention rule
+// What a rule matches can't change, delete it and create a new one instead
+type UpdateRetentionRuleRequest struct {
+	RetentionDays *int    `json:"retentionDays,omitempty" binding:"omitempty,min=1,max=36500"`
+	Description   *string `json:"description,omitempty" binding:"omitempty,max=500"`
+}
+
+// RetentionRuleListResponse represents the retention rules and the default for logs they don't match
+type RetentionRuleListResponse struct {
+	Rules                []*models.RetentionRule `json:"rules"`
+	DefaultRetentionDays int                     `json:"defaultRetentionDays"` // LOG_RETENTION_DAYS, replaced by a rule matching every log
+}
+
 // LogCategoriesResponse represents the list of available log categories
 type LogCategoriesResponse struct {
 	Categories []string `json:"categories"`
@@ -63,5 +126,37 @@ type LogStatsResponse struct {
 	ByCategory map[string]int `json:"byCategory"`
 	BySeverity map[string]int `json:"bySeverity"`
 	RecentLogs int            `json:"recentLogs"` // Last 24 hours
+	Last7Days  int            `json:"last7Days"`
+	Last30Days int            `json:"last30Days"`
 	Archived   int            `json:"archived"`
 }
+
+// LogVerifyQuery represents the query parameters for verifying the log hash chain
+type LogVerifyQuery struct {
+	FromSeq int64 `form:"fromSeq" binding:"min=0"` // first sequence number to check, the whole chain by default
+	Bundles bool  `form:"bundles"`                 // read the bundles of purged logs and check the logs in them too
+}
+
+// LogHistogramQuery represents the query parameters for the log histogram
+type LogHistogramQuery struct {
+	Bucket   string            `form:"bucket" binding:"omitempty,oneof=hour day week"` // day by default
+	From     string            `form:"from"`                                           // RFC3339 or YYYY-MM-DD, defaults by bucket size
+	To       string            `form:"to"`                                             // RFC3339 or YYYY-MM-DD, defaults to now
+	LogType  sub_model.LogType `form:"logType"`
+	Category string            `form:"category"`
+	Severity string            `form:"severity"`
+}
+
+// LogHistogramBucket is the number of logs from Start until the next bucket
+type LogHistogramBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// LogHistogramResponse represents log counts over time for dashboard charts
+type LogHistogramResponse struct {
+	Bucket  string               `json:"bucket"`
+	From    time.Time            `json:"from"`
+	To      time.Time            `json:"to"`
+	Buckets []LogHistogramBucket `json:"buckets"`
+}
@@ -0,0 +1,10 @@
+package dtos
+
+// query parameters for the notification delivery log, newest first, always paged
+type List_Notifications_Query struct {
+	VolunteerID string `form:"volunteerId"`
+	EventID     string `form:"eventId"`
+	Status      string `form:"status" binding:"omitempty,oneof=pending sent failed skipped"`
+	Cursor      string `form:"cursor"`
+	Limit       int    `form:"limit" binding:"min=0,max=200"`
+}
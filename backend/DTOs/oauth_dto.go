@@ -1,6 +1,6 @@
 package dtos
 
-type GoogleOAuthCallbackDTO struct {
+type OAuthCallbackDTO struct {
 	Code  string `json:"code" binding:"required"`
 	State string `json:"state"`
 }
@@ -14,6 +14,13 @@ type OAuthLoginResponse struct {
 	IsNewUser   bool   `json:"isNewUser"` // true if account was just created
 }
 
-type LinkGoogleAccountDTO struct {
-	Code string `json:"code" binding:"required"`
+type LinkOAuthAccountDTO struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state"`
+}
+
+// OAuthProviderInfo describes a login option shown on the login page
+type OAuthProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
 }
@@ -0,0 +1,37 @@
+package dtos
+
+import "time"
+
+// DTOS FOR SERVICE ACCOUNTS AND API KEYS
+
+// for creating a service account
+type Create_ServiceAccount_Input struct {
+	Name        string   `json:"name" binding:"required,min=3,max=50"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes" binding:"required,min=1"`
+}
+
+// for updating a service account, disabling it blocks all of its keys
+type Update_ServiceAccount_Input struct {
+	Name        *string   `json:"name,omitempty" binding:"omitempty,min=3,max=50"`
+	Description *string   `json:"description,omitempty"`
+	Scopes      *[]string `json:"scopes,omitempty" binding:"omitempty,min=1"`
+	IsDisabled  *bool     `json:"isDisabled,omitempty"`
+}
+
+// for creating an API key, scopes must be a subset of the service account's scopes
+type Create_APIKey_Input struct {
+	Name          string   `json:"name" binding:"required,min=3,max=50"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty" binding:"omitempty,min=1,max=365"` // defaults to 90
+}
+
+// returned once when a key is created, the plaintext key can't be retrieved again
+type Create_APIKey_Output struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	Prefix    string    `json:"prefix"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
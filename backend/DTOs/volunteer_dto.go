@@ -1,19 +1,40 @@
 package dtos
 
-import "time"
+import (
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"time"
+)
 
 // DTOS FOR VOLUNTEER THINGS
 
-// for creating a volunteer, pretty simple input
+// for creating a volunteer, only the name is required
 type Create_Volunteer_Input struct {
-	Name string `json:"name" binding:"required,min=2,max=100"`
+	Name      string   `json:"name" binding:"required,min=2,max=100"`
+	Email     string   `json:"email,omitempty" binding:"omitempty,email,max=254"`
+	Phone     string   `json:"phone,omitempty" binding:"omitempty,max=30"`
+	StudentID string   `json:"studentId,omitempty" binding:"omitempty,max=50"`
+	YearLevel string   `json:"yearLevel,omitempty" binding:"omitempty,max=50"`
+	Section   string   `json:"section,omitempty" binding:"omitempty,max=50"`
+	Skills    []string `json:"skills,omitempty" binding:"omitempty,max=30,dive,max=50"`
+	Tags      []string `json:"tags,omitempty" binding:"omitempty,max=30,dive,max=50"`
 }
 
 // input for update request about the volunteer, atleast one value in order to update
 // so we need to handle the possiblity of nullability
+// an empty string or list clears a profile field
 type Update_Volunteer_Input struct {
-	Name       *string `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
-	IsDisabled *bool   `json:"isDisabled,omitempty"`
+	Name       *string   `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
+	IsDisabled *bool     `json:"isDisabled,omitempty"`
+	Email      *string   `json:"email,omitempty" binding:"omitempty,max=254"`
+	Phone      *string   `json:"phone,omitempty" binding:"omitempty,max=30"`
+	StudentID  *string   `json:"studentId,omitempty" binding:"omitempty,max=50"`
+	YearLevel  *string   `json:"yearLevel,omitempty" binding:"omitempty,max=50"`
+	Section    *string   `json:"section,omitempty" binding:"omitempty,max=50"`
+	Skills     *[]string `json:"skills,omitempty" binding:"omitempty,max=30,dive,max=50"`
+	Tags       *[]string `json:"tags,omitempty" binding:"omitempty,max=30,dive,max=50"`
+
+	EmailOptOut *bool `json:"emailOptOut,omitempty"` // stops schedule emails
 }
 
 // sends detailed information about the Volunteer
@@ -23,11 +44,87 @@ type GetByID_Output struct {
 	IsDisabled  bool      `json:"isDisabled"`
 	CreatedAt   time.Time `json:"createdAt"`
 	LastUpdated time.Time `json:"lastUpdated"`
+	Email       string    `json:"email,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	StudentID   string    `json:"studentId,omitempty"`
+	YearLevel   string    `json:"yearLevel,omitempty"`
+	Section     string    `json:"section,omitempty"`
+	Skills      []string  `json:"skills,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	EmailOptOut bool      `json:"emailOptOut,omitempty"`
 }
 
 // just gives simple information about the users, but not full details
+// this is all anonymous callers get, the profile needs a login
 type VolunteerList_Output struct {
 	ID         string `json:"id"`
 	Name       string `json:"name"`
 	IsDisabled bool   `json:"isDisabled"`
 }
+
+// query parameters for listing volunteers, all optional
+// without limit or cursor the whole filtered list is returned as before
+type List_Volunteers_Query struct {
+	Search       string   `form:"q" binding:"max=100"`      // part of the name
+	Prefix       string   `form:"prefix" binding:"max=100"` // start of the name or of a word in it
+	DepartmentID string   `form:"departmentId"`
+	Disabled     *bool    `form:"disabled"`
+	Tags         []string `form:"tag"` // repeat or comma separate, all have to match
+	CreatedFrom  string   `form:"createdFrom"`
+	CreatedTo    string   `form:"createdTo"`
+	Sort         string   `form:"sort"` // name or createdAt, "-" in front for descending
+	Cursor       string   `form:"cursor"`
+	Limit        int      `form:"limit" binding:"min=0,max=200"`
+}
+
+// everything held about a volunteer, for data subject access requests
+type Volunteer_Export_Output struct {
+	ExportedAt  time.Time                     `json:"exportedAt"`
+	Volunteer   GetByID_Output                `json:"volunteer"`
+	Memberships []Volunteer_Membership_Export `json:"memberships"`
+	Events      []Volunteer_Event_Export      `json:"events"`
+	Accounts    []GetByID_AuthUser_Output     `json:"accounts"`
+	Invites     []Invite_Output               `json:"invites"`
+	Logs        []LogResponse                 `json:"logs"`
+	// logs purged to cold storage, read back from their bundles
+	ArchivedLogs []LogResponse `json:"archivedLogs"`
+	// the notifications queued for the volunteer, with the address and text they were sent with
+	Notifications []*models.Notification `json:"notifications"`
+}
+
+// a department the volunteer is a member of
+type Volunteer_Membership_Export struct {
+	DepartmentID   string    `json:"departmentId"`
+	DepartmentName string    `json:"departmentName"`
+	MembershipType string    `json:"membershipType"`
+	JoinedDate     time.Time `json:"joinedDate"`
+	LastUpdated    time.Time `json:"lastUpdated"`
+}
+
+// an event the volunteer is scheduled for or has a status in
+type Volunteer_Event_Export struct {
+	EventID     string                    `json:"eventId"`
+	EventName   string                    `json:"eventName"`
+	TimeAndDate time.Time                 `json:"timeAndDate"`
+	Scheduled   bool                      `json:"scheduled"`
+	Voluntary   bool                      `json:"voluntary"`
+	Status      *sub_model.ScheduleStatus `json:"status,omitempty"`
+}
+
+// confirms a hard delete, it can't be undone
+// the reason is kept on the erasure record after the data is gone, so it is one of a fixed set rather than free text
+type Erase_Volunteer_Input struct {
+	Confirm bool   `json:"confirm" binding:"required"`
+	Reason  string `json:"reason" binding:"omitempty,oneof=subject_request consent_withdrawn retention_expired legal_obligation other"`
+}
+
+// what an erasure removed or pseudonymized
+type Volunteer_Erase_Output struct {
+	VolunteerID              string `json:"volunteerId"`
+	DepartmentsUpdated       int    `json:"departmentsUpdated"`
+	EventsUpdated            int    `json:"eventsUpdated"`
+	InvitesDeleted           int    `json:"invitesDeleted"`
+	LogsPseudonymized        int    `json:"logsPseudonymized"`
+	BundledLogsPseudonymized int    `json:"bundledLogsPseudonymized"` // in cold storage bundles
+	NotificationsDeleted     int    `json:"notificationsDeleted"`
+}
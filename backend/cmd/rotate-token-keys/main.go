@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"sheduling-server/repository"
+	"sheduling-server/repository/firebase"
+	"sheduling-server/utils"
+
+	"github.com/joho/godotenv"
+)
+
+// Re-encrypts stored OAuth tokens under the active TOKEN_ENCRYPTION_KEY_ID
+//
+// To rotate keys:
+//  1. Add the new key to TOKEN_ENCRYPTION_KEYS (keep the old one) and set TOKEN_ENCRYPTION_KEY_ID to it
+//  2. Run this tool
+//  3. Remove the old key from TOKEN_ENCRYPTION_KEYS
+//
+// Records still holding plaintext tokens from before encryption are encrypted as well.
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	fmt.Println("=== CEL Scheduling System - OAuth Token Key Rotation ===")
+	fmt.Println()
+
+	// Load environment variables - try parent directories
+	if err := godotenv.Load("../../.env"); err != nil {
+		if err := godotenv.Load("../.env"); err != nil {
+			if err := godotenv.Load(".env"); err != nil {
+				log.Println("Warning: No .env file found, using system environment variables")
+			}
+		}
+	}
+
+	if err := utils.InitTokenEncryption(); err != nil {
+		log.Fatalf("Failed to load token encryption keys: %v", err)
+	}
+	if utils.ActiveTokenKeyID() == "" {
+		log.Fatal("TOKEN_ENCRYPTION_KEYS must be set to rotate keys")
+	}
+
+	// Initialize database
+	ctx := context.Background()
+	var db repository.Database
+	var err error
+
+	dbType := getEnv("DB_TYPE", "firebase")
+	switch dbType {
+	case "firebase":
+		db, err = firebase.NewFirebaseDB(
+			ctx,
+			getEnv("FIREBASE_CREDENTIALS_PATH", ""),
+			getEnv("FIREBASE_CREDENTIALS_JSON", ""),
+			getEnv("FIREBASE_PROJECT_ID", ""),
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize Firebase: %v", err)
+		}
+	default:
+		log.Fatalf("Unsupported database type: %s", dbType)
+	}
+	defer db.Close()
+
+	users, err := db.AuthUsers().ListUsers(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	rewrapped, encrypted, skipped, failed := 0, 0, 0, 0
+	for _, user := range users {
+		rewrap, plaintext := false, false
+		var rewrapErr error
+		for i := range user.OAuthAccounts {
+			token := &user.OAuthAccounts[i]
+			switch {
+			case token.Secrets != nil:
+				// Only the data key needs re-wrapping, the token ciphertext stays the same
+				changed, err := utils.RewrapSecret(token.Secrets)
+				if err != nil {
+					rewrapErr = err
+				}
+				rewrap = rewrap || changed
+			case token.AccessToken != "" || token.RefreshToken != "":
+				// Legacy plaintext record, UpdateUser encrypts it under the active key
+				plaintext = true
+			}
+		}
+
+		switch {
+		case rewrapErr != nil:
+			log.Printf("  ✗ %s (%s): %v", user.Username, user.ID, rewrapErr)
+			failed++
+			continue
+		case plaintext:
+			encrypted++
+		case rewrap:
+			rewrapped++
+		default:
+			skipped++
+			continue
+		}
+
+		if *dryRun {
+			fmt.Printf("  would update %s (%s)\n", user.Username, user.ID)
+			continue
+		}
+
+		if err := db.AuthUsers().UpdateUser(ctx, user); err != nil {
+			log.Printf("  ✗ %s (%s): %v", user.Username, user.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  ✓ %s (%s)\n", user.Username, user.ID)
+	}
+
+	fmt.Println()
+	if *dryRun {
+		fmt.Println("Dry run, nothing was written.")
+	}
+	fmt.Printf("Active key:          %s\n", utils.ActiveTokenKeyID())
+	fmt.Printf("Re-wrapped:          %d\n", rewrapped)
+	fmt.Printf("Plaintext encrypted: %d\n", encrypted)
+	fmt.Printf("Already current:     %d\n", skipped)
+	fmt.Printf("Failed:              %d\n", failed)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// attendanceBoardPing keeps proxies from closing a quiet board
+const attendanceBoardPing = 25 * time.Second
+
+// attendanceBoardWriteTimeout drops viewers that stopped reading
+const attendanceBoardWriteTimeout = 10 * time.Second
+
+type AttendanceBoardHandler struct {
+	db     repository.Database
+	broker *utils.AttendanceBroker
+}
+
+// NewAttendanceBoardHandler takes the broker the database publishes status changes to
+func NewAttendanceBoardHandler(db repository.Database, broker *utils.AttendanceBroker) *AttendanceBoardHandler {
+	return &AttendanceBoardHandler{db: db, broker: broker}
+}
+
+// Board opens a WebSocket that sends a snapshot of the event's statuses, then every change as it happens
+// GET /api/events/:id/board
+// Admins see every volunteer, department heads only the members of the departments they head when they connect.
+// Changes carry the whole status, clients replace the status with the same volunteer ID
+func (h *AttendanceBoardHandler) Board(c *gin.Context) {
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	visible, code, err := h.visibleVolunteers(c)
+	if err != nil {
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Subscribing before the snapshot, so no change falls between the two
+	sub, err := h.broker.Subscribe(eventID)
+	if errors.Is(err, utils.ErrTooManyBoardViewers) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	event, err := h.db.EventSchedules().GetEventByID(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	snapshot := dtos.AttendanceBoard_Message{
+		Type:     "snapshot",
+		EventID:  eventID,
+		Statuses: []sub_model.ScheduleStatus{},
+		At:       time.Now().UTC(),
+	}
+	for _, status := range event.Statuses {
+		if visible == nil || visible[status.VolunteerID] {
+			snapshot.Statuses = append(snapshot.Statuses, status)
+		}
+	}
+
+	// Access is checked with the bearer token or API key, not cookies, so any origin may connect
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		h.serveBoard(ws, sub, visible, snapshot)
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveBoard sends the snapshot and the changes until the viewer disconnects or falls behind
+func (h *AttendanceBoardHandler) serveBoard(ws *websocket.Conn, sub *utils.AttendanceSubscription, visible map[string]bool, snapshot dtos.AttendanceBoard_Message) {
+	ctx := ws.Request().Context()
+	send := func(message dtos.AttendanceBoard_Message) error {
+		ws.SetWriteDeadline(time.Now().Add(attendanceBoardWriteTimeout))
+		return websocket.JSON.Send(ws, message)
+	}
+	if err := send(snapshot); err != nil {
+		return
+	}
+
+	// The board is read-only, reading only notices when the viewer closes the connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var ignored string
+		for websocket.Message.Receive(ws, &ignored) == nil {
+		}
+	}()
+
+	ping := time.NewTicker(attendanceBoardPing)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case <-ping.C:
+			err = send(dtos.AttendanceBoard_Message{Type: "ping", EventID: snapshot.EventID, At: time.Now().UTC()})
+		case change, ok := <-sub.Changes:
+			if !ok {
+				slog.InfoContext(ctx, "Attendance board viewer fell behind, closing", "eventId", snapshot.EventID)
+				return // the viewer reconnects for a new snapshot
+			}
+			if visible != nil && !visible[change.VolunteerID] {
+				continue
+			}
+			err = send(dtos.AttendanceBoard_Message{
+				Type:        "status",
+				EventID:     change.EventID,
+				VolunteerID: change.VolunteerID,
+				Action:      string(change.Action),
+				Status:      change.Status,
+				At:          change.ChangedAt,
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// visibleVolunteers returns the volunteers a department head may see, nil for admins who see everyone
+func (h *AttendanceBoardHandler) visibleVolunteers(c *gin.Context) (map[string]bool, int, error) {
+	if c.GetInt("accessLevel") == int(models.ADMIN) {
+		return nil, http.StatusOK, nil
+	}
+
+	ctx := c.Request.Context()
+	authUser, err := h.db.AuthUsers().GetUserByID(ctx, c.GetString("userID"))
+	if err != nil {
+		return nil, http.StatusUnauthorized, errors.New("User not found")
+	}
+	departments, err := h.db.Departments().GetUserDepartments(ctx, authUser.VolunteerID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Failed to fetch user departments")
+	}
+	if len(departments) == 0 {
+		return nil, http.StatusForbidden, errors.New("You are not a department head")
+	}
+
+	visible := map[string]bool{}
+	for _, dept := range departments {
+		for _, member := range dept.VolunteerMembers {
+			visible[member.VolunteerID] = true
+		}
+	}
+	return visible, http.StatusOK, nil
+}
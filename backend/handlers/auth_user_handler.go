@@ -26,7 +26,19 @@ func (h *AuthUserHandler) List(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, users)
+
+	// Return sanitized output (no passwords or tokens)
+	output := make([]dtos.AuthUserList_Output, 0, len(users))
+	for _, user := range users {
+		output = append(output, dtos.AuthUserList_Output{
+			ID:          user.ID,
+			Username:    user.Username,
+			VolunteerID: user.VolunteerID,
+			AccessLevel: int(user.AccessLevel),
+			IsDisabled:  user.IsDisabled,
+		})
+	}
+	c.JSON(200, output)
 }
 
 func (h *AuthUserHandler) GetByID(c *gin.Context) {
@@ -36,7 +48,7 @@ func (h *AuthUserHandler) GetByID(c *gin.Context) {
 		c.JSON(404, gin.H{"error": "User not found"})
 		return
 	}
-	c.JSON(200, user)
+	c.JSON(200, toAuthUserOutput(user))
 }
 
 func (h *AuthUserHandler) Create(c *gin.Context) {
@@ -77,17 +89,7 @@ func (h *AuthUserHandler) Create(c *gin.Context) {
 	})
 
 	// Return sanitized output (no password)
-	output := dtos.GetByID_AuthUser_Output{
-		ID:          user.ID,
-		VolunteerID: user.VolunteerID,
-		Username:    user.Username,
-		AccessLevel: int(user.AccessLevel),
-		CreatedAt:   user.CreatedAt,
-		LastUpdated: user.LastUpdated,
-		IsDisabled:  user.IsDisabled,
-	}
-
-	c.JSON(201, output)
+	c.JSON(201, toAuthUserOutput(&user))
 }
 
 func (h *AuthUserHandler) Update(c *gin.Context) {
@@ -183,17 +185,7 @@ func (h *AuthUserHandler) Update(c *gin.Context) {
 	}
 
 	// Return sanitized output
-	output := dtos.GetByID_AuthUser_Output{
-		ID:          user.ID,
-		VolunteerID: user.VolunteerID,
-		Username:    user.Username,
-		AccessLevel: int(user.AccessLevel),
-		CreatedAt:   user.CreatedAt,
-		LastUpdated: user.LastUpdated,
-		IsDisabled:  user.IsDisabled,
-	}
-
-	c.JSON(200, output)
+	c.JSON(200, toAuthUserOutput(user))
 }
 
 // Login handles user authentication
@@ -273,6 +265,11 @@ func (h *AuthUserHandler) GetCurrentUser(c *gin.Context) {
 	}
 
 	// Return sanitized output
+	c.JSON(http.StatusOK, toAuthUserOutput(user))
+}
+
+// toAuthUserOutput converts a user to its sanitized output (no password or OAuth tokens)
+func toAuthUserOutput(user *models.AuthUser) dtos.GetByID_AuthUser_Output {
 	output := dtos.GetByID_AuthUser_Output{
 		ID:          user.ID,
 		VolunteerID: user.VolunteerID,
@@ -283,10 +280,14 @@ func (h *AuthUserHandler) GetCurrentUser(c *gin.Context) {
 		IsDisabled:  user.IsDisabled,
 	}
 
-	// Include ThirdAuth if present
-	if user.ThirdAuth.Provider != "" {
-		output.ThirdAuth = &user.ThirdAuth
+	// Include linked accounts if present
+	for _, account := range user.OAuthAccounts {
+		output.OAuthAccounts = append(output.OAuthAccounts, dtos.OAuthAccount_Output{
+			Provider: string(account.Provider),
+			Email:    account.Email,
+			LinkedAt: account.LinkedAt,
+		})
 	}
 
-	c.JSON(http.StatusOK, output)
+	return output
 }
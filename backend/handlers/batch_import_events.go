@@ -0,0 +1,694 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// Event import columns, found by their header so they can be in any order
+const (
+	eventColumnName        = "name"
+	eventColumnDescription = "description"
+	eventColumnDate        = "date"
+	eventColumnTime        = "time"
+	eventColumnLocation    = "location"
+	eventColumnDepartments = "departments"
+	eventColumnVolunteers  = "volunteers"
+)
+
+// eventMappingFields are the fields a column mapping can assign for event imports
+var eventMappingFields = []string{eventColumnName, eventColumnDescription, eventColumnDate, eventColumnTime, eventColumnLocation, eventColumnDepartments, eventColumnVolunteers}
+
+// eventColumnAliases maps lowercase header text to the column it stands for
+var eventColumnAliases = map[string]string{
+	"name":                 eventColumnName,
+	"event":                eventColumnName,
+	"event name":           eventColumnName,
+	"description":          eventColumnDescription,
+	"details":              eventColumnDescription,
+	"date":                 eventColumnDate,
+	"date/time":            eventColumnDate,
+	"date and time":        eventColumnDate,
+	"time":                 eventColumnTime,
+	"start time":           eventColumnTime,
+	"location":             eventColumnLocation,
+	"venue":                eventColumnLocation,
+	"departments":          eventColumnDepartments,
+	"department":           eventColumnDepartments,
+	"assigned departments": eventColumnDepartments,
+	"volunteers":           eventColumnVolunteers,
+	"scheduled volunteers": eventColumnVolunteers,
+	"roster":               eventColumnVolunteers,
+}
+
+// Formats accepted in the date and time cells
+var (
+	importDateLayouts = []string{"2006-01-02", "1/2/2006", "1/2/06", "01-02-06", "Jan 2, 2006", "January 2, 2006", "2-Jan-06", "2-Jan-2006"}
+	importTimeLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3 PM", "3PM"}
+)
+
+// importLocation is the timezone dates in spreadsheets are written in, set with IMPORT_TIMEZONE
+func importLocation() *time.Location {
+	name := os.Getenv("IMPORT_TIMEZONE")
+	if name == "" {
+		name = "Asia/Manila"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("Unknown IMPORT_TIMEZONE, using UTC", "timezone", name, "error", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// PreviewEventImport parses an events sheet and returns the preview with roster conflicts
+func (h *BatchImportHandler) PreviewEventImport(c *gin.Context) {
+	mapping, err := parseColumnMapping(c, eventMappingFields, []string{eventColumnName, eventColumnDate})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, rows, ok := readUploadedSheet(c)
+	if !ok {
+		return
+	}
+
+	events, validationErrors := parseEventRows(rows, mapping, importLocation())
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusOK, dtos.EventImportPreviewResponse{
+			ValidationErrors: validationErrors,
+			Events:           []dtos.EventPreview{},
+			Conflicts:        []dtos.VolunteerConflict{},
+		})
+		return
+	}
+
+	previews, conflicts, validationErrors, err := h.matchEventNames(c.Request.Context(), events)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect conflicts: " + err.Error()})
+		return
+	}
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusOK, dtos.EventImportPreviewResponse{
+			ValidationErrors: validationErrors,
+			Events:           []dtos.EventPreview{},
+			Conflicts:        []dtos.VolunteerConflict{},
+		})
+		return
+	}
+
+	// Store the session so any instance can resume or execute it
+	session := &models.ImportSession{
+		ID:          uuid.New().String(),
+		CreatedBy:   c.GetString("userID"),
+		FileName:    file.Filename,
+		Kind:        models.ImportKindEvents,
+		Status:      models.ImportSessionPending,
+		Events:      events,
+		CreatedAt:   time.Now().UTC(),
+		ExpiresAt:   time.Now().UTC().Add(importSessionTTL),
+		LastUpdated: time.Now().UTC(),
+	}
+	if err := h.db.ImportSessions().CreateSession(c.Request.Context(), session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save import session: " + err.Error()})
+		return
+	}
+
+	utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_STARTED, sub_model.SEVERITY_INFO, map[string]interface{}{
+		sub_model.META_FILE_NAME:   file.Filename,
+		sub_model.META_FILE_SIZE:   file.Size,
+		sub_model.META_ROW_COUNT:   len(rows),
+		sub_model.META_IMPORT_TYPE: string(models.ImportKindEvents),
+		"sessionId":                session.ID,
+		"totalEvents":              len(events),
+		"totalVolunteers":          countEventVolunteers(events),
+	})
+
+	c.JSON(http.StatusOK, dtos.EventImportPreviewResponse{
+		Events:           previews,
+		Conflicts:        conflicts,
+		ValidationErrors: []dtos.ValidationError{},
+		TotalEvents:      len(events),
+		TotalVolunteers:  countEventVolunteers(events),
+		SessionID:        session.ID,
+	})
+}
+
+// resumeEventImport returns the preview of a stored events session, names are matched again against current data
+func (h *BatchImportHandler) resumeEventImport(c *gin.Context, session *models.ImportSession) {
+	previews, conflicts, validationErrors, err := h.matchEventNames(c.Request.Context(), session.Events)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect conflicts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.EventImportPreviewResponse{
+		Events:           previews,
+		Conflicts:        conflicts,
+		ValidationErrors: validationErrors,
+		TotalEvents:      len(session.Events),
+		TotalVolunteers:  countEventVolunteers(session.Events),
+		SessionID:        session.ID,
+	})
+}
+
+// executeEventImport creates the events of a claimed session, and any roster volunteers the admin chose to create
+func (h *BatchImportHandler) executeEventImport(c *gin.Context, session *models.ImportSession, resolutions map[string]dtos.ConflictResolution) {
+	fail := func(status int, stage string, err error, rollback *dtos.BatchImportRollback) {
+		h.releaseSession(session.ID)
+		utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_FAILED, sub_model.SEVERITY_ERROR, map[string]interface{}{
+			sub_model.META_ERROR_MESSAGE: err.Error(),
+			sub_model.META_IMPORT_TYPE:   string(models.ImportKindEvents),
+			"sessionId":                  session.ID,
+			"stage":                      stage,
+		})
+		c.JSON(status, dtos.BatchImportExecuteResponse{
+			Success:      false,
+			ErrorMessage: err.Error(),
+			RolledBack:   rollback,
+		})
+	}
+
+	lookups, err := h.loadImportLookups(c.Request.Context())
+	if err != nil {
+		fail(http.StatusInternalServerError, "lookup", err, nil)
+		return
+	}
+
+	// Everything is queued first and written together
+	uow := h.db.NewUnitOfWork()
+	volunteerIDs := make(map[string]string) // lowercase name -> ID, empty if skipped
+	createdVolunteerIDs := []string{}
+	volunteersReused := 0
+	eventIDs := []string{}
+
+	for _, row := range session.Events {
+		groups := []string{}
+		for _, deptName := range row.Departments {
+			dept, exists := lookups.departmentsByName[importNameKey(deptName)]
+			if !exists {
+				fail(http.StatusBadRequest, "department_matching", fmt.Errorf("department '%s' no longer exists", deptName), nil)
+				return
+			}
+			groups = append(groups, dept.ID)
+		}
+
+		scheduled := []string{}
+		seen := make(map[string]bool)
+		for _, name := range row.Volunteers {
+			key := importNameKey(name)
+			volunteerID, resolved := volunteerIDs[key]
+			if !resolved {
+				resolution, hasResolution := resolutions[key]
+				volunteerID, err = lookups.resolveRosterName(name, resolution, hasResolution)
+				if err != nil {
+					fail(http.StatusBadRequest, "volunteer_resolution", err, nil)
+					return
+				}
+
+				switch {
+				case hasResolution && resolution.Decision == dtos.DecisionCreateOne:
+					volunteer := &models.VolunteerModel{
+						ID:          uuid.New().String(),
+						Name:        name,
+						CreatedAt:   time.Now().UTC(),
+						LastUpdated: time.Now().UTC(),
+						IsDisabled:  false,
+					}
+					uow.CreateVolunteer(volunteer)
+					volunteerID = volunteer.ID
+					createdVolunteerIDs = append(createdVolunteerIDs, volunteer.ID)
+				case volunteerID != "":
+					volunteersReused++
+				}
+				volunteerIDs[key] = volunteerID
+			}
+
+			if volunteerID == "" || seen[volunteerID] {
+				continue
+			}
+			seen[volunteerID] = true
+			scheduled = append(scheduled, volunteerID)
+		}
+
+		event := &models.EventSchedule{
+			ID:                  uuid.New().String(),
+			Name:                row.Name,
+			Description:         row.Description,
+			TimeAndDate:         row.TimeAndDate.UTC(),
+			ScheduledVolunteers: scheduled,
+			VoluntaryVolunteers: []string{},
+			AssignedGroups:      groups,
+			Statuses:            []sub_model.ScheduleStatus{},
+			IsDisabled:          false,
+			CreateAt:            time.Now().UTC(),
+			LastUpdated:         time.Now().UTC(),
+		}
+		if row.Location != "" {
+			event.Location = &models.EventLocation{Address: row.Location}
+		}
+		uow.CreateEvent(event)
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	// The session is completed with the last batch, so once the import is written it can't be executed again
+	uow.CompleteImportSession(session.ID)
+	result, err := uow.Commit(c.Request.Context())
+	if err != nil {
+		fail(http.StatusInternalServerError, "commit", fmt.Errorf("failed to import: %v", err), &dtos.BatchImportRollback{
+			VolunteerIDs:  result.RolledBackVolunteerIDs,
+			DepartmentIDs: result.RolledBackDepartmentIDs,
+			EventIDs:      result.RolledBackEventIDs,
+			Errors:        result.RollbackErrors,
+		})
+		return
+	}
+
+	// Clean up session
+	if err := h.db.ImportSessions().DeleteSession(c.Request.Context(), session.ID); err != nil {
+		slog.WarnContext(c.Request.Context(), "Failed to delete import session", "sessionId", session.ID, "error", err)
+	}
+
+	utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_COMPLETED, sub_model.SEVERITY_INFO, map[string]interface{}{
+		sub_model.META_SUCCESS_COUNT:      len(createdVolunteerIDs) + len(eventIDs),
+		sub_model.META_IMPORT_TYPE:        string(models.ImportKindEvents),
+		sub_model.META_CREATED_VOLUNTEERS: len(createdVolunteerIDs),
+		sub_model.META_CREATED_EVENTS:     len(eventIDs),
+		"sessionId":                       session.ID,
+		"volunteersReused":                volunteersReused,
+	})
+
+	c.JSON(http.StatusOK, dtos.BatchImportExecuteResponse{
+		Success:             true,
+		EventsCreated:       len(eventIDs),
+		VolunteersCreated:   len(createdVolunteerIDs),
+		VolunteersReused:    volunteersReused,
+		CreatedEventIDs:     eventIDs,
+		CreatedVolunteerIDs: createdVolunteerIDs,
+	})
+}
+
+// parseEventRows parses a sheet with one event per row
+// Without a mapping the columns are found from the header row
+func parseEventRows(rows [][]string, mapping *dtos.ColumnMapping, loc *time.Location) ([]sub_model.ImportEvent, []dtos.ValidationError) {
+	if len(rows) == 0 {
+		return nil, []dtos.ValidationError{{
+			ErrorType: dtos.ErrorTypeInvalidFileFormat,
+			Message:   "Excel file is empty",
+		}}
+	}
+
+	columns := make(map[string]int)
+	firstRow := 1
+	if mapping != nil {
+		columns = mapping.Columns
+		firstRow = *mapping.HeaderRows
+	} else {
+		// Find columns from the header row
+		for colIdx, header := range rows[0] {
+			if column, ok := eventColumnAliases[strings.ToLower(strings.Join(strings.Fields(header), " "))]; ok {
+				if _, taken := columns[column]; !taken {
+					columns[column] = colIdx
+				}
+			}
+		}
+	}
+
+	var validationErrors []dtos.ValidationError
+	for _, required := range []string{eventColumnName, eventColumnDate} {
+		if _, ok := columns[required]; !ok {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType: dtos.ErrorTypeMissingColumn,
+				Message:   fmt.Sprintf("Missing '%s' column in the header row", required),
+			})
+		}
+	}
+	if len(validationErrors) > 0 {
+		return nil, validationErrors
+	}
+
+	cell := func(row []string, column string) string {
+		colIdx, ok := columns[column]
+		if !ok || colIdx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[colIdx])
+	}
+	columnIndex := func(column string) int {
+		if colIdx, ok := columns[column]; ok {
+			return colIdx
+		}
+		return -1
+	}
+
+	var events []sub_model.ImportEvent
+	seenEvents := make(map[string]int) // lowercase name + time -> row
+	for rowIdx := firstRow; rowIdx < len(rows); rowIdx++ {
+		row := rows[rowIdx]
+
+		name := cell(row, eventColumnName)
+		dateCell := cell(row, eventColumnDate)
+		timeCell := cell(row, eventColumnTime)
+		departments := splitImportList(cell(row, eventColumnDepartments))
+		volunteers := splitImportList(cell(row, eventColumnVolunteers))
+
+		// Skip empty rows
+		if name == "" && dateCell == "" && timeCell == "" && len(departments) == 0 && len(volunteers) == 0 {
+			continue
+		}
+
+		if name == "" {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:   dtos.ErrorTypeEmptyEventName,
+				Message:     fmt.Sprintf("Event name is empty in row %d", rowIdx+1),
+				ColumnIndex: columnIndex(eventColumnName),
+				RowIndex:    rowIdx,
+			})
+			continue
+		}
+
+		timeAndDate, err := parseImportDateTime(dateCell, timeCell, loc)
+		if err != nil {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:   dtos.ErrorTypeInvalidDateTime,
+				Message:     fmt.Sprintf("Invalid date/time for '%s' in row %d: %v", name, rowIdx+1, err),
+				ColumnIndex: columnIndex(eventColumnDate),
+				RowIndex:    rowIdx,
+				EventName:   name,
+			})
+			continue
+		}
+
+		eventKey := strings.ToLower(name) + "|" + timeAndDate.Format(time.RFC3339)
+		if seenRow, exists := seenEvents[eventKey]; exists {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:   dtos.ErrorTypeDuplicateEvent,
+				Message:     fmt.Sprintf("Event '%s' at the same time is already in row %d", name, seenRow+1),
+				ColumnIndex: columnIndex(eventColumnName),
+				RowIndex:    rowIdx,
+				EventName:   name,
+			})
+			continue
+		}
+		seenEvents[eventKey] = rowIdx
+
+		// Check for duplicates in the roster
+		seenVolunteers := make(map[string]bool)
+		roster := []string{}
+		for _, volunteer := range volunteers {
+			key := importNameKey(volunteer)
+			if seenVolunteers[key] {
+				validationErrors = append(validationErrors, dtos.ValidationError{
+					ErrorType:   dtos.ErrorTypeDuplicateInColumn,
+					Message:     fmt.Sprintf("Duplicate volunteer '%s' in row %d", volunteer, rowIdx+1),
+					ColumnIndex: columnIndex(eventColumnVolunteers),
+					RowIndex:    rowIdx,
+					EventName:   name,
+				})
+				continue
+			}
+			seenVolunteers[key] = true
+			roster = append(roster, volunteer)
+		}
+
+		events = append(events, sub_model.ImportEvent{
+			Name:              name,
+			Description:       cell(row, eventColumnDescription),
+			TimeAndDate:       timeAndDate,
+			Location:          cell(row, eventColumnLocation),
+			Departments:       departments,
+			Volunteers:        roster,
+			RowIndex:          rowIdx,
+			DepartmentsColumn: columnIndex(eventColumnDepartments),
+			VolunteersColumn:  columnIndex(eventColumnVolunteers),
+		})
+	}
+
+	if len(events) == 0 && len(validationErrors) == 0 {
+		validationErrors = append(validationErrors, dtos.ValidationError{
+			ErrorType: dtos.ErrorTypeInvalidFileFormat,
+			Message:   "No events found in the file",
+		})
+	}
+
+	return events, validationErrors
+}
+
+// parseImportDateTime reads the date and time cells in the import timezone
+// The time can be in its own column or in the date cell, Excel serial numbers are accepted too
+func parseImportDateTime(dateCell, timeCell string, loc *time.Location) (time.Time, error) {
+	if dateCell == "" {
+		return time.Time{}, fmt.Errorf("date is empty")
+	}
+
+	// Unformatted date cells come through as serial numbers
+	if serial, err := strconv.ParseFloat(dateCell, 64); err == nil {
+		parsed, err := excelize.ExcelDateToTime(serial, false)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if timeCell != "" {
+			dateCell = parsed.Format("2006-01-02")
+		} else if parsed.Hour() == 0 && parsed.Minute() == 0 {
+			return time.Time{}, fmt.Errorf("time is missing")
+		} else {
+			dateCell = parsed.Format("2006-01-02 15:04")
+		}
+	}
+	if serial, err := strconv.ParseFloat(timeCell, 64); err == nil && serial < 1 {
+		parsed, err := excelize.ExcelDateToTime(serial, false)
+		if err != nil {
+			return time.Time{}, err
+		}
+		timeCell = parsed.Format("15:04")
+	}
+
+	value := strings.Join(strings.Fields(dateCell+" "+timeCell), " ")
+	if parsed, err := time.ParseInLocation(time.RFC3339, value, loc); err == nil {
+		return parsed.UTC(), nil
+	}
+	for _, dateLayout := range importDateLayouts {
+		for _, timeLayout := range importTimeLayouts {
+			if parsed, err := time.ParseInLocation(dateLayout+" "+timeLayout, value, loc); err == nil {
+				return parsed.UTC(), nil
+			}
+		}
+		if _, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+			return time.Time{}, fmt.Errorf("time is missing")
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized format '%s'", value)
+}
+
+// splitImportList splits a cell listing several names
+// Semicolons and line breaks separate names, commas only when neither is used (names can be "Last, First")
+func splitImportList(value string) []string {
+	separators := ";\n"
+	if !strings.ContainsAny(value, separators) {
+		separators = ","
+	}
+
+	names := []string{}
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool {
+		return strings.ContainsRune(separators, r)
+	}) {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// importNameKey is how names from a file are compared with names in the database
+func importNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// importLookups holds the active volunteers and departments an import is matched against
+type importLookups struct {
+	volunteersByName  map[string][]*models.VolunteerModel
+	volunteersByID    map[string]*models.VolunteerModel
+	departmentsByName map[string]*models.DepartmentModel
+	matcher           *utils.NameMatcher // active volunteers, for similar names
+}
+
+// loadImportLookups loads the active volunteers and departments by name
+func (h *BatchImportHandler) loadImportLookups(ctx context.Context) (*importLookups, error) {
+	volunteers, err := h.db.Volunteers().ListVolunteer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	departments, err := h.db.Departments().ListDepartments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lookups := &importLookups{
+		volunteersByName:  make(map[string][]*models.VolunteerModel),
+		volunteersByID:    make(map[string]*models.VolunteerModel),
+		departmentsByName: make(map[string]*models.DepartmentModel),
+		matcher:           utils.NewNameMatcher(),
+	}
+	for _, vol := range volunteers {
+		if vol.IsDisabled {
+			continue
+		}
+		key := importNameKey(vol.Name)
+		lookups.volunteersByName[key] = append(lookups.volunteersByName[key], vol)
+		lookups.volunteersByID[vol.ID] = vol
+		lookups.matcher.Add(vol.ID, vol.Name)
+	}
+	for _, dept := range departments {
+		if dept.IsDisabled {
+			continue
+		}
+		key := importNameKey(dept.DepartmentName)
+		if _, exists := lookups.departmentsByName[key]; !exists {
+			lookups.departmentsByName[key] = dept
+		}
+	}
+	return lookups, nil
+}
+
+// resolveRosterName returns the volunteer ID for a roster name, empty if it is skipped or will be created
+func (l *importLookups) resolveRosterName(name string, resolution dtos.ConflictResolution, hasResolution bool) (string, error) {
+	if hasResolution {
+		switch resolution.Decision {
+		case dtos.DecisionReuseExisting:
+			if _, exists := l.volunteersByID[resolution.VolunteerID]; !exists {
+				return "", fmt.Errorf("volunteer '%s' chosen for '%s' does not exist", resolution.VolunteerID, name)
+			}
+			return resolution.VolunteerID, nil
+		case dtos.DecisionCreateOne, dtos.DecisionSkip:
+			return "", nil
+		default:
+			return "", fmt.Errorf("decision '%s' is not supported for event imports", resolution.Decision)
+		}
+	}
+
+	matches := l.volunteersByName[importNameKey(name)]
+	switch len(matches) {
+	case 1:
+		return matches[0].ID, nil
+	case 0:
+		return "", fmt.Errorf("no volunteer named '%s', choose to create, skip or reuse one", name)
+	default:
+		return "", fmt.Errorf("several volunteers are named '%s', choose which one to use", name)
+	}
+}
+
+// matchEventNames matches roster and department names against the database
+// Unknown departments are validation errors, unknown or ambiguous volunteers are conflicts to resolve
+func (h *BatchImportHandler) matchEventNames(ctx context.Context, events []sub_model.ImportEvent) ([]dtos.EventPreview, []dtos.VolunteerConflict, []dtos.ValidationError, error) {
+	lookups, err := h.loadImportLookups(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	previews := []dtos.EventPreview{}
+	conflicts := []dtos.VolunteerConflict{}
+	validationErrors := []dtos.ValidationError{}
+	conflictIndex := make(map[string]int) // lowercase name -> index in conflicts
+
+	for _, event := range events {
+		preview := dtos.EventPreview{
+			Name:        event.Name,
+			Description: event.Description,
+			TimeAndDate: event.TimeAndDate,
+			Location:    event.Location,
+			Departments: []dtos.MatchedName{},
+			Volunteers:  []dtos.MatchedName{},
+			RowIndex:    event.RowIndex,
+		}
+
+		for _, deptName := range event.Departments {
+			dept, exists := lookups.departmentsByName[importNameKey(deptName)]
+			if !exists {
+				validationErrors = append(validationErrors, dtos.ValidationError{
+					ErrorType:      dtos.ErrorTypeUnknownDepartment,
+					Message:        fmt.Sprintf("Department '%s' in row %d does not exist", deptName, event.RowIndex+1),
+					ColumnIndex:    event.DepartmentsColumn,
+					RowIndex:       event.RowIndex,
+					DepartmentName: deptName,
+					EventName:      event.Name,
+				})
+				continue
+			}
+			preview.Departments = append(preview.Departments, dtos.MatchedName{Name: deptName, ID: dept.ID})
+		}
+
+		for _, name := range event.Volunteers {
+			key := importNameKey(name)
+			matches := lookups.volunteersByName[key]
+			if len(matches) == 1 {
+				preview.Volunteers = append(preview.Volunteers, dtos.MatchedName{Name: name, ID: matches[0].ID})
+				continue
+			}
+			preview.Volunteers = append(preview.Volunteers, dtos.MatchedName{Name: name})
+
+			occurrence := dtos.ConflictOccurrence{
+				EventName:   event.Name,
+				ColumnIndex: event.VolunteersColumn,
+				RowIndex:    event.RowIndex,
+			}
+			if idx, exists := conflictIndex[key]; exists {
+				conflicts[idx].Occurrences = append(conflicts[idx].Occurrences, occurrence)
+				continue
+			}
+
+			conflict := dtos.VolunteerConflict{
+				VolunteerName: name,
+				ConflictType:  dtos.ConflictTypeNotInDB,
+				Occurrences:   []dtos.ConflictOccurrence{occurrence},
+			}
+			if len(matches) == 0 {
+				if candidates := lookups.matcher.Candidates(name, utils.PossibleMatchThreshold, maxPossibleMatches); len(candidates) > 0 {
+					conflict.ConflictType = dtos.ConflictTypePossibleMatch
+					conflict.Candidates = toCandidateInfo(candidates, lookups.volunteersByID)
+				}
+			} else {
+				conflict.ConflictType = dtos.ConflictTypeAmbiguousInDB
+				for _, vol := range matches {
+					conflict.Candidates = append(conflict.Candidates, dtos.ExistingVolunteerInfo{
+						ID:        vol.ID,
+						Name:      vol.Name,
+						CreatedAt: vol.CreatedAt,
+					})
+				}
+			}
+			conflictIndex[key] = len(conflicts)
+			conflicts = append(conflicts, conflict)
+		}
+
+		previews = append(previews, preview)
+	}
+
+	return previews, conflicts, validationErrors, nil
+}
+
+// countEventVolunteers counts the unique roster names in an events import
+func countEventVolunteers(events []sub_model.ImportEvent) int {
+	volunteerMap := make(map[string]bool)
+	for _, event := range events {
+		for _, name := range event.Volunteers {
+			volunteerMap[importNameKey(name)] = true
+		}
+	}
+	return len(volunteerMap)
+}
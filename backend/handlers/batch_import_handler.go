@@ -3,31 +3,59 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	dtos "sheduling-server/DTOs"
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
 	"sheduling-server/repository"
 	"sheduling-server/utils"
+	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/xuri/excelize/v2"
 )
 
+// Fields a column mapping can assign for department imports
+const (
+	departmentFieldName      = "department"
+	departmentFieldVolunteer = "volunteer"
+	departmentFieldRole      = "role" // optional, cells containing "head" mark the department head
+
+	// Optional volunteer profile columns
+	departmentFieldEmail     = "email"
+	departmentFieldPhone     = "phone"
+	departmentFieldStudentID = "studentId"
+	departmentFieldYearLevel = "yearLevel"
+	departmentFieldSection   = "section"
+	departmentFieldSkills    = "skills"
+	departmentFieldTags      = "tags"
+)
+
+var departmentMappingFields = []string{
+	departmentFieldName, departmentFieldVolunteer, departmentFieldRole,
+	departmentFieldEmail, departmentFieldPhone, departmentFieldStudentID, departmentFieldYearLevel, departmentFieldSection, departmentFieldSkills, departmentFieldTags,
+}
+
+// maxPossibleMatches is how many similar volunteers are suggested for a name
+const maxPossibleMatches = 3
+
+// importSessionTTL is how long a previewed import can be resumed and executed
+const importSessionTTL = 30 * time.Minute
+
+// importClaimLease is how long an executing import keeps its session, longer than an import takes
+// A session whose import crashed can be executed again once it passed
+const importClaimLease = 10 * time.Minute
+
 type BatchImportHandler struct {
-	db       repository.Database
-	sessions map[string]*dtos.ImportSession // In-memory session storage
-	mu       sync.RWMutex
+	db repository.Database
 }
 
 func NewBatchImportHandler(db repository.Database) *BatchImportHandler {
 	handler := &BatchImportHandler{
-		db:       db,
-		sessions: make(map[string]*dtos.ImportSession),
+		db: db,
 	}
 
 	// Start cleanup goroutine for expired sessions
@@ -38,58 +66,49 @@ func NewBatchImportHandler(db repository.Database) *BatchImportHandler {
 
 // PreviewBatchImport parses the Excel file and returns preview with conflicts
 func (h *BatchImportHandler) PreviewBatchImport(c *gin.Context) {
-	// Get the uploaded file
-	file, err := c.FormFile("file")
+	mapping, err := parseColumnMapping(c, departmentMappingFields, []string{departmentFieldName, departmentFieldVolunteer})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
-		return
-	}
-
-	// Check file extension
-	if !strings.HasSuffix(strings.ToLower(file.Filename), ".xlsx") &&
-		!strings.HasSuffix(strings.ToLower(file.Filename), ".xls") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file format. Please upload an Excel file (.xlsx or .xls)"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Open the uploaded file
-	src, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+	mode := models.ImportMode(c.DefaultPostForm("mode", string(models.ImportModeCreate)))
+	if mode != models.ImportModeCreate && mode != models.ImportModeMerge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode, expected 'create' or 'merge'"})
 		return
 	}
-	defer src.Close()
 
-	// Parse Excel file
-	xlsx, err := excelize.OpenReader(src)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse Excel file: " + err.Error()})
+	file, rows, ok := readUploadedSheet(c)
+	if !ok {
 		return
 	}
-	defer xlsx.Close()
 
-	// Get the first sheet
-	sheets := xlsx.GetSheetList()
-	if len(sheets) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Excel file has no sheets"})
-		return
+	// Parse departments from columns, or from rows when the organizer mapped their own layout
+	var departments []dtos.DepartmentPreview
+	var validationErrors []dtos.ValidationError
+	var profiles []sub_model.ImportVolunteerProfile
+	if mapping != nil {
+		departments, validationErrors = parseMappedDepartments(rows, mapping)
+		var profileErrors []dtos.ValidationError
+		profiles, profileErrors = parseVolunteerProfiles(rows, mapping)
+		validationErrors = append(validationErrors, profileErrors...)
+	} else {
+		departments, validationErrors = h.parseColumns(rows)
 	}
-
-	sheetName := sheets[0]
-	rows, err := xlsx.GetRows(sheetName)
+	// Volunteers whose student ID is already on file are that volunteer, however their name is spelled
+	studentMatches, matchErrors, err := h.matchStudentIDs(c.Request.Context(), profiles, studentIDColumn(mapping))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read sheet data"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match student IDs: " + err.Error()})
 		return
 	}
-
-	// Parse departments from columns
-	departments, validationErrors := h.parseColumns(rows)
+	validationErrors = append(validationErrors, matchErrors...)
 	if len(validationErrors) > 0 {
 		// Return validation errors immediately
 		c.JSON(http.StatusOK, dtos.BatchImportPreviewResponse{
 			ValidationErrors: validationErrors,
 			Departments:      []dtos.DepartmentPreview{},
 			Conflicts:        []dtos.VolunteerConflict{},
+			Mode:             string(mode),
 		})
 		return
 	}
@@ -100,28 +119,38 @@ func (h *BatchImportHandler) PreviewBatchImport(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect conflicts: " + err.Error()})
 		return
 	}
+	conflicts = withoutStudentIDMatches(conflicts, studentMatches)
 
-	// Count unique volunteers
-	volunteerMap := make(map[string]bool)
-	for _, dept := range departments {
-		volunteerMap[dept.HeadName] = true
-		for _, member := range dept.Members {
-			volunteerMap[member] = true
+	totalVolunteers := countVolunteers(departments)
+
+	var diffs []dtos.DepartmentDiff
+	if mode == models.ImportModeMerge {
+		diffs, err = h.buildDepartmentDiffs(c.Request.Context(), departments, studentMatches)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare departments: " + err.Error()})
+			return
 		}
 	}
 
-	// Create session
-	sessionID := uuid.New().String()
-	session := &dtos.ImportSession{
-		SessionID:   sessionID,
-		Departments: departments,
+	// Store the session so any instance can resume or execute it
+	session := &models.ImportSession{
+		ID:          uuid.New().String(),
+		CreatedBy:   c.GetString("userID"),
+		FileName:    file.Filename,
+		Kind:        models.ImportKindDepartments,
+		Mode:        mode,
+		Status:      models.ImportSessionPending,
+		Departments: toImportDepartments(departments),
+		Profiles:    profiles,
 		CreatedAt:   time.Now().UTC(),
-		ExpiresAt:   time.Now().UTC().Add(30 * time.Minute), // 30 min expiry
+		ExpiresAt:   time.Now().UTC().Add(importSessionTTL),
+		LastUpdated: time.Now().UTC(),
 	}
-
-	h.mu.Lock()
-	h.sessions[sessionID] = session
-	h.mu.Unlock()
+	if err := h.db.ImportSessions().CreateSession(c.Request.Context(), session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save import session: " + err.Error()})
+		return
+	}
+	sessionID := session.ID
 
 	// Log batch import started
 	utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_STARTED, sub_model.SEVERITY_INFO, map[string]interface{}{
@@ -129,22 +158,133 @@ func (h *BatchImportHandler) PreviewBatchImport(c *gin.Context) {
 		sub_model.META_FILE_SIZE: file.Size,
 		sub_model.META_ROW_COUNT: len(rows),
 		"sessionId":              sessionID,
-		"totalVolunteers":        len(volunteerMap),
+		"mode":                   string(mode),
+		"totalVolunteers":        totalVolunteers,
 		"totalDepartments":       len(departments),
 	})
 
 	response := dtos.BatchImportPreviewResponse{
-		Departments:      departments,
-		Conflicts:        conflicts,
-		ValidationErrors: []dtos.ValidationError{},
-		TotalVolunteers:  len(volunteerMap),
-		TotalDepartments: len(departments),
-		SessionID:        sessionID,
+		Departments:       departments,
+		Conflicts:         conflicts,
+		ValidationErrors:  []dtos.ValidationError{},
+		TotalVolunteers:   totalVolunteers,
+		TotalDepartments:  len(departments),
+		SessionID:         sessionID,
+		Mode:              string(mode),
+		DepartmentDiffs:   diffs,
+		VolunteerProfiles: toProfilePreviews(profiles, studentMatches),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// ListImportSessions returns the current admin's pending imports
+func (h *BatchImportHandler) ListImportSessions(c *gin.Context) {
+	sessions, err := h.db.ImportSessions().ListSessionsByUser(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	output := []dtos.ImportSessionSummary{}
+	for _, session := range sessions {
+		if session.IsExpired(now) || session.Status == models.ImportSessionCompleted {
+			continue
+		}
+		status := session.Status
+		if status == models.ImportSessionExecuting && !session.IsClaimed(now) {
+			status = models.ImportSessionPending // its import crashed, it can be executed again
+		}
+		output = append(output, dtos.ImportSessionSummary{
+			SessionID:        session.ID,
+			FileName:         session.FileName,
+			Kind:             string(session.ImportKindOrDefault()),
+			Mode:             string(session.Mode),
+			Status:           string(status),
+			TotalDepartments: len(session.Departments),
+			TotalEvents:      len(session.Events),
+			TotalVolunteers:  countVolunteers(toDepartmentPreviews(session.Departments)) + countEventVolunteers(session.Events),
+			CreatedAt:        session.CreatedAt,
+			ExpiresAt:        session.ExpiresAt,
+		})
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].CreatedAt.After(output[j].CreatedAt)
+	})
+
+	c.JSON(http.StatusOK, output)
+}
+
+// ResumeImportSession returns the preview of a stored session, conflicts are detected again against current data
+func (h *BatchImportHandler) ResumeImportSession(c *gin.Context) {
+	session, ok := h.getOwnedSession(c, c.Param("sessionId"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired session"})
+		return
+	}
+
+	if session.ImportKindOrDefault() == models.ImportKindEvents {
+		h.resumeEventImport(c, session)
+		return
+	}
+
+	departments := toDepartmentPreviews(session.Departments)
+	conflicts, err := h.detectConflicts(c.Request.Context(), departments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect conflicts: " + err.Error()})
+		return
+	}
+	// A student ID that was disabled since the preview fails on execute, it isn't checked again here
+	studentMatches, _, err := h.matchStudentIDs(c.Request.Context(), session.Profiles, -1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match student IDs: " + err.Error()})
+		return
+	}
+	conflicts = withoutStudentIDMatches(conflicts, studentMatches)
+
+	var diffs []dtos.DepartmentDiff
+	if session.Mode == models.ImportModeMerge {
+		diffs, err = h.buildDepartmentDiffs(c.Request.Context(), departments, studentMatches)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare departments: " + err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, dtos.BatchImportPreviewResponse{
+		Departments:       departments,
+		Conflicts:         conflicts,
+		ValidationErrors:  []dtos.ValidationError{},
+		TotalVolunteers:   countVolunteers(departments),
+		TotalDepartments:  len(departments),
+		SessionID:         session.ID,
+		Mode:              string(session.Mode),
+		DepartmentDiffs:   diffs,
+		VolunteerProfiles: toProfilePreviews(session.Profiles, studentMatches),
+	})
+}
+
+// DeleteImportSession discards a pending import
+func (h *BatchImportHandler) DeleteImportSession(c *gin.Context) {
+	session, ok := h.getOwnedSession(c, c.Param("sessionId"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired session"})
+		return
+	}
+	if session.IsClaimed(time.Now().UTC()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import is currently being executed"})
+		return
+	}
+
+	if err := h.db.ImportSessions().DeleteSession(c.Request.Context(), session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Import session discarded"})
+}
+
 // ExecuteBatchImport executes the import based on user's conflict resolutions
 func (h *BatchImportHandler) ExecuteBatchImport(c *gin.Context) {
 	var request dtos.BatchImportExecuteRequest
@@ -154,60 +294,171 @@ func (h *BatchImportHandler) ExecuteBatchImport(c *gin.Context) {
 	}
 
 	// Retrieve session
-	h.mu.RLock()
-	session, exists := h.sessions[request.SessionID]
-	h.mu.RUnlock()
-
-	if !exists {
+	session, ok := h.getOwnedSession(c, request.SessionID)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired session"})
 		return
 	}
 
+	// Claim the session so a double submit or a second instance can't import it twice
+	// The claim runs out after importClaimLease, so a session whose import crashed can be executed again
+	claimed, err := h.db.ImportSessions().ClaimSession(c.Request.Context(), session.ID, time.Now().UTC(), importClaimLease)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !claimed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import is already being executed"})
+		return
+	}
+
 	// Build resolution map for quick lookup
 	resolutionMap := make(map[string]dtos.ConflictResolution)
 	for _, res := range request.Resolutions {
 		resolutionMap[strings.ToLower(strings.TrimSpace(res.VolunteerName))] = res
 	}
 
-	ctx := c.Request.Context()
+	if session.ImportKindOrDefault() == models.ImportKindEvents {
+		h.executeEventImport(c, session, resolutionMap)
+		return
+	}
+	departmentPreviews := toDepartmentPreviews(session.Departments)
 
-	// Create volunteers first
-	volunteerIDMap, volunteersCreated, volunteersReused, err := h.createVolunteers(ctx, session.Departments, resolutionMap)
+	// Volunteers found by student ID are reused, like in the preview
+	studentMatches, matchErrors, err := h.matchStudentIDs(c.Request.Context(), session.Profiles, -1)
 	if err != nil {
-		// Log batch import failure
+		h.releaseSession(session.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(matchErrors) > 0 {
+		// A matched volunteer was disabled after the preview
+		h.releaseSession(session.ID)
+		c.JSON(http.StatusConflict, gin.H{"error": matchErrors[0].Message})
+		return
+	}
+
+	// In merge mode departments that already exist are updated instead of created
+	toCreate := departmentPreviews
+	var toMerge []departmentMerge
+	defaults := make(map[string]string)
+	if session.Mode == models.ImportModeMerge {
+		lookups, err := h.loadImportLookups(c.Request.Context())
+		if err != nil {
+			h.releaseSession(session.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		toCreate, toMerge = splitForMerge(departmentPreviews, lookups.departmentsByName)
+
+		// Same defaults the preview diff assumed
+		for key, matches := range lookups.volunteersByName {
+			if len(matches) == 1 {
+				defaults[key] = matches[0].ID
+			}
+		}
+	}
+	for key, vol := range studentMatches {
+		defaults[key] = vol.ID
+	}
+
+	// Everything is queued first and written together, so a failure can't leave orphaned volunteers
+	uow := h.db.NewUnitOfWork()
+	volunteerIDMap, volunteerIDs, volunteersReused := h.createVolunteers(uow, departmentPreviews, resolutionMap, defaults, profilesByName(session.Profiles))
+
+	departmentIDs, err := h.createDepartments(uow, toCreate, volunteerIDMap)
+	if err == nil {
+		err = checkMergeMembers(toMerge, volunteerIDMap)
+	}
+	if err != nil {
+		h.releaseSession(session.ID)
+		// Nothing has been written yet
 		utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_FAILED, sub_model.SEVERITY_ERROR, map[string]interface{}{
 			sub_model.META_ERROR_MESSAGE: err.Error(),
 			"sessionId":                  request.SessionID,
-			"stage":                      "volunteer_creation",
+			"stage":                      "department_creation",
 		})
 		c.JSON(http.StatusInternalServerError, dtos.BatchImportExecuteResponse{
 			Success:      false,
-			ErrorMessage: "Failed to create volunteers: " + err.Error(),
+			ErrorMessage: "Failed to create departments: " + err.Error(),
 		})
 		return
 	}
 
-	// Create departments with member references
-	departmentIDs, err := h.createDepartments(ctx, session.Departments, volunteerIDMap)
+	// The session is completed with the last batch, so once the import is written it can't be executed again
+	uow.CompleteImportSession(session.ID)
+	result, err := uow.Commit(c.Request.Context())
 	if err != nil {
-		// Log batch import failure
+		h.releaseSession(session.ID)
+		rollback := &dtos.BatchImportRollback{
+			VolunteerIDs:  result.RolledBackVolunteerIDs,
+			DepartmentIDs: result.RolledBackDepartmentIDs,
+			Errors:        result.RollbackErrors,
+		}
 		utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_FAILED, sub_model.SEVERITY_ERROR, map[string]interface{}{
 			sub_model.META_ERROR_MESSAGE: err.Error(),
 			"sessionId":                  request.SessionID,
-			"stage":                      "department_creation",
-			sub_model.META_SUCCESS_COUNT: volunteersCreated,
+			"stage":                      "commit",
+			"atomic":                     result.Atomic,
+			"chunksCommitted":            result.Chunks,
+			"rolledBackVolunteers":       len(rollback.VolunteerIDs),
+			"rolledBackDepartments":      len(rollback.DepartmentIDs),
+			"rollbackErrors":             rollback.Errors,
 		})
 		c.JSON(http.StatusInternalServerError, dtos.BatchImportExecuteResponse{
 			Success:      false,
-			ErrorMessage: "Failed to create departments: " + err.Error(),
+			ErrorMessage: "Failed to import: " + err.Error(),
+			RolledBack:   rollback,
 		})
 		return
 	}
 
+	// Membership changes on existing departments go through the repository one by one, so they are undone by hand
+	var ops []membershipOp
+	var stats mergeStats
+	for _, merge := range toMerge {
+		headID, memberIDs, _ := departmentMemberIDs(merge.preview, volunteerIDMap)
+		applied, mergeStats, err := h.applyMerge(c.Request.Context(), merge, headID, memberIDs, request.RemoveMissing)
+		ops = append(ops, applied...)
+		stats.added += mergeStats.added
+		stats.removed += mergeStats.removed
+		stats.typesChanged += mergeStats.typesChanged
+		if err == nil {
+			continue
+		}
+
+		h.releaseSession(session.ID)
+		undoErrors := h.undoMembershipOps(ops)
+		undone := uow.Undo()
+		rollback := &dtos.BatchImportRollback{
+			VolunteerIDs:  undone.RolledBackVolunteerIDs,
+			DepartmentIDs: undone.RolledBackDepartmentIDs,
+			Errors:        append(undone.RollbackErrors, undoErrors...),
+		}
+		utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_FAILED, sub_model.SEVERITY_ERROR, map[string]interface{}{
+			sub_model.META_ERROR_MESSAGE:   err.Error(),
+			sub_model.META_DEPARTMENT_ID:   merge.department.ID,
+			sub_model.META_DEPARTMENT_NAME: merge.department.DepartmentName,
+			"sessionId":                    request.SessionID,
+			"stage":                        "department_merge",
+			"membershipChangesUndone":      len(ops),
+			"rolledBackVolunteers":         len(rollback.VolunteerIDs),
+			"rolledBackDepartments":        len(rollback.DepartmentIDs),
+			"rollbackErrors":               rollback.Errors,
+		})
+		c.JSON(http.StatusInternalServerError, dtos.BatchImportExecuteResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("Failed to merge department '%s': %v", merge.department.DepartmentName, err),
+			RolledBack:   rollback,
+		})
+		return
+	}
+	volunteersCreated := len(volunteerIDs)
+
 	// Clean up session
-	h.mu.Lock()
-	delete(h.sessions, request.SessionID)
-	h.mu.Unlock()
+	if err := h.db.ImportSessions().DeleteSession(c.Request.Context(), session.ID); err != nil {
+		slog.WarnContext(c.Request.Context(), "Failed to delete import session", "sessionId", session.ID, "error", err)
+	}
 
 	// Log successful batch import completion
 	utils.CreateEnhancedLog(c, h.db, sub_model.BATCH_IMPORT_COMPLETED, sub_model.SEVERITY_INFO, map[string]interface{}{
@@ -218,6 +469,11 @@ func (h *BatchImportHandler) ExecuteBatchImport(c *gin.Context) {
 		"departmentsCreated":               len(departmentIDs),
 		sub_model.META_CREATED_VOLUNTEERS:  volunteersCreated,
 		sub_model.META_CREATED_DEPARTMENTS: len(departmentIDs),
+		"mode":                             string(session.Mode),
+		"departmentsMerged":                len(toMerge),
+		"membersAdded":                     stats.added,
+		"membersRemoved":                   stats.removed,
+		"memberTypesChanged":               stats.typesChanged,
 	})
 
 	c.JSON(http.StatusOK, dtos.BatchImportExecuteResponse{
@@ -226,6 +482,11 @@ func (h *BatchImportHandler) ExecuteBatchImport(c *gin.Context) {
 		VolunteersCreated:    volunteersCreated,
 		VolunteersReused:     volunteersReused,
 		CreatedDepartmentIDs: departmentIDs,
+		CreatedVolunteerIDs:  volunteerIDs,
+		DepartmentsMerged:    len(toMerge),
+		MembersAdded:         stats.added,
+		MembersRemoved:       stats.removed,
+		MemberTypesChanged:   stats.typesChanged,
 	})
 }
 
@@ -342,6 +603,130 @@ func (h *BatchImportHandler) parseColumns(rows [][]string) ([]dtos.DepartmentPre
 	return departments, validationErrors
 }
 
+// parseMappedDepartments parses rows of (department, volunteer, role) into department previews
+// Without a role column the first volunteer listed for a department is its head
+func parseMappedDepartments(rows [][]string, mapping *dtos.ColumnMapping) ([]dtos.DepartmentPreview, []dtos.ValidationError) {
+	var departments []dtos.DepartmentPreview
+	var validationErrors []dtos.ValidationError
+
+	deptCol := mapping.Columns[departmentFieldName]
+	volunteerCol := mapping.Columns[departmentFieldVolunteer]
+	roleCol, hasRole := mapping.Columns[departmentFieldRole]
+	cell := func(row []string, colIdx int) string {
+		if colIdx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[colIdx])
+	}
+
+	deptIndex := make(map[string]int)               // lowercase department name -> index in departments
+	seenMembers := make(map[string]map[string]bool) // lowercase department name -> lowercase volunteers
+	for rowIdx := *mapping.HeaderRows; rowIdx < len(rows); rowIdx++ {
+		row := rows[rowIdx]
+		deptName := cell(row, deptCol)
+		volunteerName := cell(row, volunteerCol)
+
+		// Skip empty rows
+		if deptName == "" && volunteerName == "" {
+			continue
+		}
+
+		if deptName == "" {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:   dtos.ErrorTypeEmptyDepartmentName,
+				Message:     fmt.Sprintf("Department name is empty in row %d", rowIdx+1),
+				ColumnIndex: deptCol,
+				RowIndex:    rowIdx,
+			})
+			continue
+		}
+		if volunteerName == "" {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:      dtos.ErrorTypeEmptyVolunteerName,
+				Message:        fmt.Sprintf("Volunteer name is empty in row %d", rowIdx+1),
+				ColumnIndex:    volunteerCol,
+				RowIndex:       rowIdx,
+				DepartmentName: deptName,
+			})
+			continue
+		}
+
+		deptKey := strings.ToLower(deptName)
+		idx, exists := deptIndex[deptKey]
+		if !exists {
+			idx = len(departments)
+			deptIndex[deptKey] = idx
+			seenMembers[deptKey] = make(map[string]bool)
+			departments = append(departments, dtos.DepartmentPreview{
+				DepartmentName: deptName,
+				Members:        []string{},
+				ColumnIndex:    deptCol,
+			})
+		}
+		dept := &departments[idx]
+
+		volunteerKey := strings.ToLower(volunteerName)
+		if seenMembers[deptKey][volunteerKey] {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:      dtos.ErrorTypeDuplicateInColumn,
+				Message:        fmt.Sprintf("Duplicate volunteer '%s' in department '%s' (row %d)", volunteerName, dept.DepartmentName, rowIdx+1),
+				ColumnIndex:    volunteerCol,
+				RowIndex:       rowIdx,
+				DepartmentName: dept.DepartmentName,
+			})
+			continue
+		}
+		seenMembers[deptKey][volunteerKey] = true
+
+		isHead := !exists
+		if hasRole {
+			isHead = strings.Contains(strings.ToLower(cell(row, roleCol)), "head")
+		}
+		if isHead && dept.HeadName != "" {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:      dtos.ErrorTypeMultipleHeads,
+				Message:        fmt.Sprintf("Department '%s' already has '%s' as head (row %d)", dept.DepartmentName, dept.HeadName, rowIdx+1),
+				ColumnIndex:    roleCol,
+				RowIndex:       rowIdx,
+				DepartmentName: dept.DepartmentName,
+			})
+			continue
+		}
+
+		if isHead {
+			dept.HeadName = volunteerName
+			headRow := rowIdx
+			dept.HeadRowIndex = &headRow
+		} else {
+			dept.Members = append(dept.Members, volunteerName)
+			dept.MemberRowIndexes = append(dept.MemberRowIndexes, rowIdx)
+		}
+	}
+
+	valid := []dtos.DepartmentPreview{}
+	for _, dept := range departments {
+		if dept.HeadName == "" {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:      dtos.ErrorTypeEmptyHead,
+				Message:        fmt.Sprintf("Department head is empty for '%s'", dept.DepartmentName),
+				ColumnIndex:    deptCol,
+				DepartmentName: dept.DepartmentName,
+			})
+			continue
+		}
+		valid = append(valid, dept)
+	}
+
+	if len(valid) == 0 && len(validationErrors) == 0 {
+		validationErrors = append(validationErrors, dtos.ValidationError{
+			ErrorType: dtos.ErrorTypeInvalidFileFormat,
+			Message:   "No departments found in the file",
+		})
+	}
+
+	return valid, validationErrors
+}
+
 // detectConflicts finds duplicate volunteers and existing volunteers in DB
 func (h *BatchImportHandler) detectConflicts(ctx context.Context, departments []dtos.DepartmentPreview) ([]dtos.VolunteerConflict, error) {
 	// Map: lowercase volunteer name -> occurrences
@@ -354,7 +739,7 @@ func (h *BatchImportHandler) detectConflicts(ctx context.Context, departments []
 		volunteerOccurrences[headKey] = append(volunteerOccurrences[headKey], dtos.ConflictOccurrence{
 			DepartmentName: dept.DepartmentName,
 			ColumnIndex:    dept.ColumnIndex,
-			RowIndex:       1, // Head is always row 2 (index 1)
+			RowIndex:       dept.HeadRow(),
 			IsHead:         true,
 		})
 
@@ -364,7 +749,7 @@ func (h *BatchImportHandler) detectConflicts(ctx context.Context, departments []
 			volunteerOccurrences[memberKey] = append(volunteerOccurrences[memberKey], dtos.ConflictOccurrence{
 				DepartmentName: dept.DepartmentName,
 				ColumnIndex:    dept.ColumnIndex,
-				RowIndex:       idx + 2, // Members start from row 3 (index 2)
+				RowIndex:       dept.MemberRow(idx),
 				IsHead:         false,
 			})
 		}
@@ -376,11 +761,17 @@ func (h *BatchImportHandler) detectConflicts(ctx context.Context, departments []
 		return nil, err
 	}
 
-	// Map existing volunteers by lowercase name
+	// Map existing volunteers by lowercase name, and index active ones for similar names
 	existingVolunteerMap := make(map[string]*models.VolunteerModel)
+	existingByID := make(map[string]*models.VolunteerModel)
+	matcher := utils.NewNameMatcher()
 	for _, vol := range existingVolunteers {
 		key := strings.ToLower(strings.TrimSpace(vol.Name))
 		existingVolunteerMap[key] = vol
+		existingByID[vol.ID] = vol
+		if !vol.IsDisabled {
+			matcher.Add(vol.ID, vol.Name)
+		}
 	}
 
 	// Build conflicts
@@ -423,6 +814,10 @@ func (h *BatchImportHandler) detectConflicts(ctx context.Context, departments []
 				// TODO: Count current departments (would need additional query)
 				CurrentDeptCount: 0,
 			}
+		} else if candidates := matcher.Candidates(originalName, utils.PossibleMatchThreshold, maxPossibleMatches); len(candidates) > 0 {
+			// Similar to existing volunteers, the admin can pick one with REUSE_EXISTING
+			conflict.ConflictType = dtos.ConflictTypePossibleMatch
+			conflict.Candidates = toCandidateInfo(candidates, existingByID)
 		} else if len(occurrences) > 1 {
 			// Duplicate in import
 			conflict.ConflictType = dtos.ConflictTypeDuplicateInImport
@@ -437,10 +832,12 @@ func (h *BatchImportHandler) detectConflicts(ctx context.Context, departments []
 	return conflicts, nil
 }
 
-// createVolunteers creates volunteers based on resolutions
-func (h *BatchImportHandler) createVolunteers(ctx context.Context, departments []dtos.DepartmentPreview, resolutions map[string]dtos.ConflictResolution) (map[string]string, int, int, error) {
+// createVolunteers queues volunteers to be created based on resolutions
+// Names without a resolution reuse the volunteer in defaults if there is one, otherwise they are created
+// Created volunteers get their profile from profiles
+func (h *BatchImportHandler) createVolunteers(uow repository.UnitOfWork, departments []dtos.DepartmentPreview, resolutions map[string]dtos.ConflictResolution, defaults map[string]string, profiles map[string]sub_model.ImportVolunteerProfile) (map[string]string, []string, int) {
 	volunteerIDMap := make(map[string]string) // lowercase name -> ID
-	volunteersCreated := 0
+	createdIDs := []string{}
 	volunteersReused := 0
 
 	// Collect all unique volunteers
@@ -474,16 +871,19 @@ func (h *BatchImportHandler) createVolunteers(ctx context.Context, departments [
 					LastUpdated: time.Now().UTC(),
 					IsDisabled:  false,
 				}
-				if err := h.db.Volunteers().CreateVolunteer(ctx, volunteer); err != nil {
-					return nil, 0, 0, fmt.Errorf("failed to create volunteer '%s': %w", originalName, err)
-				}
+				applyImportProfile(volunteer, profiles[volunteerKey], true)
+				uow.CreateVolunteer(volunteer)
 				volunteerIDMap[volunteerKey] = volunteer.ID
-				volunteersCreated++
+				createdIDs = append(createdIDs, volunteer.ID)
 			case dtos.DecisionCreateMultiple:
 				// For CREATE_MULTIPLE, we'll create separate volunteers with dept suffix
 				// This is handled per-department below
 				continue
 			}
+		} else if existingID, ok := defaults[volunteerKey]; ok {
+			// Merge imports keep using the volunteer with this exact name
+			volunteerIDMap[volunteerKey] = existingID
+			volunteersReused++
 		} else {
 			// No resolution needed (no conflict), create volunteer
 			volunteer := &models.VolunteerModel{
@@ -493,11 +893,10 @@ func (h *BatchImportHandler) createVolunteers(ctx context.Context, departments [
 				LastUpdated: time.Now().UTC(),
 				IsDisabled:  false,
 			}
-			if err := h.db.Volunteers().CreateVolunteer(ctx, volunteer); err != nil {
-				return nil, 0, 0, fmt.Errorf("failed to create volunteer '%s': %w", originalName, err)
-			}
+			applyImportProfile(volunteer, profiles[volunteerKey], true)
+			uow.CreateVolunteer(volunteer)
 			volunteerIDMap[volunteerKey] = volunteer.ID
-			volunteersCreated++
+			createdIDs = append(createdIDs, volunteer.ID)
 		}
 	}
 
@@ -529,35 +928,27 @@ func (h *BatchImportHandler) createVolunteers(ctx context.Context, departments [
 					LastUpdated: time.Now().UTC(),
 					IsDisabled:  false,
 				}
-				if err := h.db.Volunteers().CreateVolunteer(ctx, volunteer); err != nil {
-					return nil, 0, 0, fmt.Errorf("failed to create volunteer '%s': %w", volunteer.Name, err)
-				}
+				applyImportProfile(volunteer, profiles[volunteerKey], false)
+				uow.CreateVolunteer(volunteer)
 				// Store with composite key
 				compositeKey := fmt.Sprintf("%s|%s", volunteerKey, dept.DepartmentName)
 				volunteerIDMap[compositeKey] = volunteer.ID
-				volunteersCreated++
+				createdIDs = append(createdIDs, volunteer.ID)
 			}
 		}
 	}
 
-	return volunteerIDMap, volunteersCreated, volunteersReused, nil
+	return volunteerIDMap, createdIDs, volunteersReused
 }
 
-// createDepartments creates departments with volunteer members
-func (h *BatchImportHandler) createDepartments(ctx context.Context, departments []dtos.DepartmentPreview, volunteerIDMap map[string]string) ([]string, error) {
+// createDepartments queues departments with volunteer members to be created
+func (h *BatchImportHandler) createDepartments(uow repository.UnitOfWork, departments []dtos.DepartmentPreview, volunteerIDMap map[string]string) ([]string, error) {
 	var departmentIDs []string
 
 	for _, deptPreview := range departments {
-		// Get head ID
-		headKey := strings.ToLower(strings.TrimSpace(deptPreview.HeadName))
-		compositeHeadKey := fmt.Sprintf("%s|%s", headKey, deptPreview.DepartmentName)
-
-		headID, exists := volunteerIDMap[compositeHeadKey]
-		if !exists {
-			headID, exists = volunteerIDMap[headKey]
-		}
-		if !exists {
-			return nil, fmt.Errorf("head ID not found for '%s'", deptPreview.HeadName)
+		headID, memberIDs, err := departmentMemberIDs(deptPreview, volunteerIDMap)
+		if err != nil {
+			return nil, err
 		}
 
 		// Build member list
@@ -569,20 +960,7 @@ func (h *BatchImportHandler) createDepartments(ctx context.Context, departments
 				LastUpdated:    time.Now().UTC(),
 			},
 		}
-
-		// Add regular members
-		for _, memberName := range deptPreview.Members {
-			memberKey := strings.ToLower(strings.TrimSpace(memberName))
-			compositeMemberKey := fmt.Sprintf("%s|%s", memberKey, deptPreview.DepartmentName)
-
-			memberID, exists := volunteerIDMap[compositeMemberKey]
-			if !exists {
-				memberID, exists = volunteerIDMap[memberKey]
-			}
-			if !exists {
-				return nil, fmt.Errorf("member ID not found for '%s'", memberName)
-			}
-
+		for _, memberID := range memberIDs {
 			members = append(members, sub_model.MembershipInfo{
 				VolunteerID:    memberID,
 				JoinedDate:     time.Now().UTC(),
@@ -601,29 +979,139 @@ func (h *BatchImportHandler) createDepartments(ctx context.Context, departments
 			IsDisabled:       false,
 		}
 
-		if err := h.db.Departments().CreateDepartment(ctx, department); err != nil {
-			return nil, fmt.Errorf("failed to create department '%s': %w", deptPreview.DepartmentName, err)
-		}
-
+		uow.CreateDepartment(department)
 		departmentIDs = append(departmentIDs, department.ID)
 	}
 
 	return departmentIDs, nil
 }
 
+// departmentMemberIDs looks up the volunteer IDs of a department's head and members
+func departmentMemberIDs(deptPreview dtos.DepartmentPreview, volunteerIDMap map[string]string) (string, []string, error) {
+	// Get head ID
+	headKey := strings.ToLower(strings.TrimSpace(deptPreview.HeadName))
+	compositeHeadKey := fmt.Sprintf("%s|%s", headKey, deptPreview.DepartmentName)
+
+	headID, exists := volunteerIDMap[compositeHeadKey]
+	if !exists {
+		headID, exists = volunteerIDMap[headKey]
+	}
+	if !exists {
+		return "", nil, fmt.Errorf("head ID not found for '%s'", deptPreview.HeadName)
+	}
+
+	// Regular members
+	memberIDs := []string{}
+	for _, memberName := range deptPreview.Members {
+		memberKey := strings.ToLower(strings.TrimSpace(memberName))
+		compositeMemberKey := fmt.Sprintf("%s|%s", memberKey, deptPreview.DepartmentName)
+
+		memberID, exists := volunteerIDMap[compositeMemberKey]
+		if !exists {
+			memberID, exists = volunteerIDMap[memberKey]
+		}
+		if !exists {
+			return "", nil, fmt.Errorf("member ID not found for '%s'", memberName)
+		}
+		memberIDs = append(memberIDs, memberID)
+	}
+
+	return headID, memberIDs, nil
+}
+
 // cleanupExpiredSessions periodically removes expired sessions
 func (h *BatchImportHandler) cleanupExpiredSessions() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		now := time.Now().UTC()
-		h.mu.Lock()
-		for sessionID, session := range h.sessions {
-			if now.After(session.ExpiresAt) {
-				delete(h.sessions, sessionID)
-			}
+		deleted, err := h.db.ImportSessions().DeleteExpiredSessions(context.Background(), time.Now().UTC())
+		if err != nil {
+			slog.Warn("Failed to clean up expired import sessions", "error", err)
+			continue
+		}
+		if deleted > 0 {
+			slog.Info("Cleaned up expired import sessions", "deleted", deleted)
 		}
-		h.mu.Unlock()
 	}
 }
+
+// getOwnedSession loads a session that belongs to the current user and hasn't expired
+func (h *BatchImportHandler) getOwnedSession(c *gin.Context, sessionID string) (*models.ImportSession, bool) {
+	session, err := h.db.ImportSessions().GetSession(c.Request.Context(), sessionID)
+	if err != nil {
+		return nil, false
+	}
+	if session.CreatedBy != c.GetString("userID") || session.IsExpired(time.Now().UTC()) || session.Status == models.ImportSessionCompleted {
+		return nil, false
+	}
+	return session, true
+}
+
+// releaseSession puts a session back to pending so the admin can retry
+func (h *BatchImportHandler) releaseSession(sessionID string) {
+	if err := h.db.ImportSessions().ReleaseSession(context.Background(), sessionID); err != nil {
+		slog.Warn("Failed to release import session", "sessionId", sessionID, "error", err)
+	}
+}
+
+// toCandidateInfo converts name candidates to the volunteer info shown to the admin
+func toCandidateInfo(candidates []utils.NameCandidate, volunteersByID map[string]*models.VolunteerModel) []dtos.ExistingVolunteerInfo {
+	result := make([]dtos.ExistingVolunteerInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		info := dtos.ExistingVolunteerInfo{
+			ID:         candidate.ID,
+			Name:       candidate.Name,
+			Confidence: candidate.Confidence,
+		}
+		if vol, ok := volunteersByID[candidate.ID]; ok {
+			info.CreatedAt = vol.CreatedAt
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
+// countVolunteers counts the unique volunteer names in an import
+func countVolunteers(departments []dtos.DepartmentPreview) int {
+	volunteerMap := make(map[string]bool)
+	for _, dept := range departments {
+		volunteerMap[dept.HeadName] = true
+		for _, member := range dept.Members {
+			volunteerMap[member] = true
+		}
+	}
+	return len(volunteerMap)
+}
+
+// toImportDepartments converts parsed departments to the stored session format
+func toImportDepartments(departments []dtos.DepartmentPreview) []sub_model.ImportDepartment {
+	result := make([]sub_model.ImportDepartment, 0, len(departments))
+	for _, dept := range departments {
+		result = append(result, sub_model.ImportDepartment{
+			DepartmentName:   dept.DepartmentName,
+			HeadName:         dept.HeadName,
+			Members:          dept.Members,
+			ColumnIndex:      dept.ColumnIndex,
+			HeadRowIndex:     dept.HeadRowIndex,
+			MemberRowIndexes: dept.MemberRowIndexes,
+		})
+	}
+	return result
+}
+
+// toDepartmentPreviews converts stored session departments back to previews
+func toDepartmentPreviews(departments []sub_model.ImportDepartment) []dtos.DepartmentPreview {
+	result := make([]dtos.DepartmentPreview, 0, len(departments))
+	for _, dept := range departments {
+		result = append(result, dtos.DepartmentPreview{
+			DepartmentName:   dept.DepartmentName,
+			HeadName:         dept.HeadName,
+			Members:          dept.Members,
+			ColumnIndex:      dept.ColumnIndex,
+			HeadRowIndex:     dept.HeadRowIndex,
+			MemberRowIndexes: dept.MemberRowIndexes,
+		})
+	}
+	return result
+}
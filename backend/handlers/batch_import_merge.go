@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"strings"
+	"time"
+)
+
+// departmentMerge is an imported department that matched an existing one
+type departmentMerge struct {
+	preview    dtos.DepartmentPreview
+	department *models.DepartmentModel
+}
+
+// splitForMerge separates imported departments into new ones and ones that match an existing department by name
+func splitForMerge(previews []dtos.DepartmentPreview, departmentsByName map[string]*models.DepartmentModel) ([]dtos.DepartmentPreview, []departmentMerge) {
+	create := []dtos.DepartmentPreview{}
+	merge := []departmentMerge{}
+	for _, preview := range previews {
+		if dept, exists := departmentsByName[importNameKey(preview.DepartmentName)]; exists {
+			merge = append(merge, departmentMerge{preview: preview, department: dept})
+			continue
+		}
+		create = append(create, preview)
+	}
+	return create, merge
+}
+
+// checkMergeMembers makes sure every merged department's members have an ID before anything is written
+func checkMergeMembers(merges []departmentMerge, volunteerIDMap map[string]string) error {
+	for _, merge := range merges {
+		if _, _, err := departmentMemberIDs(merge.preview, volunteerIDMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// membershipDiff is what has to change for a department to match the file
+type membershipDiff struct {
+	added     []sub_model.MembershipInfo
+	promoted  []string // to HEAD
+	demoted   []string // to MEMBER
+	missing   []string // not in the file
+	unchanged int
+}
+
+// diffMembership compares current members with the head and members from the file
+// Heads that aren't the file's head are demoted, members missing from the file are only listed
+func diffMembership(current []sub_model.MembershipInfo, headID string, memberIDs []string) membershipDiff {
+	var diff membershipDiff
+
+	wanted := map[string]string{headID: string(sub_model.HEAD)}
+	for _, id := range memberIDs {
+		if _, exists := wanted[id]; !exists {
+			wanted[id] = string(sub_model.MEMBER)
+		}
+	}
+
+	existing := make(map[string]bool)
+	for _, member := range current {
+		existing[member.VolunteerID] = true
+		wantedType, inFile := wanted[member.VolunteerID]
+
+		switch {
+		case !inFile:
+			diff.missing = append(diff.missing, member.VolunteerID)
+			if member.MembershipType == string(sub_model.HEAD) {
+				diff.demoted = append(diff.demoted, member.VolunteerID)
+			}
+		case wantedType == member.MembershipType:
+			diff.unchanged++
+		case wantedType == string(sub_model.HEAD):
+			diff.promoted = append(diff.promoted, member.VolunteerID)
+		default:
+			diff.demoted = append(diff.demoted, member.VolunteerID)
+		}
+	}
+
+	// Keep the file's order for new members, head first
+	for _, id := range append([]string{headID}, memberIDs...) {
+		if existing[id] {
+			continue
+		}
+		existing[id] = true
+		diff.added = append(diff.added, sub_model.MembershipInfo{
+			VolunteerID:    id,
+			JoinedDate:     time.Now().UTC(),
+			MembershipType: wanted[id],
+			LastUpdated:    time.Now().UTC(),
+		})
+	}
+
+	return diff
+}
+
+// buildDepartmentDiffs previews a merge import, names matching one active volunteer are assumed to be reused
+// Volunteers found by student ID are reused whatever their name
+func (h *BatchImportHandler) buildDepartmentDiffs(ctx context.Context, previews []dtos.DepartmentPreview, studentMatches map[string]*models.VolunteerModel) ([]dtos.DepartmentDiff, error) {
+	lookups, err := h.loadImportLookups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Volunteers that will be created get a placeholder ID so they show up as added
+	const newVolunteerPrefix = "new:"
+	nameOf := func(id string) string {
+		if vol, ok := lookups.volunteersByID[id]; ok {
+			return vol.Name
+		}
+		return id
+	}
+	idOf := func(name string) string {
+		if vol, ok := studentMatches[importNameKey(name)]; ok {
+			return vol.ID
+		}
+		if matches := lookups.volunteersByName[importNameKey(name)]; len(matches) == 1 {
+			return matches[0].ID
+		}
+		return newVolunteerPrefix + name
+	}
+	toNames := func(ids []string) []dtos.MatchedName {
+		names := []dtos.MatchedName{}
+		for _, id := range ids {
+			names = append(names, dtos.MatchedName{Name: nameOf(id), ID: id})
+		}
+		return names
+	}
+
+	diffs := []dtos.DepartmentDiff{}
+	for _, preview := range previews {
+		memberIDs := []string{}
+		for _, member := range preview.Members {
+			memberIDs = append(memberIDs, idOf(member))
+		}
+
+		output := dtos.DepartmentDiff{DepartmentName: preview.DepartmentName}
+		var current []sub_model.MembershipInfo
+		if dept, exists := lookups.departmentsByName[importNameKey(preview.DepartmentName)]; exists {
+			output.DepartmentID = dept.ID
+			current = dept.VolunteerMembers
+		}
+
+		diff := diffMembership(current, idOf(preview.HeadName), memberIDs)
+		output.Added = []dtos.MemberChange{}
+		for _, added := range diff.added {
+			change := dtos.MemberChange{MembershipType: added.MembershipType}
+			if name, isNew := strings.CutPrefix(added.VolunteerID, newVolunteerPrefix); isNew {
+				change.Name = name
+			} else {
+				change.Name = nameOf(added.VolunteerID)
+				change.VolunteerID = added.VolunteerID
+			}
+			output.Added = append(output.Added, change)
+		}
+		output.Promoted = toNames(diff.promoted)
+		output.Demoted = toNames(diff.demoted)
+		output.Missing = toNames(diff.missing)
+		output.Unchanged = diff.unchanged
+
+		diffs = append(diffs, output)
+	}
+
+	return diffs, nil
+}
+
+// membershipOp is a membership change that was applied, kept so it can be undone
+type membershipOp struct {
+	departmentID string
+	volunteerID  string
+	kind         string // "add", "type" or "remove"
+	previousType string
+	previous     sub_model.MembershipInfo
+}
+
+// mergeStats counts what a merge changed
+type mergeStats struct {
+	added, removed, typesChanged int
+}
+
+// applyMerge updates an existing department's membership to match the file
+// Returns the applied changes so the caller can undo them if a later department fails
+func (h *BatchImportHandler) applyMerge(ctx context.Context, merge departmentMerge, headID string, memberIDs []string, removeMissing bool) ([]membershipOp, mergeStats, error) {
+	var ops []membershipOp
+	var stats mergeStats
+	deptID := merge.department.ID
+
+	// Read again, the department may have changed since the session was previewed
+	dept, err := h.db.Departments().GetByID(ctx, deptID)
+	if err != nil {
+		return ops, stats, err
+	}
+	currentMembers := make(map[string]sub_model.MembershipInfo)
+	for _, member := range dept.VolunteerMembers {
+		currentMembers[member.VolunteerID] = member
+	}
+
+	diff := diffMembership(dept.VolunteerMembers, headID, memberIDs)
+
+	missing := make(map[string]bool)
+	if removeMissing {
+		for _, id := range diff.missing {
+			missing[id] = true
+		}
+	}
+
+	for _, id := range diff.promoted {
+		if err := h.db.Departments().UpdateMemberType(ctx, deptID, id, string(sub_model.HEAD)); err != nil {
+			return ops, stats, err
+		}
+		ops = append(ops, membershipOp{departmentID: deptID, volunteerID: id, kind: "type", previousType: currentMembers[id].MembershipType})
+		stats.typesChanged++
+	}
+	for _, id := range diff.demoted {
+		if missing[id] {
+			continue // removed below
+		}
+		if err := h.db.Departments().UpdateMemberType(ctx, deptID, id, string(sub_model.MEMBER)); err != nil {
+			return ops, stats, err
+		}
+		ops = append(ops, membershipOp{departmentID: deptID, volunteerID: id, kind: "type", previousType: currentMembers[id].MembershipType})
+		stats.typesChanged++
+	}
+	for i := range diff.added {
+		member := diff.added[i]
+		if err := h.db.Departments().AddMemberToDepartment(ctx, deptID, &member); err != nil {
+			return ops, stats, err
+		}
+		ops = append(ops, membershipOp{departmentID: deptID, volunteerID: member.VolunteerID, kind: "add"})
+		stats.added++
+	}
+	for _, id := range diff.missing {
+		if !missing[id] {
+			continue
+		}
+		if err := h.db.Departments().RemoveMemberFromDepartment(ctx, deptID, id); err != nil {
+			return ops, stats, err
+		}
+		ops = append(ops, membershipOp{departmentID: deptID, volunteerID: id, kind: "remove", previous: currentMembers[id]})
+		stats.removed++
+	}
+
+	return ops, stats, nil
+}
+
+// undoMembershipOps reverts applied membership changes, newest first, and returns what couldn't be reverted
+func (h *BatchImportHandler) undoMembershipOps(ops []membershipOp) []string {
+	// The request context may already be cancelled, the undo has to run anyway
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var errs []string
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		var err error
+		switch op.kind {
+		case "add":
+			err = h.db.Departments().RemoveMemberFromDepartment(ctx, op.departmentID, op.volunteerID)
+		case "type":
+			err = h.db.Departments().UpdateMemberType(ctx, op.departmentID, op.volunteerID, op.previousType)
+		case "remove":
+			previous := op.previous
+			err = h.db.Departments().AddMemberToDepartment(ctx, op.departmentID, &previous)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("departments/%s member %s: %v", op.departmentID, op.volunteerID, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/utils"
+	"strings"
+)
+
+// parseVolunteerProfiles reads the profile columns of a mapped department import
+// A volunteer listed in several rows gets one profile, the rows may fill different fields but can't disagree
+func parseVolunteerProfiles(rows [][]string, mapping *dtos.ColumnMapping) ([]sub_model.ImportVolunteerProfile, []dtos.ValidationError) {
+	var profiles []sub_model.ImportVolunteerProfile
+	var validationErrors []dtos.ValidationError
+
+	columns := make(map[string]int)
+	for _, field := range []string{departmentFieldEmail, departmentFieldPhone, departmentFieldStudentID, departmentFieldYearLevel, departmentFieldSection, departmentFieldSkills, departmentFieldTags} {
+		if colIdx, ok := mapping.Columns[field]; ok {
+			columns[field] = colIdx
+		}
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	volunteerCol := mapping.Columns[departmentFieldVolunteer]
+	cell := func(row []string, field string) string {
+		colIdx, ok := columns[field]
+		if !ok || colIdx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[colIdx])
+	}
+
+	profileIndex := make(map[string]int)    // name key -> index in profiles
+	studentIDOwners := make(map[string]int) // normalized student ID -> index in profiles
+	for rowIdx := *mapping.HeaderRows; rowIdx < len(rows); rowIdx++ {
+		row := rows[rowIdx]
+		name := ""
+		if volunteerCol < len(row) {
+			name = strings.TrimSpace(row[volunteerCol])
+		}
+		if name == "" {
+			continue // reported by parseMappedDepartments
+		}
+
+		fieldError := func(errorType dtos.ValidationErrorType, field, message string) {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:   errorType,
+				Message:     message,
+				ColumnIndex: columns[field],
+				RowIndex:    rowIdx,
+			})
+		}
+
+		email, err := utils.NormalizeEmail(cell(row, departmentFieldEmail))
+		if err != nil {
+			fieldError(dtos.ErrorTypeInvalidEmail, departmentFieldEmail, fmt.Sprintf("Invalid email '%s' for '%s' in row %d", cell(row, departmentFieldEmail), name, rowIdx+1))
+			continue
+		}
+		phone, err := utils.NormalizePhone(cell(row, departmentFieldPhone))
+		if err != nil {
+			fieldError(dtos.ErrorTypeInvalidPhone, departmentFieldPhone, fmt.Sprintf("Invalid phone number '%s' for '%s' in row %d", cell(row, departmentFieldPhone), name, rowIdx+1))
+			continue
+		}
+		parsed := sub_model.ImportVolunteerProfile{
+			Name:      name,
+			Email:     email,
+			Phone:     phone,
+			StudentID: cell(row, departmentFieldStudentID),
+			YearLevel: cell(row, departmentFieldYearLevel),
+			Section:   cell(row, departmentFieldSection),
+			Skills:    utils.NormalizeLabels(splitImportList(cell(row, departmentFieldSkills))),
+			Tags:      utils.NormalizeLabels(splitImportList(cell(row, departmentFieldTags))),
+			RowIndex:  rowIdx,
+		}
+
+		key := importNameKey(name)
+		studentKey := utils.NormalizeStudentID(parsed.StudentID)
+		if owner, taken := studentIDOwners[studentKey]; studentKey != "" && taken && importNameKey(profiles[owner].Name) != key {
+			fieldError(dtos.ErrorTypeDuplicateStudentID, departmentFieldStudentID, fmt.Sprintf("Student ID '%s' in row %d is also used by '%s'", parsed.StudentID, rowIdx+1, profiles[owner].Name))
+			continue
+		}
+
+		idx, exists := profileIndex[key]
+		if !exists {
+			idx = len(profiles)
+			profileIndex[key] = idx
+			profiles = append(profiles, sub_model.ImportVolunteerProfile{Name: name, RowIndex: rowIdx})
+		}
+		profile := &profiles[idx]
+
+		// Fill the profile, a field already read from another row has to match
+		conflict := ""
+		merge := func(field string, current *string, value string, same bool) {
+			switch {
+			case value == "" || same:
+			case *current == "":
+				*current = value
+			case conflict == "":
+				conflict = field
+			}
+		}
+		merge(departmentFieldEmail, &profile.Email, parsed.Email, profile.Email == parsed.Email)
+		merge(departmentFieldPhone, &profile.Phone, parsed.Phone, profile.Phone == parsed.Phone)
+		merge(departmentFieldStudentID, &profile.StudentID, parsed.StudentID, utils.NormalizeStudentID(profile.StudentID) == utils.NormalizeStudentID(parsed.StudentID))
+		merge(departmentFieldYearLevel, &profile.YearLevel, parsed.YearLevel, strings.EqualFold(profile.YearLevel, parsed.YearLevel))
+		merge(departmentFieldSection, &profile.Section, parsed.Section, strings.EqualFold(profile.Section, parsed.Section))
+		if conflict != "" {
+			fieldError(dtos.ErrorTypeConflictingProfile, conflict, fmt.Sprintf("'%s' has a different %s in row %d than in row %d", name, conflict, rowIdx+1, profile.RowIndex+1))
+			continue
+		}
+		profile.Skills = utils.NormalizeLabels(append(profile.Skills, parsed.Skills...))
+		profile.Tags = utils.NormalizeLabels(append(profile.Tags, parsed.Tags...))
+		if studentKey != "" {
+			studentIDOwners[studentKey] = idx
+		}
+	}
+
+	return profiles, validationErrors
+}
+
+// matchStudentIDs finds the existing volunteers with the student IDs of the imported profiles, by name key
+// A student ID of a disabled volunteer can't be reused or created again, so it is a validation error
+func (h *BatchImportHandler) matchStudentIDs(ctx context.Context, profiles []sub_model.ImportVolunteerProfile, studentIDColumn int) (map[string]*models.VolunteerModel, []dtos.ValidationError, error) {
+	matches := make(map[string]*models.VolunteerModel)
+	hasStudentIDs := false
+	for _, profile := range profiles {
+		if profile.StudentID != "" {
+			hasStudentIDs = true
+			break
+		}
+	}
+	if !hasStudentIDs {
+		return matches, nil, nil
+	}
+
+	volunteers, err := h.db.Volunteers().ListVolunteer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byStudentID := make(map[string]*models.VolunteerModel)
+	for _, vol := range volunteers {
+		if key := utils.NormalizeStudentID(vol.StudentID); key != "" {
+			byStudentID[key] = vol
+		}
+	}
+
+	var validationErrors []dtos.ValidationError
+	for _, profile := range profiles {
+		vol, exists := byStudentID[utils.NormalizeStudentID(profile.StudentID)]
+		if profile.StudentID == "" || !exists {
+			continue
+		}
+		if vol.IsDisabled {
+			validationErrors = append(validationErrors, dtos.ValidationError{
+				ErrorType:   dtos.ErrorTypeDisabledStudentID,
+				Message:     fmt.Sprintf("Student ID '%s' of '%s' belongs to disabled volunteer '%s'", profile.StudentID, profile.Name, vol.Name),
+				ColumnIndex: studentIDColumn,
+				RowIndex:    profile.RowIndex,
+			})
+			continue
+		}
+		matches[importNameKey(profile.Name)] = vol
+	}
+	return matches, validationErrors, nil
+}
+
+// studentIDColumn returns the mapped student ID column, -1 without one
+func studentIDColumn(mapping *dtos.ColumnMapping) int {
+	if mapping == nil {
+		return -1
+	}
+	if colIdx, ok := mapping.Columns[departmentFieldStudentID]; ok {
+		return colIdx
+	}
+	return -1
+}
+
+// withoutStudentIDMatches drops name conflicts for volunteers already found by student ID
+func withoutStudentIDMatches(conflicts []dtos.VolunteerConflict, matches map[string]*models.VolunteerModel) []dtos.VolunteerConflict {
+	result := []dtos.VolunteerConflict{}
+	for _, conflict := range conflicts {
+		if _, matched := matches[importNameKey(conflict.VolunteerName)]; !matched {
+			result = append(result, conflict)
+		}
+	}
+	return result
+}
+
+// toProfilePreviews converts imported profiles for the preview response
+func toProfilePreviews(profiles []sub_model.ImportVolunteerProfile, matches map[string]*models.VolunteerModel) []dtos.VolunteerProfilePreview {
+	if len(profiles) == 0 {
+		return nil
+	}
+	result := make([]dtos.VolunteerProfilePreview, 0, len(profiles))
+	for _, profile := range profiles {
+		preview := dtos.VolunteerProfilePreview{
+			Name:      profile.Name,
+			Email:     profile.Email,
+			Phone:     profile.Phone,
+			StudentID: profile.StudentID,
+			YearLevel: profile.YearLevel,
+			Section:   profile.Section,
+			Skills:    profile.Skills,
+			Tags:      profile.Tags,
+			RowIndex:  profile.RowIndex,
+		}
+		if vol, matched := matches[importNameKey(profile.Name)]; matched {
+			preview.MatchedVolunteer = &dtos.MatchedName{Name: vol.Name, ID: vol.ID}
+		}
+		result = append(result, preview)
+	}
+	return result
+}
+
+// profilesByName indexes imported profiles by name key
+func profilesByName(profiles []sub_model.ImportVolunteerProfile) map[string]sub_model.ImportVolunteerProfile {
+	result := make(map[string]sub_model.ImportVolunteerProfile)
+	for _, profile := range profiles {
+		result[importNameKey(profile.Name)] = profile
+	}
+	return result
+}
+
+// applyImportProfile copies an imported profile to a volunteer being created
+// Volunteers split per department share a person's profile but not their student ID, which has to stay unique
+func applyImportProfile(volunteer *models.VolunteerModel, profile sub_model.ImportVolunteerProfile, withStudentID bool) {
+	volunteer.Email = profile.Email
+	volunteer.Phone = profile.Phone
+	volunteer.YearLevel = profile.YearLevel
+	volunteer.Section = profile.Section
+	volunteer.Skills = profile.Skills
+	volunteer.Tags = profile.Tags
+	if withStudentID {
+		volunteer.StudentID = profile.StudentID
+	}
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	dtos "sheduling-server/DTOs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Import file formats
+const (
+	importFormatExcel = "excel"
+	importFormatCSV   = "csv"
+)
+
+// maxImportFileSize keeps text files from being read into memory without bound
+const maxImportFileSize = 10 << 20
+
+// inspectSampleRows is how many rows the inspect endpoint returns for the mapping step
+const inspectSampleRows = 10
+
+// importSource is the parsed content of an uploaded import file
+type importSource struct {
+	Format    string
+	Encoding  string // text files only
+	Delimiter string // text files only
+	Sheets    []string
+	Sheet     string
+	Rows      [][]string
+}
+
+// importSourceError is a problem with the uploaded file, with the status to respond with
+type importSourceError struct {
+	status  int
+	message string
+}
+
+func (e *importSourceError) Error() string {
+	return e.message
+}
+
+// readUploadedSheet reads the rows of the uploaded file, writes the error response on failure
+// Workbooks use the sheet named in the "sheet" form field (name or 1-based number), the first sheet otherwise
+func readUploadedSheet(c *gin.Context) (*multipart.FileHeader, [][]string, bool) {
+	file, source, ok := readUploadedSource(c)
+	if !ok {
+		return nil, nil, false
+	}
+	return file, source.Rows, true
+}
+
+// readUploadedSource reads the uploaded file with its detected format, writes the error response on failure
+func readUploadedSource(c *gin.Context) (*multipart.FileHeader, *importSource, bool) {
+	// Get the uploaded file
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return nil, nil, false
+	}
+
+	source, err := readImportSource(file, c.PostForm("sheet"))
+	if err != nil {
+		if sourceErr, ok := err.(*importSourceError); ok {
+			c.JSON(sourceErr.status, gin.H{"error": sourceErr.message})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, nil, false
+	}
+
+	return file, source, true
+}
+
+// readImportSource parses an Excel workbook or a CSV/TSV file
+func readImportSource(file *multipart.FileHeader, sheet string) (*importSource, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	switch ext {
+	case ".xlsx", ".xlsm", ".xls":
+	case ".csv", ".tsv", ".txt":
+	default:
+		return nil, &importSourceError{http.StatusBadRequest, "Invalid file format. Please upload an Excel (.xlsx, .xls) or CSV/TSV (.csv, .tsv) file"}
+	}
+
+	// Open the uploaded file
+	src, err := file.Open()
+	if err != nil {
+		return nil, &importSourceError{http.StatusInternalServerError, "Failed to open file"}
+	}
+	defer src.Close()
+
+	if ext == ".csv" || ext == ".tsv" || ext == ".txt" {
+		return readTextSource(src, ext)
+	}
+	return readExcelSource(src, sheet)
+}
+
+// readExcelSource reads one sheet of a workbook
+func readExcelSource(src io.Reader, sheet string) (*importSource, error) {
+	xlsx, err := excelize.OpenReader(src)
+	if err != nil {
+		return nil, &importSourceError{http.StatusBadRequest, "Failed to parse Excel file: " + err.Error()}
+	}
+	defer xlsx.Close()
+
+	sheets := xlsx.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, &importSourceError{http.StatusBadRequest, "Excel file has no sheets"}
+	}
+
+	sheetName, ok := pickSheet(sheets, sheet)
+	if !ok {
+		return nil, &importSourceError{http.StatusBadRequest, fmt.Sprintf("Sheet '%s' not found, the workbook has: %s", sheet, strings.Join(sheets, ", "))}
+	}
+
+	rows, err := xlsx.GetRows(sheetName)
+	if err != nil {
+		return nil, &importSourceError{http.StatusInternalServerError, "Failed to read sheet data"}
+	}
+
+	return &importSource{
+		Format: importFormatExcel,
+		Sheets: sheets,
+		Sheet:  sheetName,
+		Rows:   rows,
+	}, nil
+}
+
+// pickSheet finds a sheet by name (case-insensitive) or 1-based number, empty picks the first sheet
+func pickSheet(sheets []string, sheet string) (string, bool) {
+	sheet = strings.TrimSpace(sheet)
+	if sheet == "" {
+		return sheets[0], true
+	}
+	for _, name := range sheets {
+		if strings.EqualFold(name, sheet) {
+			return name, true
+		}
+	}
+	if number, err := strconv.Atoi(sheet); err == nil && number >= 1 && number <= len(sheets) {
+		return sheets[number-1], true
+	}
+	return "", false
+}
+
+// readTextSource reads a delimited text file, detecting its encoding and delimiter
+func readTextSource(src io.Reader, ext string) (*importSource, error) {
+	data, err := io.ReadAll(io.LimitReader(src, maxImportFileSize+1))
+	if err != nil {
+		return nil, &importSourceError{http.StatusInternalServerError, "Failed to read file"}
+	}
+	if len(data) > maxImportFileSize {
+		return nil, &importSourceError{http.StatusBadRequest, "File is too large"}
+	}
+
+	text, encoding, err := decodeImportText(data)
+	if err != nil {
+		return nil, &importSourceError{http.StatusBadRequest, "Failed to decode file: " + err.Error()}
+	}
+
+	delimiter := '\t'
+	if ext != ".tsv" {
+		delimiter = detectDelimiter(text)
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1 // rows can have different lengths, like sheet rows
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, &importSourceError{http.StatusBadRequest, "Failed to parse file: " + err.Error()}
+	}
+
+	return &importSource{
+		Format:    importFormatCSV,
+		Encoding:  encoding,
+		Delimiter: string(delimiter),
+		Rows:      rows,
+	}, nil
+}
+
+// decodeImportText converts a text file to UTF-8
+// UTF-8 and UTF-16 are recognised by their byte order mark, anything that isn't valid UTF-8 is read as Windows-1252 (Excel's "CSV" on Windows)
+func decodeImportText(data []byte) (string, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), "utf-8", nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		return string(decoded), "utf-16le", err
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoded, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		return string(decoded), "utf-16be", err
+	case utf8.Valid(data):
+		return string(data), "utf-8", nil
+	default:
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+		return string(decoded), "windows-1252", err
+	}
+}
+
+// detectDelimiter picks the delimiter that splits the first lines into the same number of fields most often
+func detectDelimiter(text string) rune {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 20 {
+		lines = lines[:20]
+	}
+	sample := strings.Join(lines, "\n")
+
+	best, bestScore := ',', 0
+	for _, candidate := range []rune{',', ';', '\t', '|'} {
+		reader := csv.NewReader(strings.NewReader(sample))
+		reader.Comma = candidate
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		// Score rows that have the most common field count, only counting splits into 2+ fields
+		counts := make(map[int]int)
+		for {
+			record, err := reader.Read()
+			if err != nil {
+				break
+			}
+			if len(record) > 1 {
+				counts[len(record)]++
+			}
+		}
+		for _, count := range counts {
+			if count > bestScore {
+				best, bestScore = candidate, count
+			}
+		}
+	}
+	return best
+}
+
+// parseColumnMapping reads the optional "mapping" form field, nil means the default layout
+func parseColumnMapping(c *gin.Context, fields []string, required []string) (*dtos.ColumnMapping, error) {
+	raw := strings.TrimSpace(c.PostForm("mapping"))
+	if raw == "" {
+		return nil, nil
+	}
+
+	var mapping dtos.ColumnMapping
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid column mapping: %v", err)
+	}
+	if mapping.HeaderRows == nil {
+		headerRows := 1
+		mapping.HeaderRows = &headerRows
+	}
+	if *mapping.HeaderRows < 0 {
+		return nil, fmt.Errorf("invalid column mapping: headerRows can't be negative")
+	}
+
+	for field, colIdx := range mapping.Columns {
+		known := false
+		for _, f := range fields {
+			if f == field {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("invalid column mapping: unknown field '%s', expected one of %s", field, strings.Join(fields, ", "))
+		}
+		if colIdx < 0 {
+			return nil, fmt.Errorf("invalid column mapping: column for '%s' can't be negative", field)
+		}
+	}
+	for _, field := range required {
+		if _, ok := mapping.Columns[field]; !ok {
+			return nil, fmt.Errorf("invalid column mapping: '%s' column is required", field)
+		}
+	}
+
+	return &mapping, nil
+}
+
+// InspectBatchImport reads an uploaded file without importing it, so the organizer can pick a sheet and map columns
+func (h *BatchImportHandler) InspectBatchImport(c *gin.Context) {
+	file, source, ok := readUploadedSource(c)
+	if !ok {
+		return
+	}
+
+	sample := source.Rows
+	if len(sample) > inspectSampleRows {
+		sample = sample[:inspectSampleRows]
+	}
+	columnCount := 0
+	for _, row := range source.Rows {
+		if len(row) > columnCount {
+			columnCount = len(row)
+		}
+	}
+
+	c.JSON(http.StatusOK, dtos.BatchImportInspectResponse{
+		FileName:    file.Filename,
+		Format:      source.Format,
+		Encoding:    source.Encoding,
+		Delimiter:   source.Delimiter,
+		Sheets:      source.Sheets,
+		Sheet:       source.Sheet,
+		RowCount:    len(source.Rows),
+		ColumnCount: columnCount,
+		SampleRows:  sample,
+		MappingFields: map[string][]string{
+			"departments": departmentMappingFields,
+			"events":      eventMappingFields,
+		},
+	})
+}
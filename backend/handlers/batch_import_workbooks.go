@@ -0,0 +1,618 @@
+package handlers
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxContentType is the MIME type of generated workbooks
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Sheet names used in generated workbooks
+const (
+	templateInstructionsSheet = "Instructions"
+	templateListsSheet        = "Lists"
+	reportFindingsSheet       = "Import Findings"
+)
+
+// templateDataRows is how far down data validation reaches in templates
+const templateDataRows = 500
+
+// reportCommentAuthor is shown as the author of comments added to error reports
+const reportCommentAuthor = "Import Check"
+
+// Cell fills used in error reports
+var (
+	reportErrorFill    = excelize.Fill{Type: "pattern", Color: []string{"FFC7CE"}, Pattern: 1}
+	reportConflictFill = excelize.Fill{Type: "pattern", Color: []string{"FFEB9C"}, Pattern: 1}
+)
+
+// DownloadImportTemplate returns a blank workbook with the expected layout, example rows and data validation
+func (h *BatchImportHandler) DownloadImportTemplate(c *gin.Context) {
+	kind := models.ImportKind(c.DefaultQuery("type", string(models.ImportKindDepartments)))
+
+	var xlsx *excelize.File
+	var err error
+	switch kind {
+	case models.ImportKindDepartments:
+		xlsx, err = departmentTemplate()
+	case models.ImportKindEvents:
+		var departmentNames []string
+		departmentNames, err = h.activeDepartmentNames(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch departments: " + err.Error()})
+			return
+		}
+		xlsx, err = eventTemplate(departmentNames)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type, expected 'departments' or 'events'"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build template: " + err.Error()})
+		return
+	}
+	defer xlsx.Close()
+
+	writeWorkbook(c, xlsx, fmt.Sprintf("%s-import-template.xlsx", kind))
+}
+
+// DownloadImportReport checks an uploaded file like the preview does and returns it with the findings
+// highlighted and commented on the offending cells, plus a sheet listing every finding
+func (h *BatchImportHandler) DownloadImportReport(c *gin.Context) {
+	kind := models.ImportKind(c.DefaultPostForm("type", string(models.ImportKindDepartments)))
+
+	var mapping *dtos.ColumnMapping
+	var err error
+	switch kind {
+	case models.ImportKindDepartments:
+		mapping, err = parseColumnMapping(c, departmentMappingFields, []string{departmentFieldName, departmentFieldVolunteer})
+	case models.ImportKindEvents:
+		mapping, err = parseColumnMapping(c, eventMappingFields, []string{eventColumnName, eventColumnDate})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type, expected 'departments' or 'events'"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, source, ok := readUploadedSource(c)
+	if !ok {
+		return
+	}
+
+	// Same checks as the preview, conflicts are only looked for in what parsed
+	var validationErrors []dtos.ValidationError
+	var conflicts []dtos.VolunteerConflict
+	if kind == models.ImportKindEvents {
+		events, parseErrors := parseEventRows(source.Rows, mapping, importLocation())
+		validationErrors = parseErrors
+		if len(events) > 0 {
+			var matchErrors []dtos.ValidationError
+			_, conflicts, matchErrors, err = h.matchEventNames(c.Request.Context(), events)
+			validationErrors = append(validationErrors, matchErrors...)
+		}
+	} else {
+		var departments []dtos.DepartmentPreview
+		var profiles []sub_model.ImportVolunteerProfile
+		if mapping != nil {
+			departments, validationErrors = parseMappedDepartments(source.Rows, mapping)
+			var profileErrors []dtos.ValidationError
+			profiles, profileErrors = parseVolunteerProfiles(source.Rows, mapping)
+			validationErrors = append(validationErrors, profileErrors...)
+		} else {
+			departments, validationErrors = h.parseColumns(source.Rows)
+		}
+		studentMatches, matchErrors, matchErr := h.matchStudentIDs(c.Request.Context(), profiles, studentIDColumn(mapping))
+		err = matchErr
+		validationErrors = append(validationErrors, matchErrors...)
+		if err == nil && len(departments) > 0 {
+			conflicts, err = h.detectConflicts(c.Request.Context(), departments)
+			conflicts = withoutStudentIDMatches(conflicts, studentMatches)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect conflicts: " + err.Error()})
+		return
+	}
+
+	xlsx, sheet, err := reportWorkbook(file, source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report: " + err.Error()})
+		return
+	}
+	defer xlsx.Close()
+
+	if err := annotateWorkbook(xlsx, sheet, validationErrors, conflicts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report: " + err.Error()})
+		return
+	}
+
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	writeWorkbook(c, xlsx, base+"-report.xlsx")
+}
+
+// activeDepartmentNames lists the names of departments that aren't disabled, sorted
+func (h *BatchImportHandler) activeDepartmentNames(c *gin.Context) ([]string, error) {
+	departments, err := h.db.Departments().ListDepartments(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, dept := range departments {
+		if !dept.IsDisabled {
+			names = append(names, dept.DepartmentName)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// departmentTemplate builds the column layout template: department name, then head, then members
+func departmentTemplate() (*excelize.File, error) {
+	xlsx := excelize.NewFile()
+	sheet := "Departments"
+	if err := xlsx.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	rows := [][]interface{}{
+		{"Worship Team", "Ushering"},
+		{"Juan Dela Cruz", "Maria Santos"},
+		{"Ana Reyes", "Jose Garcia"},
+		{"Mark Villanueva", "Liza Mendoza"},
+		{"Carla Bautista", ""},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := xlsx.SetSheetRow(sheet, cell, &row); err != nil {
+			return nil, err
+		}
+	}
+
+	headerStyle, err := xlsx.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"D9E1F2"}, Pattern: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	headStyle, err := xlsx.NewStyle(&excelize.Style{Font: &excelize.Font{Italic: true}})
+	if err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetCellStyle(sheet, "A1", "Z1", headerStyle); err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetCellStyle(sheet, "A2", "Z2", headStyle); err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetColWidth(sheet, "A", "Z", 24); err != nil {
+		return nil, err
+	}
+
+	// One validation per row band, Excel only applies one validation to a cell
+	bands := []struct {
+		sqref, title, message string
+	}{
+		{"A1:Z1", "Department", "Department name, one department per column"},
+		{"A2:Z2", "Department head", "The head of the department in this column"},
+		{fmt.Sprintf("A3:Z%d", templateDataRows), "Members", "One member per row"},
+	}
+	for _, band := range bands {
+		dv := excelize.NewDataValidation(true)
+		dv.SetSqref(band.sqref)
+		if err := dv.SetRange(1, 100, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween); err != nil {
+			return nil, err
+		}
+		dv.SetInput(band.title, band.message)
+		dv.SetError(excelize.DataValidationErrorStyleStop, "Name too long", "Names can have at most 100 characters")
+		if err := xlsx.AddDataValidation(sheet, dv); err != nil {
+			return nil, err
+		}
+	}
+
+	instructions := []string{
+		"Each column is one department.",
+		"Row 1: department name.",
+		"Row 2: department head.",
+		"Row 3 and below: members, one per row.",
+		"A volunteer can be in more than one department, use the same spelling of their name.",
+		"Replace the example rows before uploading.",
+	}
+	if err := addInstructionsSheet(xlsx, instructions); err != nil {
+		return nil, err
+	}
+
+	return xlsx, nil
+}
+
+// eventTemplate builds the event template, departments offer a dropdown of the existing departments
+func eventTemplate(departmentNames []string) (*excelize.File, error) {
+	xlsx := excelize.NewFile()
+	sheet := "Events"
+	if err := xlsx.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	headers := []interface{}{"Name", "Date", "Time", "Location", "Description", "Departments", "Volunteers"}
+	if err := xlsx.SetSheetRow(sheet, "A1", &headers); err != nil {
+		return nil, err
+	}
+	exampleDepartment := "Worship Team"
+	if len(departmentNames) > 0 {
+		exampleDepartment = departmentNames[0]
+	}
+	examples := [][]interface{}{
+		{"Sunday Service", "2026-01-04", "09:00", "Main Hall", "First service of the year", exampleDepartment, "Juan Dela Cruz; Ana Reyes"},
+		{"Youth Night", "2026-01-09", "18:30", "Room 2", "", exampleDepartment, "Mark Villanueva"},
+	}
+	for i, row := range examples {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := xlsx.SetSheetRow(sheet, cell, &row); err != nil {
+			return nil, err
+		}
+	}
+
+	headerStyle, err := xlsx.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"D9E1F2"}, Pattern: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetCellStyle(sheet, "A1", "G1", headerStyle); err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetColWidth(sheet, "A", "G", 22); err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+
+	// Text cells keep the typed value, so dates and times read back the way the parser expects
+	textStyle, err := xlsx.NewStyle(&excelize.Style{NumFmt: 49})
+	if err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetCellStyle(sheet, "B2", fmt.Sprintf("C%d", templateDataRows), textStyle); err != nil {
+		return nil, err
+	}
+
+	name := excelize.NewDataValidation(false)
+	name.SetSqref(fmt.Sprintf("A2:A%d", templateDataRows))
+	if err := name.SetRange(1, 200, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween); err != nil {
+		return nil, err
+	}
+	name.SetError(excelize.DataValidationErrorStyleStop, "Event name", "Every event needs a name of at most 200 characters")
+	if err := xlsx.AddDataValidation(sheet, name); err != nil {
+		return nil, err
+	}
+
+	date := excelize.NewDataValidation(false)
+	date.SetSqref(fmt.Sprintf("B2:B%d", templateDataRows))
+	if err := date.SetRange(10, 10, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween); err != nil {
+		return nil, err
+	}
+	date.SetInput("Date", "Use YYYY-MM-DD, for example 2026-01-04")
+	date.SetError(excelize.DataValidationErrorStyleStop, "Invalid date", "Use YYYY-MM-DD, for example 2026-01-04")
+	if err := xlsx.AddDataValidation(sheet, date); err != nil {
+		return nil, err
+	}
+
+	timeOfDay := excelize.NewDataValidation(false)
+	timeOfDay.SetSqref(fmt.Sprintf("C2:C%d", templateDataRows))
+	if err := timeOfDay.SetRange(4, 8, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween); err != nil {
+		return nil, err
+	}
+	timeOfDay.SetInput("Time", "Required, 24-hour time, for example 18:30 (00:00 for midnight)")
+	timeOfDay.SetError(excelize.DataValidationErrorStyleStop, "Invalid time", "Use 24-hour time, for example 18:30")
+	if err := xlsx.AddDataValidation(sheet, timeOfDay); err != nil {
+		return nil, err
+	}
+
+	if len(departmentNames) > 0 {
+		// The names go on a hidden sheet, an inline list is limited to 255 characters
+		if _, err := xlsx.NewSheet(templateListsSheet); err != nil {
+			return nil, err
+		}
+		for i, deptName := range departmentNames {
+			if err := xlsx.SetCellStr(templateListsSheet, fmt.Sprintf("A%d", i+1), deptName); err != nil {
+				return nil, err
+			}
+		}
+		if err := xlsx.SetSheetVisible(templateListsSheet, false); err != nil {
+			return nil, err
+		}
+
+		departments := excelize.NewDataValidation(true)
+		departments.SetSqref(fmt.Sprintf("F2:F%d", templateDataRows))
+		departments.SetSqrefDropList(fmt.Sprintf("%s!$A$1:$A$%d", templateListsSheet, len(departmentNames)))
+		departments.SetInput("Departments", "Pick a department, or type several separated by semicolons")
+		// Only a warning, so several departments can still be typed in one cell
+		departments.SetError(excelize.DataValidationErrorStyleInformation, "Department", "Make sure every department exists, separate several with semicolons")
+		if err := xlsx.AddDataValidation(sheet, departments); err != nil {
+			return nil, err
+		}
+	}
+
+	volunteers := excelize.NewDataValidation(true)
+	volunteers.SetSqref(fmt.Sprintf("G2:G%d", templateDataRows))
+	volunteers.SetInput("Volunteers", "Volunteer names separated by semicolons")
+	if err := xlsx.AddDataValidation(sheet, volunteers); err != nil {
+		return nil, err
+	}
+
+	instructions := []string{
+		"Each row is one event, keep the header row.",
+		"Name and Date are required. Date is YYYY-MM-DD, Time is 24-hour HH:MM.",
+		"Departments and Volunteers take several names separated by semicolons.",
+		"Departments have to exist already, volunteers are matched by name.",
+		"Replace the example rows before uploading.",
+	}
+	if err := addInstructionsSheet(xlsx, instructions); err != nil {
+		return nil, err
+	}
+
+	return xlsx, nil
+}
+
+// addInstructionsSheet adds a sheet with one instruction per row after the data sheet
+func addInstructionsSheet(xlsx *excelize.File, lines []string) error {
+	if _, err := xlsx.NewSheet(templateInstructionsSheet); err != nil {
+		return err
+	}
+	for i, line := range lines {
+		if err := xlsx.SetCellStr(templateInstructionsSheet, fmt.Sprintf("A%d", i+1), line); err != nil {
+			return err
+		}
+	}
+	return xlsx.SetColWidth(templateInstructionsSheet, "A", "A", 90)
+}
+
+// reportWorkbook opens the uploaded workbook for annotating, text files are copied into a new workbook
+func reportWorkbook(file *multipart.FileHeader, source *importSource) (*excelize.File, string, error) {
+	if source.Format == importFormatExcel {
+		src, err := file.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer src.Close()
+
+		xlsx, err := excelize.OpenReader(src)
+		if err != nil {
+			return nil, "", err
+		}
+		return xlsx, source.Sheet, nil
+	}
+
+	xlsx := excelize.NewFile()
+	sheet := strings.TrimSuffix(filepath.Base(file.Filename), filepath.Ext(file.Filename))
+	if len(sheet) > 31 || sheet == "" || strings.ContainsAny(sheet, `:\/?*[]`) {
+		sheet = "Import"
+	}
+	if err := xlsx.SetSheetName("Sheet1", sheet); err != nil {
+		xlsx.Close()
+		return nil, "", err
+	}
+	for rowIdx, row := range source.Rows {
+		for colIdx, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(colIdx+1, rowIdx+1)
+			if err := xlsx.SetCellStr(sheet, cell, value); err != nil {
+				xlsx.Close()
+				return nil, "", err
+			}
+		}
+	}
+	return xlsx, sheet, nil
+}
+
+// reportFinding is one problem to show in the report
+type reportFinding struct {
+	row, column int // -1 when it has no cell
+	kind        string
+	message     string
+	isError     bool
+}
+
+// annotateWorkbook highlights and comments the cells with findings and adds a sheet listing them all
+// Errors are red and conflicts yellow, a cell with both stays red
+func annotateWorkbook(xlsx *excelize.File, sheet string, validationErrors []dtos.ValidationError, conflicts []dtos.VolunteerConflict) error {
+	var findings []reportFinding
+	for _, validationErr := range validationErrors {
+		finding := reportFinding{row: -1, column: -1, kind: string(validationErr.ErrorType), message: validationErr.Message, isError: true}
+		// These describe the whole file, not a cell
+		if validationErr.ErrorType != dtos.ErrorTypeInvalidFileFormat && validationErr.ErrorType != dtos.ErrorTypeMissingColumn && validationErr.ColumnIndex >= 0 {
+			finding.row, finding.column = validationErr.RowIndex, validationErr.ColumnIndex
+		}
+		findings = append(findings, finding)
+	}
+	for _, conflict := range conflicts {
+		message := conflictMessage(conflict)
+		for _, occurrence := range conflict.Occurrences {
+			findings = append(findings, reportFinding{
+				row:     occurrence.RowIndex,
+				column:  occurrence.ColumnIndex,
+				kind:    string(conflict.ConflictType),
+				message: message,
+			})
+		}
+	}
+
+	// Group by cell so each cell gets one comment
+	type cellNotes struct {
+		messages []string
+		isError  bool
+	}
+	cells := make(map[string]*cellNotes)
+	var order []string
+	for _, finding := range findings {
+		if finding.row < 0 || finding.column < 0 {
+			continue
+		}
+		cell, err := excelize.CoordinatesToCellName(finding.column+1, finding.row+1)
+		if err != nil {
+			continue
+		}
+		notes, exists := cells[cell]
+		if !exists {
+			notes = &cellNotes{}
+			cells[cell] = notes
+			order = append(order, cell)
+		}
+		notes.messages = append(notes.messages, fmt.Sprintf("%s: %s", finding.kind, finding.message))
+		notes.isError = notes.isError || finding.isError
+	}
+
+	// The fill is added to the style a cell has, so dates and times keep their number format
+	type highlight struct {
+		style   int
+		isError bool
+	}
+	highlighted := make(map[highlight]int)
+	for _, cell := range order {
+		notes := cells[cell]
+		existing, err := xlsx.GetCellStyle(sheet, cell)
+		if err != nil {
+			return err
+		}
+		key := highlight{style: existing, isError: notes.isError}
+		style, exists := highlighted[key]
+		if !exists {
+			merged, err := xlsx.GetStyle(existing)
+			if err != nil {
+				return err
+			}
+			merged.Fill = reportConflictFill
+			if notes.isError {
+				merged.Fill = reportErrorFill
+			}
+			if style, err = xlsx.NewStyle(merged); err != nil {
+				return err
+			}
+			highlighted[key] = style
+		}
+		if err := xlsx.SetCellStyle(sheet, cell, cell, style); err != nil {
+			return err
+		}
+		// Replace an earlier comment, a cell can only have one
+		_ = xlsx.DeleteComment(sheet, cell)
+		if err := xlsx.AddComment(sheet, excelize.Comment{
+			Author:    reportCommentAuthor,
+			Cell:      cell,
+			Paragraph: []excelize.RichTextRun{{Text: strings.Join(notes.messages, "\n")}},
+			Width:     300,
+			Height:    uint(60 + 30*len(notes.messages)),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return addFindingsSheet(xlsx, findings)
+}
+
+// conflictMessage describes a conflict and its candidates for a cell comment
+func conflictMessage(conflict dtos.VolunteerConflict) string {
+	var message string
+	switch conflict.ConflictType {
+	case dtos.ConflictTypeDuplicateInImport:
+		message = fmt.Sprintf("'%s' appears %d times in the file", conflict.VolunteerName, len(conflict.Occurrences))
+	case dtos.ConflictTypeExistingInDB:
+		message = fmt.Sprintf("'%s' already exists", conflict.VolunteerName)
+	case dtos.ConflictTypeNotInDB:
+		message = fmt.Sprintf("'%s' is not a volunteer yet", conflict.VolunteerName)
+	case dtos.ConflictTypeAmbiguousInDB:
+		message = fmt.Sprintf("Several volunteers are named '%s'", conflict.VolunteerName)
+	case dtos.ConflictTypePossibleMatch:
+		message = fmt.Sprintf("'%s' looks like an existing volunteer", conflict.VolunteerName)
+	default:
+		message = fmt.Sprintf("'%s' needs a decision", conflict.VolunteerName)
+	}
+
+	var candidates []string
+	if conflict.ExistingVolunteer != nil {
+		candidates = append(candidates, conflict.ExistingVolunteer.Name)
+	}
+	for _, candidate := range conflict.Candidates {
+		if candidate.Confidence > 0 {
+			candidates = append(candidates, fmt.Sprintf("%s (%.0f%%)", candidate.Name, candidate.Confidence*100))
+		} else {
+			candidates = append(candidates, candidate.Name)
+		}
+	}
+	if len(candidates) > 0 {
+		message += ": " + strings.Join(candidates, ", ")
+	}
+	return message
+}
+
+// addFindingsSheet lists every finding with its cell, replacing the sheet from an earlier report
+func addFindingsSheet(xlsx *excelize.File, findings []reportFinding) error {
+	if idx, _ := xlsx.GetSheetIndex(reportFindingsSheet); idx >= 0 {
+		if err := xlsx.DeleteSheet(reportFindingsSheet); err != nil {
+			return err
+		}
+	}
+	if _, err := xlsx.NewSheet(reportFindingsSheet); err != nil {
+		return err
+	}
+
+	header := []interface{}{"Cell", "Row", "Column", "Severity", "Type", "Message"}
+	if err := xlsx.SetSheetRow(reportFindingsSheet, "A1", &header); err != nil {
+		return err
+	}
+	for i, finding := range findings {
+		cell, row, column := "", "", ""
+		if finding.row >= 0 && finding.column >= 0 {
+			cell, _ = excelize.CoordinatesToCellName(finding.column+1, finding.row+1)
+			columnName, _ := excelize.ColumnNumberToName(finding.column + 1)
+			row, column = fmt.Sprint(finding.row+1), columnName
+		}
+		severity := "Conflict"
+		if finding.isError {
+			severity = "Error"
+		}
+		values := []interface{}{cell, row, column, severity, finding.kind, finding.message}
+		if err := xlsx.SetSheetRow(reportFindingsSheet, fmt.Sprintf("A%d", i+2), &values); err != nil {
+			return err
+		}
+	}
+	if len(findings) == 0 {
+		if err := xlsx.SetCellStr(reportFindingsSheet, "A2", "No problems found"); err != nil {
+			return err
+		}
+	}
+
+	boldStyle, err := xlsx.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	if err := xlsx.SetCellStyle(reportFindingsSheet, "A1", "F1", boldStyle); err != nil {
+		return err
+	}
+	return xlsx.SetColWidth(reportFindingsSheet, "F", "F", 80)
+}
+
+// writeWorkbook sends a workbook as a download
+func writeWorkbook(c *gin.Context, xlsx *excelize.File, fileName string) {
+	buf, err := xlsx.WriteToBuffer()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write workbook: " + err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, xlsxContentType, buf.Bytes())
+}
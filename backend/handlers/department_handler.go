@@ -20,13 +20,43 @@ func NewDepartmentHandler(db repository.Database) *DepartmentHandler {
 	return &DepartmentHandler{db: db}
 }
 
+// List searches and filters departments, paged when limit or cursor is given
 func (h *DepartmentHandler) List(c *gin.Context) {
-	departments, err := h.db.Departments().ListDepartments(c.Request.Context())
+	var input dtos.List_Departments_Query
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts, paginated, err := listOptions(input.Sort, input.Cursor, input.Limit, repository.SortByName, repository.SortByCreatedAt)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	createdFrom, err := parseQueryTime("createdFrom", input.CreatedFrom)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	createdTo, err := parseQueryTime("createdTo", input.CreatedTo)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.db.Departments().QueryDepartments(c.Request.Context(), repository.DepartmentQuery{
+		Search:        input.Search,
+		Prefix:        input.Prefix,
+		VolunteerID:   input.VolunteerID,
+		IsDisabled:    input.Disabled,
+		CreatedAfter:  createdFrom,
+		CreatedBefore: createdTo,
+		ListOptions:   opts,
+	})
+	if err != nil {
+		writeListError(c, err)
 		return
 	}
-	c.JSON(200, departments)
+	writeListPage(c, page, opts, paginated)
 }
 
 func (h *DepartmentHandler) GetByID(c *gin.Context) {
@@ -1,32 +1,66 @@
 package handlers
 
 import (
-	"fmt"
+	"log/slog"
 	dtos "sheduling-server/DTOs"
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
 	"sheduling-server/repository"
 	"sheduling-server/utils"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type EventHandler struct {
-	db repository.Database
+	db       repository.Database
+	notifier *utils.Notifier
 }
 
-func NewEventHandler(db repository.Database) *EventHandler {
-	return &EventHandler{db: db}
+// NewEventHandler takes the notifier that emails volunteers about their schedule
+func NewEventHandler(db repository.Database, notifier *utils.Notifier) *EventHandler {
+	return &EventHandler{db: db, notifier: notifier}
 }
 
+// List searches and filters events, paged when limit or cursor is given
 func (h *EventHandler) List(c *gin.Context) {
-	events, err := h.db.EventSchedules().ListEvent(c.Request.Context())
+	var input dtos.List_Events_Query
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts, paginated, err := listOptions(input.Sort, input.Cursor, input.Limit, repository.SortByTimeAndDate, repository.SortByName, repository.SortByCreatedAt)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, events)
+	from, err := parseQueryTime("from", input.From)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := parseQueryTime("to", input.To)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.db.EventSchedules().QueryEvents(c.Request.Context(), repository.EventQuery{
+		Search:       input.Search,
+		Prefix:       input.Prefix,
+		DepartmentID: input.DepartmentID,
+		VolunteerID:  input.VolunteerID,
+		IsDisabled:   input.Disabled,
+		From:         from,
+		To:           to,
+		ListOptions:  opts,
+	})
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+	writeListPage(c, page, opts, paginated)
 }
 
 func (h *EventHandler) GetByID(c *gin.Context) {
@@ -108,6 +142,12 @@ func (h *EventHandler) Update(c *gin.Context) {
 
 	// Track changes for logging
 	changes := make(map[string]interface{})
+	oldTime := existingEvent.TimeAndDate
+	oldAddress := ""
+	if existingEvent.Location != nil {
+		oldAddress = existingEvent.Location.Address
+	}
+	wasDisabled := existingEvent.IsDisabled
 
 	// Update only provided fields
 	if updateInput.Name != nil && *updateInput.Name != existingEvent.Name {
@@ -161,6 +201,24 @@ func (h *EventHandler) Update(c *gin.Context) {
 		return
 	}
 
+	// Tell the volunteers, a disabled event is cancelled like a deleted one
+	switch {
+	case existingEvent.IsDisabled && !wasDisabled:
+		h.notifier.NotifyEventCancelled(c.Request.Context(), existingEvent)
+	case !existingEvent.IsDisabled:
+		var changedTime *time.Time
+		var changedAddress *string
+		if !existingEvent.TimeAndDate.Equal(oldTime) {
+			changedTime = &oldTime
+		}
+		if existingEvent.Location != nil && existingEvent.Location.Address != oldAddress {
+			changedAddress = &oldAddress
+		}
+		if changedTime != nil || changedAddress != nil {
+			h.notifier.NotifyEventChanged(c.Request.Context(), existingEvent, changedTime, changedAddress)
+		}
+	}
+
 	// Log event update if there were changes
 	if len(changes) > 0 {
 		utils.CreateEnhancedLog(c, h.db, sub_model.EVENT_UPDATED, sub_model.SEVERITY_INFO, map[string]interface{}{
@@ -202,6 +260,7 @@ func (h *EventHandler) Delete(c *gin.Context) {
 		sub_model.META_EVENT_NAME: existingEvent.Name,
 		sub_model.META_REASON:     "Soft delete via Delete endpoint",
 	})
+	h.notifier.NotifyEventCancelled(c.Request.Context(), existingEvent)
 
 	c.JSON(200, gin.H{"message": "Event deleted successfully"})
 }
@@ -225,6 +284,9 @@ func (h *EventHandler) AddVolunteerStatus(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	if event, err := h.db.EventSchedules().GetEventByID(c.Request.Context(), eventID); err == nil {
+		h.notifier.NotifyAssigned(c.Request.Context(), event, []string{input.VolunteerID})
+	}
 
 	c.JSON(200, gin.H{"message": "Volunteer status added successfully"})
 }
@@ -412,7 +474,19 @@ func (h *EventHandler) AddDepartmentToEvent(c *gin.Context) {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	fmt.Printf("Adding: %d departments to %s", len(input.DepartmentID), eventID)
+	slog.DebugContext(c.Request.Context(), "Adding departments to event", "eventId", eventID, "departments", len(input.DepartmentID))
+	before, err := h.db.EventSchedules().GetEventByID(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "Event not found"})
+		return
+	}
+	added := []string{}
+	for _, deptID := range input.DepartmentID {
+		if !slices.Contains(before.AssignedGroups, deptID) && !slices.Contains(added, deptID) {
+			added = append(added, deptID)
+		}
+	}
+
 	for _, deptID := range input.DepartmentID {
 		err := h.db.EventSchedules().AddDepartmentToEvent(c.Request.Context(), eventID, deptID)
 		if err != nil {
@@ -432,6 +506,7 @@ func (h *EventHandler) AddDepartmentToEvent(c *gin.Context) {
 			sub_model.META_DEPARTMENT_ID: deptID,
 		})
 	}
+	h.notifier.NotifyDepartmentsAssigned(c.Request.Context(), before, added)
 
 	c.JSON(200, gin.H{"message": "Departments added to event successfully"})
 }
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultInviteExpiry is used when the admin doesn't pick an expiry
+const defaultInviteExpiry = 72 * time.Hour
+
+type InviteHandler struct {
+	db repository.Database
+}
+
+func NewInviteHandler(db repository.Database) *InviteHandler {
+	return &InviteHandler{db: db}
+}
+
+func (h *InviteHandler) List(c *gin.Context) {
+	invites, err := h.db.Invites().ListInvites(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	output := make([]dtos.Invite_Output, 0, len(invites))
+	for _, invite := range invites {
+		output = append(output, toInviteOutput(invite))
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+func (h *InviteHandler) Create(c *gin.Context) {
+	var input dtos.Create_Invite_Input
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if !utils.IsEmailDomainAllowed(email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email domain is not allowed"})
+		return
+	}
+
+	volunteer, err := h.db.Volunteers().GetVolunteerByID(c.Request.Context(), input.VolunteerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Volunteer not found"})
+		return
+	}
+
+	// An invite would never be redeemed if the email can already log in
+	if existing, _ := h.db.AuthUsers().GetByUsername(c.Request.Context(), email); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A user with this email already exists"})
+		return
+	}
+
+	expiry := defaultInviteExpiry
+	if input.ExpiresInHours > 0 {
+		expiry = time.Duration(input.ExpiresInHours) * time.Hour
+	}
+
+	invite := models.Invite{
+		Email:       email,
+		VolunteerID: volunteer.ID,
+		AccessLevel: models.AuthLevel(input.AccessLevel),
+		Status:      models.InvitePending,
+		CreatedBy:   c.GetString("userID"),
+		CreatedAt:   time.Now().UTC(),
+		ExpiresAt:   time.Now().UTC().Add(expiry),
+	}
+
+	if err := h.db.Invites().CreateInvite(c.Request.Context(), &invite); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateAuditLog(c, h.db, sub_model.INVITE_CREATED, map[string]interface{}{
+		sub_model.META_INVITE_ID:         invite.ID,
+		sub_model.META_INVITE_EMAIL:      invite.Email,
+		sub_model.META_VOLUNTEER_ID:      volunteer.ID,
+		sub_model.META_VOLUNTEER_NAME:    volunteer.Name,
+		sub_model.META_ACCESS_LEVEL:      int(invite.AccessLevel),
+		sub_model.META_INVITE_EXPIRES_AT: invite.ExpiresAt.Format(time.RFC3339),
+	})
+
+	c.JSON(http.StatusCreated, toInviteOutput(&invite))
+}
+
+func (h *InviteHandler) Revoke(c *gin.Context) {
+	id := c.Param("id")
+
+	invite, err := h.db.Invites().GetInviteByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	}
+
+	if err := h.db.Invites().RevokeInvite(c.Request.Context(), id, c.GetString("userID")); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateAuditLog(c, h.db, sub_model.INVITE_REVOKED, map[string]interface{}{
+		sub_model.META_INVITE_ID:    invite.ID,
+		sub_model.META_INVITE_EMAIL: invite.Email,
+		sub_model.META_VOLUNTEER_ID: invite.VolunteerID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite revoked"})
+}
+
+// toInviteOutput reports pending invites past their expiry as "expired"
+func toInviteOutput(invite *models.Invite) dtos.Invite_Output {
+	status := string(invite.Status)
+	if invite.Status == models.InvitePending && !invite.IsUsable(time.Now().UTC()) {
+		status = "expired"
+	}
+
+	return dtos.Invite_Output{
+		ID:          invite.ID,
+		Email:       invite.Email,
+		VolunteerID: invite.VolunteerID,
+		AccessLevel: int(invite.AccessLevel),
+		Status:      status,
+		CreatedBy:   invite.CreatedBy,
+		CreatedAt:   invite.CreatedAt,
+		ExpiresAt:   invite.ExpiresAt,
+		RedeemedBy:  invite.RedeemedBy,
+		RedeemedAt:  invite.RedeemedAt,
+		RevokedBy:   invite.RevokedBy,
+		RevokedAt:   invite.RevokedAt,
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/repository"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPageLimit is the page size when only a cursor is given
+const defaultPageLimit = 50
+
+// listOptions builds the sort and paging options, sort is a field name with "-" in front for descending
+// paginated is false when neither limit nor cursor was given, then the whole list is returned like before
+func listOptions(sort, cursor string, limit int, allowedSorts ...string) (repository.ListOptions, bool, error) {
+	opts := repository.ListOptions{Cursor: cursor, Limit: limit}
+
+	if sort != "" {
+		opts.SortBy = strings.TrimPrefix(sort, "-")
+		opts.Desc = strings.HasPrefix(sort, "-")
+		allowed := false
+		for _, field := range allowedSorts {
+			if field == opts.SortBy {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return opts, false, fmt.Errorf("invalid sort '%s', expected one of %s", sort, strings.Join(allowedSorts, ", "))
+		}
+	}
+
+	paginated := limit > 0 || cursor != ""
+	if paginated && opts.Limit == 0 {
+		opts.Limit = defaultPageLimit
+	}
+	return opts, paginated, nil
+}
+
+// parseQueryTime reads a date filter, either RFC3339 or a plain date (midnight UTC)
+func parseQueryTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return &t, nil
+	}
+	return nil, fmt.Errorf("invalid %s '%s', use RFC3339 or YYYY-MM-DD", name, value)
+}
+
+// splitQueryList splits repeated and comma separated query values
+func splitQueryList(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// writeListPage responds with the page, or with just the items for unpaginated requests
+func writeListPage[T any](c *gin.Context, page repository.Page[T], opts repository.ListOptions, paginated bool) {
+	if !paginated {
+		c.JSON(http.StatusOK, page.Items)
+		return
+	}
+	c.JSON(http.StatusOK, dtos.Page_Output{
+		Items:      page.Items,
+		NextCursor: page.NextCursor,
+		Limit:      opts.Limit,
+	})
+}
+
+// writeListError responds to a failed list query, a bad cursor is the client's mistake
+func writeListError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
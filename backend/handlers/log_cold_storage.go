@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	dtos "sheduling-server/DTOs"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBundles lists the bundles archived logs were purged to, newest first
+// GET /api/logs/bundles
+func (h *LogHandler) ListBundles(c *gin.Context) {
+	bundles, err := h.db.LogBundles().ListBundles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.LogBundleListResponse{Bundles: bundles})
+}
+
+// PurgeLogs moves logs archived before a date to cold storage bundles and deletes them (admin only)
+// POST /api/logs/purge
+func (h *LogHandler) PurgeLogs(c *gin.Context) {
+	var req dtos.PurgeLogsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	archivedBefore, err := parseQueryTime("archivedBefore", req.ArchivedBefore)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if archivedBefore.After(time.Now().UTC()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "archivedBefore can't be in the future"})
+		return
+	}
+
+	bundles, err := utils.PurgeArchivedLogs(c.Request.Context(), h.db.Logs(), h.db.LogBundles(), *archivedBefore)
+
+	// Bundles written before a failure are deleted from the database, so they are always logged
+	purgedCount := 0
+	for _, bundle := range bundles {
+		purgedCount += bundle.LogCount
+		utils.CreateEnhancedLog(c, h.db, sub_model.LOGS_PURGED, sub_model.SEVERITY_INFO, map[string]interface{}{
+			sub_model.META_BUNDLE_ID:       bundle.ID,
+			sub_model.META_BUNDLE_STORE:    bundle.Store,
+			sub_model.META_BUNDLE_SHA256:   bundle.SHA256,
+			sub_model.META_RECORD_COUNT:    bundle.LogCount,
+			sub_model.META_ARCHIVED_BEFORE: archivedBefore.Format(time.RFC3339),
+		})
+	}
+
+	if errors.Is(err, utils.ErrNoColdStorage) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "bundles": bundles})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.PurgeLogsResponse{
+		PurgedCount: purgedCount,
+		Bundles:     bundles,
+		Message:     "Archived logs purged to cold storage",
+	})
+}
+
+// RestoreBundle imports the logs of a bundle back into the logs collection (admin only)
+// POST /api/logs/bundles/:id/restore
+func (h *LogHandler) RestoreBundle(c *gin.Context) {
+	bundle, err := h.db.LogBundles().GetBundle(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Log bundle not found"})
+		return
+	}
+
+	restoredCount, err := utils.RestoreLogBundle(c.Request.Context(), h.db.Logs(), h.db.LogBundles(), bundle, c.GetString("userID"))
+	if errors.Is(err, utils.ErrNoColdStorage) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateEnhancedLog(c, h.db, sub_model.LOGS_RESTORED, sub_model.SEVERITY_INFO, map[string]interface{}{
+		sub_model.META_BUNDLE_ID:     bundle.ID,
+		sub_model.META_BUNDLE_STORE:  bundle.Store,
+		sub_model.META_BUNDLE_SHA256: bundle.SHA256,
+		sub_model.META_RECORD_COUNT:  restoredCount,
+	})
+
+	c.JSON(http.StatusOK, dtos.RestoreLogBundleResponse{
+		RestoredCount: restoredCount,
+		Bundle:        bundle,
+		Message:       "Log bundle restored",
+	})
+}
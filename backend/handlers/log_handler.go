@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	dtos "sheduling-server/DTOs"
+	sub_model "sheduling-server/models/sub_models"
 	"sheduling-server/repository"
+	"sheduling-server/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +23,7 @@ func NewLogHandler(db repository.Database) *LogHandler {
 	return &LogHandler{db: db}
 }
 
-// List retrieves logs with enhanced filters
+// List retrieves logs with enhanced filters, paged with the cursor of the previous page
 // GET /api/logs
 func (h *LogHandler) List(c *gin.Context) {
 	var query dtos.LogQueryInput
@@ -36,54 +42,48 @@ func (h *LogHandler) List(c *gin.Context) {
 		query.Offset = 0
 	}
 
-	// Build filters map for enhanced query
-	filters := make(map[string]interface{})
-
-	if query.LogType != "" {
-		filters["type"] = string(query.LogType)
-	}
-	if query.UserID != "" {
-		filters["userId"] = query.UserID
-	}
-	if query.VolunteerID != "" {
-		filters["volunteerId"] = query.VolunteerID
-	}
-	if query.EventID != "" {
-		filters["eventId"] = query.EventID
-	}
-	if query.DepartmentID != "" {
-		filters["departmentId"] = query.DepartmentID
-	}
-	if query.Category != "" {
-		filters["category"] = query.Category
-	}
-	if query.Severity != "" {
-		filters["severity"] = query.Severity
-	}
-	if query.StartDate != "" {
-		filters["startDate"] = query.StartDate
+	from, err := parseQueryTime("startDate", query.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if query.EndDate != "" {
-		filters["endDate"] = query.EndDate
+	to, err := parseQueryTime("endDate", query.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	filters["includeArchived"] = query.IncludeArchived
 
-	// Use enhanced filters method
-	logs, total, err := h.db.Logs().GetLogsWithEnhancedFilters(
-		c.Request.Context(),
-		filters,
-		query.Limit,
-		query.Offset,
-	)
+	logQuery := repository.LogQuery{
+		Type:            query.LogType,
+		Category:        query.Category,
+		Severity:        query.Severity,
+		UserID:          query.UserID,
+		VolunteerID:     query.VolunteerID,
+		EventID:         query.EventID,
+		DepartmentID:    query.DepartmentID,
+		TargetUserID:    query.TargetUserID,
+		From:            from,
+		To:              to,
+		IncludeArchived: query.IncludeArchived,
+		Offset:          query.Offset,
+		ListOptions:     repository.ListOptions{Cursor: query.Cursor, Limit: query.Limit},
+	}
 
+	page, err := h.db.Logs().QueryLogs(c.Request.Context(), logQuery)
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+	total, err := h.db.Logs().CountLogs(c.Request.Context(), logQuery)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	response := dtos.LogListResponse{
-		Logs:  logs,
-		Total: total,
+		Logs:       page.Items,
+		Total:      total,
+		NextCursor: page.NextCursor,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -161,74 +161,259 @@ func (h *LogHandler) ArchiveLogs(c *gin.Context) {
 // GetCategories returns all available log categories
 // GET /api/logs/categories
 func (h *LogHandler) GetCategories(c *gin.Context) {
-	categories := []string{
-		"authentication",
-		"user_management",
-		"oauth",
-		"attendance",
-		"volunteer_management",
-		"event_management",
-		"department_management",
-		"batch_operations",
-		"system",
-	}
-
 	response := dtos.LogCategoriesResponse{
-		Categories: categories,
+		Categories: sub_model.LogCategories,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetStats retrieves log statistics for dashboard
+// VerifyChain checks the log hash chain and reports the first broken link
+// Purged logs count as unverified unless bundles=true, which reads every bundle covering the range
+// GET /api/logs/verify
+func (h *LogHandler) VerifyChain(c *gin.Context) {
+	var query dtos.LogVerifyQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var loadBundle repository.LogBundleLoader
+	if query.Bundles {
+		if _, err := utils.LogColdStore(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		loadBundle = utils.LoadLogBundle
+	}
+
+	report, err := h.db.Logs().VerifyLogChain(c.Request.Context(), query.FromSeq, loadBundle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !report.Valid {
+		utils.CreateEnhancedLog(c, h.db, sub_model.SYSTEM_ERROR, sub_model.SEVERITY_ERROR, map[string]interface{}{
+			sub_model.META_ERROR_TYPE:    "log_chain_broken",
+			sub_model.META_ERROR_DETAILS: report.FirstBreak.Reason,
+			"seq":                        report.FirstBreak.Seq,
+			"breaks":                     report.Breaks,
+		})
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// logCountWorkers is how many count queries run at the same time
+const logCountWorkers = 10
+
+// maxHistogramBuckets keeps a histogram to a bounded number of count queries
+const maxHistogramBuckets = 400
+
+// GetStats retrieves log statistics for dashboard, counted with aggregation queries
 // GET /api/logs/stats
 func (h *LogHandler) GetStats(c *gin.Context) {
-	// Get all logs (this could be optimized with dedicated stat queries)
-	allLogs, total, err := h.db.Logs().ListLogs(c.Request.Context(), 10000, 0)
+	now := time.Now().UTC()
+
+	// Every count is its own query, the first five are totals and the rest go by type, category and severity
+	queries := []repository.LogQuery{
+		{IncludeArchived: true},
+		{IncludeArchived: false},
+		{IncludeArchived: true, From: timePtr(now.Add(-24 * time.Hour))},
+		{IncludeArchived: true, From: timePtr(now.AddDate(0, 0, -7))},
+		{IncludeArchived: true, From: timePtr(now.AddDate(0, 0, -30))},
+	}
+	for _, logType := range sub_model.AllLogTypes {
+		queries = append(queries, repository.LogQuery{IncludeArchived: true, Type: logType})
+	}
+	for _, category := range sub_model.LogCategories {
+		queries = append(queries, repository.LogQuery{IncludeArchived: true, Category: category})
+	}
+	severities := []string{sub_model.SEVERITY_INFO, sub_model.SEVERITY_WARNING, sub_model.SEVERITY_ERROR}
+	for _, severity := range severities {
+		queries = append(queries, repository.LogQuery{IncludeArchived: true, Severity: severity})
+	}
+
+	counts, err := h.countLogs(c.Request.Context(), queries)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Initialize counters
-	byType := make(map[string]int)
-	byCategory := make(map[string]int)
-	bySeverity := make(map[string]int)
-	archivedCount := 0
-	recentCount := 0
+	response := dtos.LogStatsResponse{
+		TotalLogs:  counts[0],
+		Archived:   counts[0] - counts[1],
+		RecentLogs: counts[2],
+		Last7Days:  counts[3],
+		Last30Days: counts[4],
+		ByType:     make(map[string]int),
+		ByCategory: make(map[string]int),
+		BySeverity: make(map[string]int),
+	}
+	for i, query := range queries[5:] {
+		count := counts[5+i]
+		if count == 0 {
+			continue
+		}
+		switch {
+		case query.Type != "":
+			response.ByType[string(query.Type)] = count
+		case query.Category != "":
+			response.ByCategory[query.Category] = count
+		case query.Severity != "":
+			response.BySeverity[query.Severity] = count
+		}
+	}
 
-	// Calculate stats
-	for _, log := range allLogs {
-		// Count by type
-		byType[string(log.Type)]++
+	c.JSON(http.StatusOK, response)
+}
 
-		// Count by category
-		if log.Category != "" {
-			byCategory[log.Category]++
-		}
+// GetHistogram counts logs per hour, day or week over a date range
+// GET /api/logs/histogram
+func (h *LogHandler) GetHistogram(c *gin.Context) {
+	var query dtos.LogHistogramQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Bucket == "" {
+		query.Bucket = "day"
+	}
 
-		// Count by severity
-		if log.Severity != "" {
-			bySeverity[log.Severity]++
+	from, err := parseQueryTime("from", query.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := parseQueryTime("to", query.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	end := time.Now().UTC()
+	if to != nil {
+		end = to.UTC()
+	}
+	var start time.Time
+	if from != nil {
+		start = from.UTC()
+	} else {
+		switch query.Bucket {
+		case "hour":
+			start = end.Add(-24 * time.Hour)
+		case "day":
+			start = end.AddDate(0, 0, -30)
+		case "week":
+			start = end.AddDate(0, 0, -7*12)
 		}
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
 
-		// Count archived
-		if log.IsArchived {
-			archivedCount++
+	// Buckets start on whole hours, days or weeks (Monday) in UTC
+	bucketStarts := []time.Time{}
+	for t := truncateToBucket(start, query.Bucket); t.Before(end); t = nextBucket(t, query.Bucket) {
+		if len(bucketStarts) == maxHistogramBuckets {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("range has more than %d %s buckets, use a larger bucket or a shorter range", maxHistogramBuckets, query.Bucket)})
+			return
 		}
+		bucketStarts = append(bucketStarts, t)
+	}
 
-		// Count recent (last 24 hours) - simplified
-		// In production, you'd query with date filter
+	queries := make([]repository.LogQuery, len(bucketStarts))
+	for i, bucketStart := range bucketStarts {
+		// Each bucket runs up to the start of the next one, the range bounds are inclusive
+		bucketEnd := nextBucket(bucketStart, query.Bucket).Add(-time.Nanosecond)
+		queries[i] = repository.LogQuery{
+			Type:            query.LogType,
+			Category:        query.Category,
+			Severity:        query.Severity,
+			From:            timePtr(bucketStart),
+			To:              timePtr(bucketEnd),
+			IncludeArchived: true,
+		}
+	}
+	counts, err := h.countLogs(c.Request.Context(), queries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	response := dtos.LogStatsResponse{
-		TotalLogs:  total,
-		ByType:     byType,
-		ByCategory: byCategory,
-		BySeverity: bySeverity,
-		RecentLogs: recentCount,
-		Archived:   archivedCount,
+	response := dtos.LogHistogramResponse{
+		Bucket:  query.Bucket,
+		From:    start,
+		To:      end,
+		Buckets: make([]dtos.LogHistogramBucket, len(bucketStarts)),
+	}
+	for i, bucketStart := range bucketStarts {
+		response.Buckets[i] = dtos.LogHistogramBucket{Start: bucketStart, Count: counts[i]}
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// countLogs runs the count queries, logCountWorkers at a time, and returns the counts in the same order
+func (h *LogHandler) countLogs(ctx context.Context, queries []repository.LogQuery) ([]int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	counts := make([]int, len(queries))
+	errs := make([]error, len(queries))
+	workers := make(chan struct{}, logCountWorkers)
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Go(func() {
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			if ctx.Err() != nil {
+				return // another count already failed
+			}
+			counts[i], errs[i] = h.db.Logs().CountLogs(ctx, query)
+			if errs[i] != nil {
+				cancel()
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// truncateToBucket returns the start of the bucket t falls in
+func truncateToBucket(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		daysSinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -daysSinceMonday)
+	default:
+		return day
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at t
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// timePtr returns a pointer to t
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logStreamHeartbeat keeps proxies from closing a quiet stream
+const logStreamHeartbeat = 25 * time.Second
+
+// maxLogStreamBackfill is the most missed logs sent to a reconnecting client
+const maxLogStreamBackfill = 500
+
+type LogStreamHandler struct {
+	db     repository.Database
+	broker *utils.LogBroker
+}
+
+// NewLogStreamHandler takes the broker the database publishes new logs to
+func NewLogStreamHandler(db repository.Database, broker *utils.LogBroker) *LogStreamHandler {
+	return &LogStreamHandler{db: db, broker: broker}
+}
+
+// Stream sends new logs matching the filters as Server-Sent Events while the connection is open (admin only)
+// GET /api/logs/stream
+// Every log is a "log" event, a client reconnecting with Last-Event-ID gets the logs it missed first,
+// a "gap" event means it missed more than are sent and should reload from /api/logs
+func (h *LogStreamHandler) Stream(c *gin.Context) {
+	var input dtos.LogStreamQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := repository.LogQuery{
+		Type:         input.LogType,
+		Category:     input.Category,
+		Severity:     input.Severity,
+		UserID:       input.UserID,
+		VolunteerID:  input.VolunteerID,
+		EventID:      input.EventID,
+		DepartmentID: input.DepartmentID,
+		TargetUserID: input.TargetUserID,
+	}
+
+	// Subscribing before catching up, so no log falls between the two
+	sub, err := h.broker.Subscribe(query)
+	if errors.Is(err, utils.ErrTooManyLogStreams) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx would buffer the stream otherwise
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	// Logs already sent while catching up, they can arrive again from the subscription
+	sent := map[string]bool{}
+	if since, lastID, ok := parseLogEventID(c.GetHeader("Last-Event-ID")); ok {
+		query.From = &since
+		query.ListOptions = repository.ListOptions{Limit: maxLogStreamBackfill}
+		page, err := h.db.Logs().QueryLogs(c.Request.Context(), query)
+		if err != nil {
+			writeStreamEvent(c.Writer, "error", "", gin.H{"error": err.Error()})
+			return
+		}
+		if page.NextCursor != "" {
+			writeStreamEvent(c.Writer, "gap", "", gin.H{"since": since})
+		}
+		for _, log := range slices.Backward(page.Items) {
+			if log.ID == lastID {
+				continue
+			}
+			if err := writeStreamEvent(c.Writer, "log", logEventID(log), toLogResponse(log)); err != nil {
+				return
+			}
+			sent[log.ID] = true
+		}
+		c.Writer.Flush()
+	}
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case log, ok := <-sub.Logs:
+			if !ok {
+				return // fell behind, the client reconnects and catches up
+			}
+			if sent[log.ID] {
+				continue
+			}
+			if err := writeStreamEvent(c.Writer, "log", logEventID(log), toLogResponse(log)); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeStreamEvent writes one Server-Sent Event with JSON data
+func writeStreamEvent(w io.Writer, event, id string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// logEventID is the time and ID of a log, so a reconnecting client can be caught up from that time
+func logEventID(log *models.SystemLog) string {
+	return strconv.FormatInt(log.TimeDetected.UnixMicro(), 10) + "-" + log.ID
+}
+
+// parseLogEventID reads back a logEventID
+func parseLogEventID(id string) (time.Time, string, bool) {
+	micros, logID, found := strings.Cut(id, "-")
+	if !found || logID == "" {
+		return time.Time{}, "", false
+	}
+	unixMicro, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.UnixMicro(unixMicro).UTC(), logID, true
+}
+
+// toLogResponse converts a log to its response shape
+func toLogResponse(log *models.SystemLog) dtos.LogResponse {
+	return dtos.LogResponse{
+		ID:           log.ID,
+		TimeDetected: log.TimeDetected,
+		Metadata:     log.Metadata,
+		Type:         log.Type,
+		LastUpdated:  log.LastUpdated,
+		Category:     log.Category,
+		Severity:     log.Severity,
+		IsArchived:   log.IsArchived,
+		ArchiveDate:  log.ArchiveDate,
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	"sheduling-server/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	db repository.Database
+}
+
+func NewNotificationHandler(db repository.Database) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+// List returns the notification delivery log, newest first (admin only)
+// GET /api/notifications
+func (h *NotificationHandler) List(c *gin.Context) {
+	var input dtos.List_Notifications_Query
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts, _, err := listOptions("", input.Cursor, input.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultPageLimit
+	}
+
+	page, err := h.db.Notifications().QueryNotifications(c.Request.Context(), repository.NotificationQuery{
+		VolunteerID: input.VolunteerID,
+		EventID:     input.EventID,
+		Status:      models.NotificationStatus(input.Status),
+		ListOptions: opts,
+	})
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+	writeListPage(c, page, opts, true)
+}
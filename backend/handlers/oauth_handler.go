@@ -2,8 +2,7 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	dtos "sheduling-server/DTOs"
 	"sheduling-server/models"
@@ -24,103 +23,130 @@ func NewOAuthHandler(db repository.Database) *OAuthHandler {
 	return &OAuthHandler{db: db}
 }
 
-// GetGoogleLoginURL generates the Google OAuth login URL
-func (h *OAuthHandler) GetGoogleLoginURL(c *gin.Context) {
-	state := utils.GenerateRandomString(32) // Create a secure random state
-	url := utils.GoogleOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
+// ListProviders returns the OAuth providers that are configured on this server
+func (h *OAuthHandler) ListProviders(c *gin.Context) {
+	providers := []dtos.OAuthProviderInfo{}
+	for _, provider := range utils.ListOAuthProviders() {
+		providers = append(providers, dtos.OAuthProviderInfo{
+			Name:        string(provider.Name()),
+			DisplayName: provider.DisplayName(),
+		})
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"url":   url,
-		"state": state,
-	})
+	c.JSON(http.StatusOK, providers)
 }
 
-// GoogleCallback handles the OAuth callback from Google
-func (h *OAuthHandler) GoogleCallback(c *gin.Context) {
-	var input dtos.GoogleOAuthCallbackDTO
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// GetLoginURL generates the login URL for the requested provider
+func (h *OAuthHandler) GetLoginURL(c *gin.Context) {
+	provider, ok := h.getProvider(c)
+	if !ok {
 		return
 	}
 
-	// Exchange code for token
-	token, err := utils.GoogleOAuthConfig.Exchange(context.Background(), input.Code)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to exchange token: " + err.Error()})
+	h.startFlow(c, provider, models.OAuthStateLogin, "")
+}
+
+// GetLinkURL generates the provider URL for linking an account to the logged-in user
+func (h *OAuthHandler) GetLinkURL(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// Get user info from Google
-	client := utils.GoogleOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+	provider, ok := h.getProvider(c)
+	if !ok {
 		return
 	}
-	defer resp.Body.Close()
 
-	var googleUser struct {
-		Email         string `json:"email"`
-		VerifiedEmail bool   `json:"verified_email"`
-		Name          string `json:"name"`
-		ID            string `json:"id"`
+	h.startFlow(c, provider, models.OAuthStateLink, userID)
+}
+
+// Callback handles the OAuth callback from the requested provider
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	provider, ok := h.getProvider(c)
+	if !ok {
+		return
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode user info"})
+
+	var input dtos.OAuthCallbackDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if !googleUser.VerifiedEmail {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email not verified with Google"})
+	token, userInfo, ok := h.exchange(c, provider, input.Code, input.State, models.OAuthStateLogin, "")
+	if !ok {
 		return
 	}
 
-	// First, check if Google is already linked to an account
-	existingUser, err := h.db.AuthUsers().GetByGoogleEmail(context.Background(), googleUser.Email)
+	// First, check if this provider account is already linked to a user
+	existingUser, err := h.db.AuthUsers().GetByOAuthAccount(c.Request.Context(), provider.Name(), userInfo.Subject, userInfo.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up user: " + err.Error()})
+		return
+	}
 
 	var authUser *models.AuthUser
 	isNewUser := false
 
-	if err != nil || existingUser == nil {
-		// Google not linked, check if user exists with this email as username
-		existingUser, err = h.db.AuthUsers().GetByUsername(context.Background(), googleUser.Email)
+	if existingUser == nil {
+		// Provider account not linked, the email only matches on this first login
+		existingUser, err = h.db.AuthUsers().GetByUsername(context.Background(), userInfo.Email)
 
 		if err != nil || existingUser == nil {
-			// Create new user with Google OAuth
+			// New accounts can only be created from a valid invite
+			if !utils.IsEmailDomainAllowed(userInfo.Email) {
+				h.logLoginFailure(c, provider, models.OAuthStateLogin, "", "email_domain_not_allowed")
+				c.JSON(http.StatusForbidden, gin.H{"error": "Email domain is not allowed"})
+				return
+			}
+
+			invite, err := h.db.Invites().GetUsableInviteByEmail(c.Request.Context(), userInfo.Email)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up invite: " + err.Error()})
+				return
+			}
+			if invite == nil {
+				h.logLoginFailure(c, provider, models.OAuthStateLogin, "", "no_valid_invite")
+				c.JSON(http.StatusForbidden, gin.H{"error": "No valid invite found for " + userInfo.Email + ", ask an admin for an invite"})
+				return
+			}
+
 			isNewUser = true
 			authUser = &models.AuthUser{
-				Username:    googleUser.Email, // Use email as username
-				AccessLevel: models.DEPTHEAD,  // Default to department head
-				ThirdAuth: sub_model.OAuthToken{
-					Provider:     sub_model.Google,
-					Email:        googleUser.Email,
-					AccessToken:  token.AccessToken,
-					RefreshToken: token.RefreshToken,
-					TokenType:    token.TokenType,
-					Expiry:       token.Expiry,
-					LinkedAt:     time.Now().UTC(),
-				},
+				VolunteerID: invite.VolunteerID,
+				Username:    userInfo.Email, // Use email as username
+				AccessLevel: invite.AccessLevel,
 				CreatedAt:   time.Now().UTC(),
 				LastUpdated: time.Now().UTC(),
 				IsDisabled:  false,
 			}
+			authUser.SetOAuthAccount(newOAuthToken(provider, userInfo, token))
 
-			err := h.db.AuthUsers().CreateUser(context.Background(), authUser)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user: " + err.Error()})
+			if err := h.db.Invites().RedeemInvite(c.Request.Context(), invite.ID, authUser); err != nil {
+				h.logLoginFailure(c, provider, models.OAuthStateLogin, "", "invite_redeem_failed")
+				c.JSON(http.StatusConflict, gin.H{"error": "Failed to redeem invite: " + err.Error()})
 				return
 			}
+
+			utils.CreateAuditLogWithUserInfo(c.Request.Context(), h.db, sub_model.INVITE_REDEEMED, authUser.ID, authUser.Username, map[string]interface{}{
+				sub_model.META_INVITE_ID:    invite.ID,
+				sub_model.META_INVITE_EMAIL: invite.Email,
+				sub_model.META_VOLUNTEER_ID: invite.VolunteerID,
+				sub_model.META_ACCESS_LEVEL: int(invite.AccessLevel),
+				sub_model.META_PROVIDER:     string(provider.Name()),
+				"invitedBy":                 invite.CreatedBy,
+			})
 		} else {
-			// User exists with this email as username, link Google OAuth
-			existingUser.ThirdAuth = sub_model.OAuthToken{
-				Provider:     sub_model.Google,
-				Email:        googleUser.Email,
-				AccessToken:  token.AccessToken,
-				RefreshToken: token.RefreshToken,
-				TokenType:    token.TokenType,
-				Expiry:       token.Expiry,
-				LinkedAt:     time.Now().UTC(),
+			// User exists with this email as username, link the provider
+			// unless another account of the same provider is linked already
+			if linked := existingUser.OAuthAccount(provider.Name()); linked != nil && linked.Subject != "" {
+				h.logLoginFailure(c, provider, models.OAuthStateLogin, existingUser.ID, "other_account_linked")
+				c.JSON(http.StatusConflict, gin.H{"error": "A different " + provider.DisplayName() + " account is linked to this user"})
+				return
 			}
+			existingUser.SetOAuthAccount(newOAuthToken(provider, userInfo, token))
 			existingUser.LastUpdated = time.Now().UTC()
 
 			err = h.db.AuthUsers().UpdateUser(context.Background(), existingUser)
@@ -131,12 +157,18 @@ func (h *OAuthHandler) GoogleCallback(c *gin.Context) {
 			authUser = existingUser
 		}
 	} else {
-		// Google already linked, just update the token
-		existingUser.ThirdAuth.AccessToken = token.AccessToken
-		existingUser.ThirdAuth.RefreshToken = token.RefreshToken
-		existingUser.ThirdAuth.TokenType = token.TokenType
-		existingUser.ThirdAuth.Expiry = token.Expiry
-		existingUser.ThirdAuth.LinkedAt = time.Now().UTC()
+		// Provider already linked, just update the token
+		previous := *existingUser.OAuthAccount(provider.Name())
+		account := newOAuthToken(provider, userInfo, token)
+		account.LinkedAt = previous.LinkedAt
+		if token.RefreshToken == "" {
+			// Providers only return a refresh token on first consent
+			if err := utils.OpenOAuthToken(&previous); err != nil {
+				slog.WarnContext(c.Request.Context(), "Failed to decrypt stored OAuth tokens", "userId", existingUser.ID, "error", err)
+			}
+			account.RefreshToken = previous.RefreshToken
+		}
+		existingUser.SetOAuthAccount(account)
 		existingUser.LastUpdated = time.Now().UTC()
 
 		err = h.db.AuthUsers().UpdateUser(context.Background(), existingUser)
@@ -160,11 +192,11 @@ func (h *OAuthHandler) GoogleCallback(c *gin.Context) {
 		logType = sub_model.USER_CREATED // New user created via OAuth
 	}
 	utils.CreateAuditLogWithUserInfo(context.Background(), h.db, logType, authUser.ID, authUser.Username, map[string]interface{}{
-		"loginMethod": "google_oauth",
-		"provider":    "google",
-		"email":       googleUser.Email,
-		"isNewUser":   isNewUser,
-		"accessLevel": int(authUser.AccessLevel),
+		sub_model.META_LOGIN_METHOD: string(provider.Name()) + "_oauth",
+		sub_model.META_PROVIDER:     string(provider.Name()),
+		sub_model.META_EMAIL:        userInfo.Email,
+		sub_model.META_IS_NEW_USER:  isNewUser,
+		sub_model.META_ACCESS_LEVEL: int(authUser.AccessLevel),
 	})
 
 	c.JSON(http.StatusOK, dtos.OAuthLoginResponse{
@@ -177,104 +209,170 @@ func (h *OAuthHandler) GoogleCallback(c *gin.Context) {
 	})
 }
 
-// LinkGoogleAccount links a Google account to an existing logged-in user
-func (h *OAuthHandler) LinkGoogleAccount(c *gin.Context) {
+// LinkAccount links an account from the requested provider to an existing logged-in user
+func (h *OAuthHandler) LinkAccount(c *gin.Context) {
 	userID := c.GetString("userID")
-	fmt.Println("=== LinkGoogleAccount called ===")
-	fmt.Printf("UserID from context: %s\n", userID)
-
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	var input dtos.LinkGoogleAccountDTO
+	provider, ok := h.getProvider(c)
+	if !ok {
+		return
+	}
+
+	var input dtos.LinkOAuthAccountDTO
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 		return
 	}
 
-	if input.Code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization code is required"})
+	token, userInfo, ok := h.exchange(c, provider, input.Code, input.State, models.OAuthStateLink, userID)
+	if !ok {
 		return
 	}
 
-	// Exchange code for token
-	token, err := utils.GoogleOAuthConfig.Exchange(context.Background(), input.Code)
+	// Check if the provider account is already linked to another user
+	existingUser, err := h.db.AuthUsers().GetByOAuthAccount(c.Request.Context(), provider.Name(), userInfo.Subject, userInfo.Email)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to exchange token: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up linked accounts: " + err.Error()})
+		return
+	}
+	if existingUser != nil && existingUser.ID != userID {
+		c.JSON(http.StatusConflict, gin.H{"error": "This " + provider.DisplayName() + " account is already linked to another user"})
 		return
 	}
 
-	// Get user info from Google
-	client := utils.GoogleOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	// Get current user
+	user, err := h.db.AuthUsers().GetUserByID(context.Background(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-	defer resp.Body.Close()
 
-	var googleUser struct {
-		Email         string `json:"email"`
-		VerifiedEmail bool   `json:"verified_email"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode user info"})
+	// Only the link of this provider is replaced, other providers stay linked
+	user.SetOAuthAccount(newOAuthToken(provider, userInfo, token))
+	user.LastUpdated = time.Now().UTC()
+
+	err = h.db.AuthUsers().UpdateUser(context.Background(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link " + provider.DisplayName() + " account"})
 		return
 	}
 
-	if !googleUser.VerifiedEmail {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email not verified with Google"})
-		return
+	// Log account linking
+	utils.CreateAuditLogWithUserInfo(context.Background(), h.db, sub_model.OAUTH_LINKED, user.ID, user.Username, map[string]interface{}{
+		sub_model.META_PROVIDER: string(provider.Name()),
+		sub_model.META_EMAIL:    userInfo.Email,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  provider.DisplayName() + " account linked successfully",
+		"provider": string(provider.Name()),
+		"email":    userInfo.Email,
+	})
+}
+
+// getProvider resolves the :provider route param, writing a 404 if it is not configured
+func (h *OAuthHandler) getProvider(c *gin.Context) (utils.OAuthProvider, bool) {
+	provider, ok := utils.GetOAuthProvider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OAuth provider not configured: " + c.Param("provider")})
+		return nil, false
 	}
+	return provider, true
+}
 
-	// Check if email is already linked to another account
-	existingUser, _ := h.db.AuthUsers().GetByGoogleEmail(context.Background(), googleUser.Email)
-	if existingUser != nil && existingUser.ID != userID {
-		c.JSON(http.StatusConflict, gin.H{"error": "This Google account is already linked to another user"})
+// startFlow stores a new login attempt and responds with the provider's auth URL
+func (h *OAuthHandler) startFlow(c *gin.Context, provider utils.OAuthProvider, purpose models.OAuthStatePurpose, userID string) {
+	state := utils.NewOAuthState(string(provider.Name()), purpose, userID)
+
+	url, err := provider.AuthCodeURL(c.Request.Context(), state.ID, utils.AuthURLOptions(state)...)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to build login URL: " + err.Error()})
 		return
 	}
 
-	// Get current user
-	user, err := h.db.AuthUsers().GetUserByID(context.Background(), userID)
-	if err != nil {
-		fmt.Printf("Failed to get user by ID %s: %v\n", userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := h.db.OAuthStates().CreateState(c.Request.Context(), state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login: " + err.Error()})
 		return
 	}
 
-	fmt.Printf("Found user: ID=%s, Username=%s\n", user.ID, user.Username)
+	c.JSON(http.StatusOK, gin.H{
+		"url":       url,
+		"state":     state.ID,
+		"expiresAt": state.ExpiresAt.Format(time.RFC3339),
+	})
+}
 
-	// Update user with Google OAuth
-	user.ThirdAuth = sub_model.OAuthToken{
-		Provider:     sub_model.Google,
-		Email:        googleUser.Email,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenType:    token.TokenType,
-		Expiry:       token.Expiry,
-		LinkedAt:     time.Now().UTC(),
+// exchange validates the stored state, trades the code for tokens and returns the verified identity
+// Every failure writes the error response and is logged as USER_LOGIN_FAILED
+func (h *OAuthHandler) exchange(c *gin.Context, provider utils.OAuthProvider, code, stateID string, purpose models.OAuthStatePurpose, userID string) (*oauth2.Token, *utils.OAuthUserInfo, bool) {
+	fail := func(status int, message, reason string) (*oauth2.Token, *utils.OAuthUserInfo, bool) {
+		h.logLoginFailure(c, provider, purpose, userID, reason)
+		c.JSON(status, gin.H{"error": message})
+		return nil, nil, false
 	}
-	user.LastUpdated = time.Now().UTC()
 
-	err = h.db.AuthUsers().UpdateUser(context.Background(), user)
+	if code == "" {
+		return fail(http.StatusBadRequest, "Authorization code is required", "missing_code")
+	}
+	if stateID == "" {
+		return fail(http.StatusBadRequest, "OAuth state is required", "missing_state")
+	}
+
+	state, err := h.db.OAuthStates().ConsumeState(c.Request.Context(), stateID)
 	if err != nil {
-		fmt.Printf("Failed to update user: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link Google account"})
-		return
+		return fail(http.StatusBadRequest, "Invalid or expired OAuth state", "unknown_state")
+	}
+	if err := utils.ValidateOAuthState(state, string(provider.Name()), purpose, userID); err != nil {
+		return fail(http.StatusBadRequest, "Invalid or expired OAuth state", err.Error())
 	}
 
-	fmt.Printf("Successfully linked Google account %s to user %s\n", googleUser.Email, user.Username)
+	token, err := provider.Exchange(c.Request.Context(), code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return fail(http.StatusBadRequest, "Failed to exchange token: "+err.Error(), "token_exchange_failed")
+	}
 
-	// Log Google account linking
-	utils.CreateAuditLogWithUserInfo(context.Background(), h.db, sub_model.OAUTH_LINKED, user.ID, user.Username, map[string]interface{}{
-		"provider": "google",
-		"email":    googleUser.Email,
-	})
+	userInfo, err := provider.UserInfo(c.Request.Context(), token, state.Nonce)
+	if err != nil {
+		return fail(http.StatusUnauthorized, "Failed to verify user info: "+err.Error(), "id_token_invalid")
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Google account linked successfully",
-		"email":   googleUser.Email,
+	if userInfo.Subject == "" {
+		return fail(http.StatusUnauthorized, "Failed to verify user info: no subject from "+provider.DisplayName(), "missing_subject")
+	}
+
+	if !userInfo.EmailVerified {
+		return fail(http.StatusBadRequest, "Email not verified with "+provider.DisplayName(), "email_not_verified")
+	}
+
+	return token, userInfo, true
+}
+
+// logLoginFailure records a rejected OAuth callback as USER_LOGIN_FAILED
+func (h *OAuthHandler) logLoginFailure(c *gin.Context, provider utils.OAuthProvider, purpose models.OAuthStatePurpose, userID, reason string) {
+	utils.CreateLogWithSeverity(c.Request.Context(), h.db, sub_model.USER_LOGIN_FAILED, sub_model.SEVERITY_WARNING, map[string]interface{}{
+		sub_model.META_LOGIN_METHOD: string(provider.Name()) + "_oauth",
+		sub_model.META_PROVIDER:     string(provider.Name()),
+		sub_model.META_REASON:       reason,
+		sub_model.META_USER_ID:      userID,
+		"purpose":                   string(purpose),
+		"clientIp":                  c.ClientIP(),
 	})
 }
+
+// newOAuthToken builds the linked account record for a freshly exchanged token
+func newOAuthToken(provider utils.OAuthProvider, userInfo *utils.OAuthUserInfo, token *oauth2.Token) sub_model.OAuthToken {
+	return sub_model.OAuthToken{
+		Provider:     provider.Name(),
+		Subject:      userInfo.Subject,
+		Email:        userInfo.Email,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		LinkedAt:     time.Now().UTC(),
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionRuleHandler struct {
+	db        repository.Database
+	scheduler *utils.RetentionScheduler
+}
+
+// NewRetentionRuleHandler takes the scheduler that applies the rules, for its default retention and dry runs
+func NewRetentionRuleHandler(db repository.Database, scheduler *utils.RetentionScheduler) *RetentionRuleHandler {
+	return &RetentionRuleHandler{db: db, scheduler: scheduler}
+}
+
+// List returns the retention rules and the default retention (admin only)
+// GET /api/logs/retention-rules
+func (h *RetentionRuleHandler) List(c *gin.Context) {
+	rules, err := h.db.RetentionRules().ListRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.RetentionRuleListResponse{
+		Rules:                rules,
+		DefaultRetentionDays: h.scheduler.RetentionDays(),
+	})
+}
+
+// Create adds a retention rule, one per category, type and severity (admin only)
+// POST /api/logs/retention-rules
+func (h *RetentionRuleHandler) Create(c *gin.Context) {
+	var req dtos.CreateRetentionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Category != "" && !slices.Contains(sub_model.LogCategories, req.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown log category"})
+		return
+	}
+	if req.Type != "" && !slices.Contains(sub_model.AllLogTypes, req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown log type"})
+		return
+	}
+	if req.Category != "" && req.Type != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set either a category or a type, a type belongs to one category"})
+		return
+	}
+
+	now := time.Now().UTC()
+	rule := models.RetentionRule{
+		Category:      req.Category,
+		Type:          req.Type,
+		Severity:      req.Severity,
+		RetentionDays: req.RetentionDays,
+		Description:   req.Description,
+		CreatedAt:     now,
+		CreatedBy:     c.GetString("userID"),
+		LastUpdated:   now,
+		UpdatedBy:     c.GetString("userID"),
+	}
+	if err := h.db.RetentionRules().CreateRule(c.Request.Context(), &rule); err != nil {
+		if errors.Is(err, repository.ErrRetentionRuleExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateEnhancedLog(c, h.db, sub_model.CONFIGURATION_CHANGED, sub_model.SEVERITY_INFO, map[string]interface{}{
+		sub_model.META_CONFIG_KEY: "logRetention." + rule.ID,
+		sub_model.META_OLD_VALUE:  nil,
+		sub_model.META_NEW_VALUE:  rule,
+	})
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// Update changes the retention or description of a rule (admin only)
+// PUT /api/logs/retention-rules/:id
+func (h *RetentionRuleHandler) Update(c *gin.Context) {
+	var req dtos.UpdateRetentionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.db.RetentionRules().GetRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention rule not found"})
+		return
+	}
+	old := *rule
+
+	if req.RetentionDays != nil {
+		rule.RetentionDays = *req.RetentionDays
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+	if rule.RetentionDays == old.RetentionDays && rule.Description == old.Description {
+		c.JSON(http.StatusOK, rule)
+		return
+	}
+	rule.LastUpdated = time.Now().UTC()
+	rule.UpdatedBy = c.GetString("userID")
+
+	if err := h.db.RetentionRules().UpdateRule(c.Request.Context(), rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateEnhancedLog(c, h.db, sub_model.CONFIGURATION_CHANGED, sub_model.SEVERITY_INFO, map[string]interface{}{
+		sub_model.META_CONFIG_KEY: "logRetention." + rule.ID,
+		sub_model.META_OLD_VALUE:  old,
+		sub_model.META_NEW_VALUE:  rule,
+	})
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// Delete removes a rule, its logs fall back to the next matching rule (admin only)
+// DELETE /api/logs/retention-rules/:id
+func (h *RetentionRuleHandler) Delete(c *gin.Context) {
+	rule, err := h.db.RetentionRules().GetRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention rule not found"})
+		return
+	}
+
+	if err := h.db.RetentionRules().DeleteRule(c.Request.Context(), rule.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateEnhancedLog(c, h.db, sub_model.CONFIGURATION_CHANGED, sub_model.SEVERITY_INFO, map[string]interface{}{
+		sub_model.META_CONFIG_KEY: "logRetention." + rule.ID,
+		sub_model.META_OLD_VALUE:  rule,
+		sub_model.META_NEW_VALUE:  nil,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Retention rule deleted successfully"})
+}
+
+// DryRun reports what the next archival would archive under the current rules, without archiving (admin only)
+// GET /api/logs/retention/dry-run
+func (h *RetentionRuleHandler) DryRun(c *gin.Context) {
+	report, err := h.scheduler.ApplyRetention(c.Request.Context(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"net/http"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAPIKeyExpiry is used when the admin doesn't pick an expiry
+const defaultAPIKeyExpiry = 90 * 24 * time.Hour
+
+type ServiceAccountHandler struct {
+	db repository.Database
+}
+
+func NewServiceAccountHandler(db repository.Database) *ServiceAccountHandler {
+	return &ServiceAccountHandler{db: db}
+}
+
+func (h *ServiceAccountHandler) List(c *gin.Context) {
+	accounts, err := h.db.ServiceAccounts().ListServiceAccounts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+func (h *ServiceAccountHandler) Create(c *gin.Context) {
+	var input dtos.Create_ServiceAccount_Input
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if invalid := invalidScopes(input.Scopes, models.APIKeyScopes); len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scopes", "scopes": invalid})
+		return
+	}
+
+	account := models.ServiceAccount{
+		Name:        input.Name,
+		Description: input.Description,
+		Scopes:      input.Scopes,
+		IsDisabled:  false,
+		CreatedBy:   c.GetString("userID"),
+		CreatedAt:   time.Now().UTC(),
+		LastUpdated: time.Now().UTC(),
+	}
+
+	if err := h.db.ServiceAccounts().CreateServiceAccount(c.Request.Context(), &account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateAuditLog(c, h.db, sub_model.SERVICE_ACCOUNT_CREATED, map[string]interface{}{
+		sub_model.META_SERVICE_ACCOUNT_ID:   account.ID,
+		sub_model.META_SERVICE_ACCOUNT_NAME: account.Name,
+		sub_model.META_SCOPES:               account.Scopes,
+	})
+
+	c.JSON(http.StatusCreated, account)
+}
+
+func (h *ServiceAccountHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	var input dtos.Update_ServiceAccount_Input
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.db.ServiceAccounts().GetServiceAccountByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	}
+
+	changes := make(map[string]interface{})
+	if input.Name != nil && *input.Name != account.Name {
+		changes["oldName"] = account.Name
+		changes["newName"] = *input.Name
+		account.Name = *input.Name
+	}
+	if input.Description != nil && *input.Description != account.Description {
+		changes["description"] = *input.Description
+		account.Description = *input.Description
+	}
+	if input.Scopes != nil {
+		if invalid := invalidScopes(*input.Scopes, models.APIKeyScopes); len(invalid) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scopes", "scopes": invalid})
+			return
+		}
+		changes["oldScopes"] = account.Scopes
+		changes["newScopes"] = *input.Scopes
+		account.Scopes = *input.Scopes
+	}
+	if input.IsDisabled != nil && *input.IsDisabled != account.IsDisabled {
+		changes[sub_model.META_OLD_IS_DISABLED] = account.IsDisabled
+		changes[sub_model.META_NEW_IS_DISABLED] = *input.IsDisabled
+		account.IsDisabled = *input.IsDisabled
+	}
+	account.LastUpdated = time.Now().UTC()
+
+	if err := h.db.ServiceAccounts().UpdateServiceAccount(c.Request.Context(), account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateAuditLog(c, h.db, sub_model.SERVICE_ACCOUNT_UPDATED, map[string]interface{}{
+		sub_model.META_SERVICE_ACCOUNT_ID:   account.ID,
+		sub_model.META_SERVICE_ACCOUNT_NAME: account.Name,
+		sub_model.META_CHANGES:              changes,
+	})
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *ServiceAccountHandler) ListKeys(c *gin.Context) {
+	keys, err := h.db.ServiceAccounts().ListAPIKeys(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateKey issues a new API key, the plaintext key is only returned in this response
+func (h *ServiceAccountHandler) CreateKey(c *gin.Context) {
+	var input dtos.Create_APIKey_Input
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.db.ServiceAccounts().GetServiceAccountByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	}
+	if account.IsDisabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service account is disabled"})
+		return
+	}
+
+	if invalid := invalidScopes(input.Scopes, account.Scopes); len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scopes not allowed for this service account", "scopes": invalid})
+		return
+	}
+
+	expiry := defaultAPIKeyExpiry
+	if input.ExpiresInDays > 0 {
+		expiry = time.Duration(input.ExpiresInDays) * 24 * time.Hour
+	}
+
+	plaintext, prefix, keyHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	key := models.APIKey{
+		ServiceAccountID: account.ID,
+		Name:             input.Name,
+		Prefix:           prefix,
+		KeyHash:          keyHash,
+		Scopes:           input.Scopes,
+		ExpiresAt:        time.Now().UTC().Add(expiry),
+		CreatedBy:        c.GetString("userID"),
+		CreatedAt:        time.Now().UTC(),
+	}
+
+	if err := h.db.ServiceAccounts().CreateAPIKey(c.Request.Context(), &key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateAuditLog(c, h.db, sub_model.API_KEY_CREATED, map[string]interface{}{
+		sub_model.META_SERVICE_ACCOUNT_ID:   account.ID,
+		sub_model.META_SERVICE_ACCOUNT_NAME: account.Name,
+		"createdApiKeyId":                   key.ID,
+		"createdApiKeyPrefix":               key.Prefix,
+		sub_model.META_SCOPES:               key.Scopes,
+	})
+
+	c.JSON(http.StatusCreated, dtos.Create_APIKey_Output{
+		ID:        key.ID,
+		Name:      key.Name,
+		Key:       plaintext,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+	})
+}
+
+func (h *ServiceAccountHandler) RevokeKey(c *gin.Context) {
+	key, err := h.db.ServiceAccounts().GetAPIKeyByID(c.Request.Context(), c.Param("keyId"))
+	if err != nil || key.ServiceAccountID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if key.RevokedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "API key is already revoked"})
+		return
+	}
+
+	if err := h.db.ServiceAccounts().RevokeAPIKey(c.Request.Context(), key.ID, c.GetString("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.CreateAuditLog(c, h.db, sub_model.API_KEY_REVOKED, map[string]interface{}{
+		sub_model.META_SERVICE_ACCOUNT_ID: key.ServiceAccountID,
+		"revokedApiKeyId":                 key.ID,
+		"revokedApiKeyPrefix":             key.Prefix,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// invalidScopes returns the requested scopes that are not in the allowed list
+func invalidScopes(requested, allowed []string) []string {
+	invalid := []string{}
+	for _, scope := range requested {
+		found := false
+		for _, a := range allowed {
+			if scope == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			invalid = append(invalid, scope)
+		}
+	}
+	return invalid
+}
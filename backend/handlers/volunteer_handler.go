@@ -1,12 +1,15 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
 	dtos "sheduling-server/DTOs"
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
 	"sheduling-server/repository"
 	"sheduling-server/utils"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,13 +23,60 @@ func NewVolunteerHandler(db repository.Database) *VolunteerHandler {
 	return &VolunteerHandler{db: db}
 }
 
+// List searches and filters volunteers, paged when limit or cursor is given
 func (h *VolunteerHandler) List(c *gin.Context) {
-	volunteers, err := h.db.Volunteers().ListVolunteer(c.Request.Context())
+	var input dtos.List_Volunteers_Query
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts, paginated, err := listOptions(input.Sort, input.Cursor, input.Limit, repository.SortByName, repository.SortByCreatedAt)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	createdFrom, err := parseQueryTime("createdFrom", input.CreatedFrom)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	createdTo, err := parseQueryTime("createdTo", input.CreatedTo)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	tags := splitQueryList(input.Tags)
+	profiles := canSeeProfiles(c)
+	if len(tags) > 0 && !profiles {
+		// Tags are part of the profile
+		c.JSON(401, gin.H{"error": "Filtering by tag requires login"})
 		return
 	}
-	c.JSON(200, volunteers)
+
+	page, err := h.db.Volunteers().QueryVolunteers(c.Request.Context(), repository.VolunteerQuery{
+		Search:        input.Search,
+		Prefix:        input.Prefix,
+		DepartmentID:  input.DepartmentID,
+		IsDisabled:    input.Disabled,
+		Tags:          tags,
+		CreatedAfter:  createdFrom,
+		CreatedBefore: createdTo,
+		ListOptions:   opts,
+	})
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+	if profiles {
+		writeListPage(c, page, opts, paginated)
+		return
+	}
+
+	public := repository.Page[dtos.VolunteerList_Output]{Items: []dtos.VolunteerList_Output{}, NextCursor: page.NextCursor}
+	for _, volunteer := range page.Items {
+		public.Items = append(public.Items, toVolunteerListOutput(volunteer))
+	}
+	writeListPage(c, public, opts, paginated)
 }
 
 func (h *VolunteerHandler) GetByID(c *gin.Context) {
@@ -36,27 +86,67 @@ func (h *VolunteerHandler) GetByID(c *gin.Context) {
 		c.JSON(404, gin.H{"error": "Volunteer not found"})
 		return
 	}
+	if !canSeeProfiles(c) {
+		c.JSON(200, toVolunteerListOutput(volunteer))
+		return
+	}
 	c.JSON(200, volunteer)
 }
 
+// canSeeProfiles reports whether the caller logged in, anonymous callers don't get contact details or student IDs
+func canSeeProfiles(c *gin.Context) bool {
+	_, ok := c.Get("accessLevel")
+	return ok
+}
+
+// toVolunteerListOutput keeps only what anonymous callers may see of a volunteer
+func toVolunteerListOutput(volunteer *models.VolunteerModel) dtos.VolunteerList_Output {
+	return dtos.VolunteerList_Output{
+		ID:         volunteer.ID,
+		Name:       volunteer.Name,
+		IsDisabled: volunteer.IsDisabled,
+	}
+}
+
 func (h *VolunteerHandler) Create(c *gin.Context) {
 	var input dtos.Create_Volunteer_Input
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
+	email, err := utils.NormalizeEmail(input.Email)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	phone, err := utils.NormalizePhone(input.Phone)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 	volunteer := models.VolunteerModel{
 		Name:        input.Name,
 		CreatedAt:   time.Now().UTC(),
 		LastUpdated: time.Now().UTC(),
 		IsDisabled:  false,
+		Email:       email,
+		Phone:       phone,
+		StudentID:   strings.TrimSpace(input.StudentID),
+		YearLevel:   strings.TrimSpace(input.YearLevel),
+		Section:     strings.TrimSpace(input.Section),
+		Skills:      utils.NormalizeLabels(input.Skills),
+		Tags:        utils.NormalizeLabels(input.Tags),
 	}
 
 	if err := h.db.Volunteers().CreateVolunteer(c.Request.Context(), &volunteer); err != nil {
+		if errors.Is(err, repository.ErrStudentIDTaken) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	fmt.Printf("created volunteer %s (ID : %s)", volunteer.Name, volunteer.ID)
+	slog.DebugContext(c.Request.Context(), "Created volunteer", "volunteerId", volunteer.ID)
 
 	// Log volunteer creation
 	utils.CreateEnhancedLog(c, h.db, sub_model.VOLUNTEER_CREATED, sub_model.SEVERITY_INFO, map[string]interface{}{
@@ -98,7 +188,54 @@ func (h *VolunteerHandler) Update(c *gin.Context) {
 		volunteer.IsDisabled = *input.IsDisabled
 	}
 
+	// Profile fields
+	updated := input.Name != nil && *input.Name != oldName
+	if input.Email != nil {
+		email, err := utils.NormalizeEmail(*input.Email)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updated = setProfileField(changes, "Email", &volunteer.Email, email) || updated
+	}
+	if input.Phone != nil {
+		phone, err := utils.NormalizePhone(*input.Phone)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updated = setProfileField(changes, "Phone", &volunteer.Phone, phone) || updated
+	}
+	if input.StudentID != nil {
+		updated = setProfileField(changes, "StudentId", &volunteer.StudentID, strings.TrimSpace(*input.StudentID)) || updated
+	}
+	if input.YearLevel != nil {
+		updated = setProfileField(changes, "YearLevel", &volunteer.YearLevel, strings.TrimSpace(*input.YearLevel)) || updated
+	}
+	if input.Section != nil {
+		updated = setProfileField(changes, "Section", &volunteer.Section, strings.TrimSpace(*input.Section)) || updated
+	}
+	if input.Skills != nil {
+		updated = setProfileLabels(changes, "Skills", &volunteer.Skills, utils.NormalizeLabels(*input.Skills)) || updated
+	}
+	if input.Tags != nil {
+		updated = setProfileLabels(changes, "Tags", &volunteer.Tags, utils.NormalizeLabels(*input.Tags)) || updated
+	}
+	if input.EmailOptOut != nil && *input.EmailOptOut != volunteer.EmailOptOut {
+		changes["oldEmailOptOut"] = volunteer.EmailOptOut
+		changes["newEmailOptOut"] = *input.EmailOptOut
+		volunteer.EmailOptOut = *input.EmailOptOut
+		updated = true
+	}
+	if len(changes) > 0 {
+		volunteer.LastUpdated = time.Now().UTC()
+	}
+
 	if err := h.db.Volunteers().UpdateVolunteer(c.Request.Context(), volunteer); err != nil {
+		if errors.Is(err, repository.ErrStudentIDTaken) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
@@ -117,7 +254,7 @@ func (h *VolunteerHandler) Update(c *gin.Context) {
 			})
 		}
 	}
-	if input.Name != nil && *input.Name != oldName {
+	if updated {
 		utils.CreateEnhancedLog(c, h.db, sub_model.VOLUNTEER_UPDATED, sub_model.SEVERITY_INFO, map[string]interface{}{
 			sub_model.META_VOLUNTEER_ID: volunteer.ID,
 			sub_model.META_CHANGES:      changes,
@@ -127,6 +264,28 @@ func (h *VolunteerHandler) Update(c *gin.Context) {
 	c.JSON(200, volunteer)
 }
 
+// setProfileField sets a profile field and records the change as old<Field>/new<Field>
+func setProfileField(changes map[string]interface{}, field string, current *string, value string) bool {
+	if *current == value {
+		return false
+	}
+	changes["old"+field] = *current
+	changes["new"+field] = value
+	*current = value
+	return true
+}
+
+// setProfileLabels is setProfileField for skills and tags
+func setProfileLabels(changes map[string]interface{}, field string, current *[]string, value []string) bool {
+	if slices.Equal(*current, value) {
+		return false
+	}
+	changes["old"+field] = *current
+	changes["new"+field] = value
+	*current = value
+	return true
+}
+
 // soft delete
 func (h *VolunteerHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
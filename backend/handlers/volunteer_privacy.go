@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	dtos "sheduling-server/DTOs"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Export returns everything held about a volunteer as a JSON download (admin only)
+// GET /api/volunteers/:id/export
+func (h *VolunteerHandler) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	volunteer, err := h.db.Volunteers().GetVolunteerByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Volunteer not found"})
+		return
+	}
+
+	departments, err := h.db.Departments().QueryDepartments(ctx, repository.DepartmentQuery{VolunteerID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := h.db.EventSchedules().QueryEvents(ctx, repository.EventQuery{VolunteerID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	accounts, err := h.linkedAccounts(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invites, err := h.volunteerInvites(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logs, err := h.volunteerLogs(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	archivedLogs, err := utils.VolunteerBundledLogs(ctx, h.db.LogBundles(), id)
+	if errors.Is(err, utils.ErrNoColdStorage) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Logs about the volunteer are in cold storage, which is not configured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	notifications, err := h.db.Notifications().QueryNotifications(ctx, repository.NotificationQuery{VolunteerID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	export := dtos.Volunteer_Export_Output{
+		ExportedAt: time.Now().UTC(),
+		Volunteer: dtos.GetByID_Output{
+			ID:          volunteer.ID,
+			Name:        volunteer.Name,
+			IsDisabled:  volunteer.IsDisabled,
+			CreatedAt:   volunteer.CreatedAt,
+			LastUpdated: volunteer.LastUpdated,
+			Email:       volunteer.Email,
+			Phone:       volunteer.Phone,
+			StudentID:   volunteer.StudentID,
+			YearLevel:   volunteer.YearLevel,
+			Section:     volunteer.Section,
+			Skills:      volunteer.Skills,
+			Tags:        volunteer.Tags,
+		},
+		Memberships:   []dtos.Volunteer_Membership_Export{},
+		Events:        []dtos.Volunteer_Event_Export{},
+		Accounts:      []dtos.GetByID_AuthUser_Output{},
+		Invites:       []dtos.Invite_Output{},
+		Logs:          []dtos.LogResponse{},
+		ArchivedLogs:  []dtos.LogResponse{},
+		Notifications: []*models.Notification{},
+	}
+	for _, dept := range departments.Items {
+		for _, member := range dept.VolunteerMembers {
+			if member.VolunteerID != id {
+				continue
+			}
+			export.Memberships = append(export.Memberships, dtos.Volunteer_Membership_Export{
+				DepartmentID:   dept.ID,
+				DepartmentName: dept.DepartmentName,
+				MembershipType: member.MembershipType,
+				JoinedDate:     member.JoinedDate,
+				LastUpdated:    member.LastUpdated,
+			})
+		}
+	}
+	for _, event := range events.Items {
+		entry := dtos.Volunteer_Event_Export{
+			EventID:     event.ID,
+			EventName:   event.Name,
+			TimeAndDate: event.TimeAndDate,
+			Scheduled:   slices.Contains(event.ScheduledVolunteers, id),
+			Voluntary:   slices.Contains(event.VoluntaryVolunteers, id),
+		}
+		for i := range event.Statuses {
+			if event.Statuses[i].VolunteerID == id {
+				entry.Status = &event.Statuses[i]
+				break
+			}
+		}
+		export.Events = append(export.Events, entry)
+	}
+	for _, account := range accounts {
+		export.Accounts = append(export.Accounts, toAuthUserOutput(account))
+	}
+	for _, invite := range invites {
+		export.Invites = append(export.Invites, toInviteOutput(invite))
+	}
+	for _, log := range logs {
+		export.Logs = append(export.Logs, toLogResponse(log))
+	}
+	for _, log := range archivedLogs {
+		export.ArchivedLogs = append(export.ArchivedLogs, toLogResponse(log))
+	}
+	export.Notifications = append(export.Notifications, notifications.Items...)
+
+	// The export is personal data leaving the system, so it is audited
+	utils.CreateEnhancedLog(c, h.db, sub_model.SENSITIVE_DATA_ACCESSED, sub_model.SEVERITY_WARNING, map[string]interface{}{
+		sub_model.META_VOLUNTEER_ID: volunteer.ID,
+		sub_model.META_DATA_TYPE:    "volunteer_export",
+		sub_model.META_RECORD_COUNT: len(export.Memberships) + len(export.Events) + len(export.Accounts) + len(export.Invites) + len(export.Logs) + len(export.ArchivedLogs) + len(export.Notifications),
+	})
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"volunteer-%s.json\"", volunteer.ID))
+	c.JSON(http.StatusOK, export)
+}
+
+// Erase hard deletes a volunteer: records the erasure in a tombstone log without personal data, then removes them
+// from departments, events and invites, deletes their notifications and pseudonymizes the logs about them,
+// in cold storage too (admin only)
+// POST /api/volunteers/:id/erase
+func (h *VolunteerHandler) Erase(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var input dtos.Erase_Volunteer_Input
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	volunteer, err := h.db.Volunteers().GetVolunteerByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Volunteer not found"})
+		return
+	}
+
+	// A login account holds its own personal data and permissions, it has to be dealt with first
+	accounts, err := h.linkedAccounts(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(accounts) > 0 {
+		userIDs := make([]string, 0, len(accounts))
+		for _, account := range accounts {
+			userIDs = append(userIDs, account.ID)
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Volunteer is linked to user accounts, relink them before erasing",
+			"userIds": userIDs,
+		})
+		return
+	}
+
+	// The tombstone is written first, so logs pseudonymized by the steps below always have their erasure record
+	// It holds only the ID and the reason, one of a fixed set, since it outlives the volunteer's data
+	erased, err := h.db.Logs().ErasedVolunteers(ctx, []string{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !erased[id] {
+		if err := utils.CreateEnhancedLog(c, h.db, sub_model.VOLUNTEER_ERASED, sub_model.SEVERITY_WARNING, map[string]interface{}{
+			sub_model.META_VOLUNTEER_ID: volunteer.ID,
+			sub_model.META_REASON:       input.Reason,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record the erasure, nothing was erased"})
+			return
+		}
+	}
+
+	// Every step can run again, and the volunteer is deleted last,
+	// so a failed erasure is finished by calling this again
+	result := dtos.Volunteer_Erase_Output{VolunteerID: volunteer.ID}
+	departments, err := h.db.Departments().QueryDepartments(ctx, repository.DepartmentQuery{VolunteerID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, dept := range departments.Items {
+		if err := h.db.Departments().RemoveMemberFromDepartment(ctx, dept.ID, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+			return
+		}
+		result.DepartmentsUpdated++
+	}
+
+	events, err := h.db.EventSchedules().QueryEvents(ctx, repository.EventQuery{VolunteerID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+		return
+	}
+	for _, event := range events.Items {
+		if err := h.db.EventSchedules().RemoveVolunteerFromEvent(ctx, event.ID, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+			return
+		}
+		result.EventsUpdated++
+	}
+
+	invites, err := h.volunteerInvites(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+		return
+	}
+	for _, invite := range invites {
+		if err := h.db.Invites().DeleteInvite(ctx, invite.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+			return
+		}
+		result.InvitesDeleted++
+	}
+
+	// Notifications hold the address and name they were sent with, pending ones are never sent
+	result.NotificationsDeleted, err = h.db.Notifications().DeleteVolunteerNotifications(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+		return
+	}
+
+	result.LogsPseudonymized, err = h.db.Logs().PseudonymizeVolunteerLogs(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+		return
+	}
+	result.BundledLogsPseudonymized, err = utils.PseudonymizeBundledLogs(ctx, h.db.LogBundles(), id)
+	if errors.Is(err, utils.ErrNoColdStorage) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Logs about the volunteer are in cold storage, which is not configured", "progress": result})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+		return
+	}
+
+	if err := h.db.Volunteers().DeleteVolunteer(ctx, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// linkedAccounts returns the user accounts linked to a volunteer
+func (h *VolunteerHandler) linkedAccounts(ctx context.Context, volunteerID string) ([]*models.AuthUser, error) {
+	users, err := h.db.AuthUsers().ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var linked []*models.AuthUser
+	for _, user := range users {
+		if user.VolunteerID == volunteerID {
+			linked = append(linked, user)
+		}
+	}
+	return linked, nil
+}
+
+// volunteerInvites returns the invites issued for a volunteer
+func (h *VolunteerHandler) volunteerInvites(ctx context.Context, volunteerID string) ([]*models.Invite, error) {
+	invites, err := h.db.Invites().ListInvites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []*models.Invite
+	for _, invite := range invites {
+		if invite.VolunteerID == volunteerID {
+			result = append(result, invite)
+		}
+	}
+	return result, nil
+}
+
+// volunteerLogs returns all the logs about a volunteer, newest first
+func (h *VolunteerHandler) volunteerLogs(ctx context.Context, volunteerID string) ([]*models.SystemLog, error) {
+	logs, total, err := h.db.Logs().GetLogsByVolunteerID(ctx, volunteerID, 500, 0)
+	if err != nil || len(logs) >= total {
+		return logs, err
+	}
+	logs, _, err = h.db.Logs().GetLogsByVolunteerID(ctx, volunteerID, total, 0)
+	return logs, err
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
+	_ "time/tzdata" // IMPORT_TIMEZONE has to resolve on hosts without a zoneinfo database
 
 	"sheduling-server/handlers"
 	"sheduling-server/middleware"
@@ -18,14 +20,28 @@ import (
 )
 
 func main() {
-	println("Starting Server")
 	// Load environment variables from .env file (optional for local dev)
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables (this is normal in production)")
+	envErr := godotenv.Load()
+
+	// Structured JSON logging, LOG_LEVEL and LOG_FORMAT can come from .env
+	utils.InitLogging()
+	slog.Info("Starting Server")
+	if envErr != nil {
+		slog.Info("No .env file found, using system environment variables (this is normal in production)")
 	}
 
-	// Initialize Google OAuth configuration
-	utils.InitGoogleOAuth()
+	// Initialize OAuth/OIDC providers
+	utils.InitOAuthProviders()
+
+	// Load the keys used to encrypt stored OAuth tokens
+	if err := utils.InitTokenEncryption(); err != nil {
+		fatal("Failed to initialize token encryption", err)
+	}
+
+	// Configure where archived logs are purged to
+	if err := utils.InitLogColdStorage(); err != nil {
+		fatal("Failed to initialize log cold storage", err)
+	}
 
 	// Initialize database
 	ctx := context.Background()
@@ -42,46 +58,106 @@ func main() {
 			getEnv("FIREBASE_PROJECT_ID", ""),
 		)
 	default:
-		log.Fatalf("Unsupported database type: %s", dbType)
+		fatal("Unsupported database type", fmt.Errorf("DB_TYPE %s", dbType))
 		return // stfu kill myself if something goes wrong
 	}
 
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to initialize database", err)
 	}
 	defer db.Close()
 
+	// New logs are also handed to the live log streams
+	logBroker := utils.NewLogBroker(100)
+	db = utils.PublishLogs(db, logBroker)
+
+	// Status changes are handed to the live attendance boards
+	attendanceBroker := utils.NewAttendanceBroker(500)
+	db = utils.PublishAttendance(db, attendanceBroker)
+
+	// Schedule emails, off unless SMTP_HOST is set, and the webhook channels that are configured
+	mailer, err := utils.NewSMTPMailerFromEnv()
+	if err != nil {
+		fatal("Failed to configure SMTP", err)
+	}
+	channels, err := utils.NotificationChannelsFromEnv()
+	if err != nil {
+		fatal("Failed to configure notification channels", err)
+	}
+	notificationLocation, err := time.LoadLocation(getEnv("NOTIFICATION_TIMEZONE", "Asia/Manila"))
+	if err != nil {
+		fatal("Invalid NOTIFICATION_TIMEZONE", err)
+	}
+	notifier := utils.NewNotifier(db, utils.NotifierConfig{
+		Mailer:   mailer,
+		Channels: channels,
+		Location: notificationLocation,
+	})
+	notifier.Start(ctx)
+
+	// Reminders before each event, queued once across restarts and instances
+	reminderConfig, err := utils.ReminderSchedulerConfigFromEnv()
+	if err != nil {
+		fatal("Failed to configure event reminders", err)
+	}
+	reminderScheduler := utils.NewReminderScheduler(db, notifier, reminderConfig)
+	reminderScheduler.Start(ctx)
+
 	// Initialize handlers
 	volunteerHandler := handlers.NewVolunteerHandler(db)
 	departmentHandler := handlers.NewDepartmentHandler(db)
-	eventHandler := handlers.NewEventHandler(db)
+	eventHandler := handlers.NewEventHandler(db, notifier)
 	authUserHandler := handlers.NewAuthUserHandler(db)
 	oauthHandler := handlers.NewOAuthHandler(db)
+	inviteHandler := handlers.NewInviteHandler(db)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(db)
 	batchImportHandler := handlers.NewBatchImportHandler(db)
 	logHandler := handlers.NewLogHandler(db)
+	logStreamHandler := handlers.NewLogStreamHandler(db, logBroker)
+	attendanceBoardHandler := handlers.NewAttendanceBoardHandler(db, attendanceBroker)
+	notificationHandler := handlers.NewNotificationHandler(db)
 
 	// Initialize and start log retention scheduler
 	retentionDays := 365 // Default to 1 year
 	if envRetentionDays := os.Getenv("LOG_RETENTION_DAYS"); envRetentionDays != "" {
 		if days, err := fmt.Sscanf(envRetentionDays, "%d", &retentionDays); err == nil && days > 0 {
-			log.Printf("Using LOG_RETENTION_DAYS from environment: %d days", retentionDays)
+			slog.Info("Using LOG_RETENTION_DAYS from environment", "days", retentionDays)
 		} else {
-			log.Printf("Invalid LOG_RETENTION_DAYS value, using default: 365 days")
+			slog.Warn("Invalid LOG_RETENTION_DAYS value, using default: 365 days")
 			retentionDays = 365
 		}
 	} else {
-		log.Printf("LOG_RETENTION_DAYS not set, using default: 365 days")
+		slog.Info("LOG_RETENTION_DAYS not set, using default: 365 days")
+	}
+
+	// Archived logs are purged to cold storage only when LOG_PURGE_AFTER_DAYS is set
+	purgeAfterDays := 0
+	if envPurgeDays := os.Getenv("LOG_PURGE_AFTER_DAYS"); envPurgeDays != "" {
+		if days, err := fmt.Sscanf(envPurgeDays, "%d", &purgeAfterDays); err == nil && days > 0 && purgeAfterDays > 0 {
+			slog.Info("Using LOG_PURGE_AFTER_DAYS from environment", "days", purgeAfterDays)
+		} else {
+			slog.Warn("Invalid LOG_PURGE_AFTER_DAYS value, archived logs will not be purged")
+			purgeAfterDays = 0
+		}
 	}
 
 	retentionScheduler := utils.NewRetentionScheduler(db.Logs(), utils.RetentionSchedulerConfig{
 		RetentionDays:  retentionDays,
 		RunImmediately: false, // Set to true for testing
+		PurgeAfterDays: purgeAfterDays,
+		Bundles:        db.LogBundles(),
+		Rules:          db.RetentionRules(),
 	})
 	retentionScheduler.Start(ctx)
-	log.Println("Log retention scheduler initialized and started")
+	slog.Info("Log retention scheduler initialized and started")
+	retentionRuleHandler := handlers.NewRetentionRuleHandler(db, retentionScheduler)
 
-	// Setup Gin router
-	r := gin.Default()
+	// Clean up OAuth login attempts that were never completed
+	utils.StartOAuthStateCleanup(ctx, db.OAuthStates(), time.Hour)
+
+	// Setup Gin router, requests are logged through slog with their request ID
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Recovery())
 
 	// Configure CORS
 	allowedOrigins := []string{
@@ -94,16 +170,16 @@ func main() {
 	// Add production frontend URL if configured
 	if frontendURL := os.Getenv("FRONTEND_URL"); frontendURL != "" {
 		allowedOrigins = append(allowedOrigins, frontendURL)
-		fmt.Printf("Added FRONTEND_URL to allowed origins: %s\n", frontendURL)
+		slog.Info("Added FRONTEND_URL to allowed origins", "url", frontendURL)
 	}
 
-	log.Printf("CORS allowed origins: %v", allowedOrigins)
+	slog.Info("CORS allowed origins", "origins", allowedOrigins)
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-API-Key", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600, // Cache preflight for 12 hours
 	}))
@@ -142,24 +218,28 @@ func main() {
 	// OAuth routes
 	oauth := r.Group("/api/oauth")
 	{
-		oauth.GET("/google/login", oauthHandler.GetGoogleLoginURL)                           // Get Google login URL
-		oauth.POST("/google/callback", oauthHandler.GoogleCallback)                          // Handle Google callback
-		oauth.POST("/google/link", middleware.RequireAuth(), oauthHandler.LinkGoogleAccount) // Link Google to existing account
+		oauth.GET("/providers", oauthHandler.ListProviders)                                 // List configured providers
+		oauth.GET("/:provider/login", oauthHandler.GetLoginURL)                             // Get provider login URL
+		oauth.POST("/:provider/callback", oauthHandler.Callback)                            // Handle provider callback
+		oauth.GET("/:provider/link/url", middleware.RequireAuth(), oauthHandler.GetLinkURL) // Get provider URL for linking
+		oauth.POST("/:provider/link", middleware.RequireAuth(), oauthHandler.LinkAccount)   // Link provider to existing account
 	}
 
 	// Volunteer routes - Public GET, Admin-only CUD
 	volunteers := r.Group("/api/volunteers")
 	{
-		// Public endpoints - anonymous can view volunteers
-		volunteers.GET("", volunteerHandler.List)
-		volunteers.GET("/:id", volunteerHandler.GetByID)
+		// Public endpoints - anonymous can view volunteer names, the profile needs a login
+		volunteers.GET("", middleware.AcceptAPIKey(db, "volunteers"), middleware.OptionalAuth(), volunteerHandler.List)
+		volunteers.GET("/:id", middleware.AcceptAPIKey(db, "volunteers"), middleware.OptionalAuth(), volunteerHandler.GetByID)
 		volunteers.GET("/:id/status-history", eventHandler.GetVolunteerStatusHistory)
 
 		// Admin-only endpoints
-		volunteers.POST("", middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Create)
-		volunteers.PUT("/:id", middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Update)
-		volunteers.DELETE("/:id", middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Delete)
-		volunteers.GET("/:id/logs", middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.GetVolunteerLogs)
+		volunteers.POST("", middleware.AcceptAPIKey(db, "volunteers"), middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Create)
+		volunteers.PUT("/:id", middleware.AcceptAPIKey(db, "volunteers"), middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Update)
+		volunteers.DELETE("/:id", middleware.AcceptAPIKey(db, "volunteers"), middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Delete)
+		volunteers.GET("/:id/logs", middleware.AcceptAPIKey(db, "logs"), middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.GetVolunteerLogs)
+		volunteers.GET("/:id/export", middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Export)
+		volunteers.POST("/:id/erase", middleware.RequireAuth(), middleware.RequireAdmin(), volunteerHandler.Erase)
 	}
 
 	// Department routes - Public GET, Admin CUD, DeptHead member management
@@ -171,15 +251,15 @@ func main() {
 		departments.GET("/:id/status-history", eventHandler.GetDepartmentStatusHistory)
 
 		// Admin-only endpoints
-		departments.POST("", middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.Create)
-		departments.PUT("/:id", middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.Update)
-		departments.DELETE("/:id", middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.Delete)
-		departments.GET("/:id/logs", middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.GetDepartmentLogs)
+		departments.POST("", middleware.AcceptAPIKey(db, "departments"), middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.Create)
+		departments.PUT("/:id", middleware.AcceptAPIKey(db, "departments"), middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.Update)
+		departments.DELETE("/:id", middleware.AcceptAPIKey(db, "departments"), middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.Delete)
+		departments.GET("/:id/logs", middleware.AcceptAPIKey(db, "logs"), middleware.RequireAuth(), middleware.RequireAdmin(), departmentHandler.GetDepartmentLogs)
 
 		// Department head can manage their own department members
-		departments.POST("/:id/members", middleware.RequireAuth(), middleware.ValidateIsDepartmentHead(db), departmentHandler.AddMember)
-		departments.PUT("/:id/members/:volunteerId", middleware.RequireAuth(), middleware.ValidateIsDepartmentHead(db), departmentHandler.UpdateMemberType)
-		departments.DELETE("/:id/members/:volunteerId", middleware.RequireAuth(), middleware.ValidateIsDepartmentHead(db), departmentHandler.RemoveMember)
+		departments.POST("/:id/members", middleware.AcceptAPIKey(db, "departments"), middleware.RequireAuth(), middleware.ValidateIsDepartmentHead(db), departmentHandler.AddMember)
+		departments.PUT("/:id/members/:volunteerId", middleware.AcceptAPIKey(db, "departments"), middleware.RequireAuth(), middleware.ValidateIsDepartmentHead(db), departmentHandler.UpdateMemberType)
+		departments.DELETE("/:id/members/:volunteerId", middleware.AcceptAPIKey(db, "departments"), middleware.RequireAuth(), middleware.ValidateIsDepartmentHead(db), departmentHandler.RemoveMember)
 	}
 
 	// Event routes - Public GET, Admin CUD, DeptHead volunteer management
@@ -190,21 +270,22 @@ func main() {
 		events.GET("/:id", eventHandler.GetByID)
 
 		// Admin-only endpoints
-		events.POST("", middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.Create)
-		events.PUT("/:id", middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.Update)
-		events.DELETE("/:id", middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.Delete)
+		events.POST("", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.Create)
+		events.PUT("/:id", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.Update)
+		events.DELETE("/:id", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.Delete)
 
 		// Department head can manage volunteers from their department
-		events.POST("/:id/status", middleware.RequireAuth(), eventHandler.AddVolunteerStatus)
-		events.PUT("/:id/status/:volunteerId", middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.UpdateVolunteerStatus)
-		events.DELETE("/:id/status/:volunteerId", middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.RemoveVolunteerFromEvent)
-		events.PUT("/:id/status/:volunteerId/TimeIn", middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.TimeInVolunteer)
-		events.PUT("/:id/status/:volunteerId/TimeOut", middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.TimeOutVolunteer)
+		events.POST("/:id/status", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), eventHandler.AddVolunteerStatus)
+		events.PUT("/:id/status/:volunteerId", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.UpdateVolunteerStatus)
+		events.DELETE("/:id/status/:volunteerId", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.RemoveVolunteerFromEvent)
+		events.PUT("/:id/status/:volunteerId/TimeIn", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.TimeInVolunteer)
+		events.PUT("/:id/status/:volunteerId/TimeOut", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.ValidateDepartmentOwnership(db), eventHandler.TimeOutVolunteer)
+		events.GET("/:id/board", middleware.AcceptAPIKey(db, "events"), middleware.TokenFromQuery(), middleware.RequireAuth(), middleware.RequireDeptHead(), attendanceBoardHandler.Board)
 
 		// Admin-only department management in events
-		events.PUT("/:id/AddDepartment", middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.AddDepartmentToEvent)
-		events.DELETE("/:id/departments/:departmentId", middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.RemoveDepartmentFromEvent)
-		events.GET("/:id/logs", middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.GetEventLogs)
+		events.PUT("/:id/AddDepartment", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.AddDepartmentToEvent)
+		events.DELETE("/:id/departments/:departmentId", middleware.AcceptAPIKey(db, "events"), middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.RemoveDepartmentFromEvent)
+		events.GET("/:id/logs", middleware.AcceptAPIKey(db, "logs"), middleware.RequireAuth(), middleware.RequireAdmin(), eventHandler.GetEventLogs)
 	}
 
 	// Auth User routes (Admin only)
@@ -218,17 +299,56 @@ func main() {
 		authUsers.PUT("/:id", authUserHandler.Update)
 	}
 
+	// Invite routes (Admin only)
+	invites := r.Group("/api/invites")
+	invites.Use(middleware.RequireAuth())
+	invites.Use(middleware.RequireAdmin())
+	{
+		invites.GET("", inviteHandler.List)
+		invites.POST("", inviteHandler.Create)
+		invites.DELETE("/:id", inviteHandler.Revoke)
+	}
+
+	// Service account and API key routes (Admin only, API keys can't manage keys)
+	serviceAccounts := r.Group("/api/service-accounts")
+	serviceAccounts.Use(middleware.RequireAuth())
+	serviceAccounts.Use(middleware.RequireAdmin())
+	{
+		serviceAccounts.GET("", serviceAccountHandler.List)
+		serviceAccounts.POST("", serviceAccountHandler.Create)
+		serviceAccounts.PUT("/:id", serviceAccountHandler.Update)
+		serviceAccounts.GET("/:id/keys", serviceAccountHandler.ListKeys)
+		serviceAccounts.POST("/:id/keys", serviceAccountHandler.CreateKey)
+		serviceAccounts.DELETE("/:id/keys/:keyId", serviceAccountHandler.RevokeKey)
+	}
+
+	// Notification delivery log (Admin only)
+	notifications := r.Group("/api/notifications")
+	notifications.Use(middleware.RequireAuth())
+	notifications.Use(middleware.RequireAdmin())
+	{
+		notifications.GET("", notificationHandler.List)
+	}
+
 	// Batch Import routes (Admin only)
 	batchImport := r.Group("/api/batch-import")
 	batchImport.Use(middleware.RequireAuth())
 	batchImport.Use(middleware.RequireAdmin())
 	{
 		batchImport.POST("/preview", batchImportHandler.PreviewBatchImport)
+		batchImport.POST("/inspect", batchImportHandler.InspectBatchImport)
+		batchImport.POST("/events/preview", batchImportHandler.PreviewEventImport)
 		batchImport.POST("/execute", batchImportHandler.ExecuteBatchImport)
+		batchImport.GET("/template", batchImportHandler.DownloadImportTemplate)
+		batchImport.POST("/report", batchImportHandler.DownloadImportReport)
+		batchImport.GET("/sessions", batchImportHandler.ListImportSessions)
+		batchImport.GET("/sessions/:sessionId", batchImportHandler.ResumeImportSession)
+		batchImport.DELETE("/sessions/:sessionId", batchImportHandler.DeleteImportSession)
 	}
 
 	// System Logs routes (Admin only)
 	logs := r.Group("/api/logs")
+	logs.Use(middleware.AcceptAPIKey(db, "logs"))
 	logs.Use(middleware.RequireAuth())
 	logs.Use(middleware.RequireAdmin())
 	{
@@ -237,12 +357,32 @@ func main() {
 		logs.POST("/archive", logHandler.ArchiveLogs)
 		logs.GET("/categories", logHandler.GetCategories)
 		logs.GET("/stats", logHandler.GetStats)
+		logs.GET("/histogram", logHandler.GetHistogram)
+		logs.GET("/verify", logHandler.VerifyChain)
+		logs.GET("/stream", logStreamHandler.Stream)
+		logs.GET("/bundles", logHandler.ListBundles)
+	}
+
+	// Purging, restoring and retention rules need a signed in admin, API keys are not accepted
+	logAdmin := r.Group("/api/logs")
+	logAdmin.Use(middleware.RequireAuth())
+	logAdmin.Use(middleware.RequireAdmin())
+	{
+		logAdmin.POST("/purge", logHandler.PurgeLogs)
+		logAdmin.POST("/bundles/:id/restore", logHandler.RestoreBundle)
+		logAdmin.GET("/retention-rules", retentionRuleHandler.List)
+		logAdmin.POST("/retention-rules", retentionRuleHandler.Create)
+		logAdmin.PUT("/retention-rules/:id", retentionRuleHandler.Update)
+		logAdmin.DELETE("/retention-rules/:id", retentionRuleHandler.Delete)
+		logAdmin.GET("/retention/dry-run", retentionRuleHandler.DryRun)
 	}
 
 	// Start server
 	port := getEnv("PORT", "8080")
-	log.Printf("Server starting on port %s", port)
-	r.Run(":" + port)
+	slog.Info("Server starting", "port", port)
+	if err := r.Run(":" + port); err != nil {
+		fatal("Server stopped", err)
+	}
 }
 
 // Helper function to get environment variable with default value
@@ -250,6 +390,12 @@ func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
-	log.Printf("Couldn't find env, %s... Yikes", key)
+	slog.Debug("Environment variable not set, using default", "key", key)
 	return defaultValue
 }
+
+// fatal logs an error that stops the server and exits
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
+}
@@ -2,18 +2,34 @@ package middleware
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
 	"sheduling-server/repository"
 	"sheduling-server/utils"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // RequireAuth validates JWT token and sets user information in context
+// Requests already authenticated by AcceptAPIKey are passed through
 func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			c.Next()
+			return
+		}
+
+		// API keys are only accepted on routes that declare a scope with AcceptAPIKey
+		if c.GetHeader(utils.APIKeyHeader) != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys are not accepted for this endpoint"})
+			c.Abort()
+			return
+		}
+
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -49,6 +65,105 @@ func RequireAuth() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuth authenticates requests that carry a token or API key like RequireAuth,
+// and lets requests without either through anonymously
+func OptionalAuth() gin.HandlerFunc {
+	requireAuth := RequireAuth()
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); !ok && c.GetHeader("Authorization") == "" && c.GetHeader(utils.APIKeyHeader) == "" {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
+
+// TokenFromQuery lets a request pass its JWT as ?access_token=, place it before RequireAuth
+// Only for WebSocket routes, browsers can't set the Authorization header when opening one
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+// AcceptAPIKey authenticates requests carrying an X-API-Key header, place it before RequireAuth
+// GET requests need the "<resource>:read" scope, everything else "<resource>:write"
+// Service accounts act with admin access on the routes their scopes allow
+func AcceptAPIKey(db repository.Database, resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader(utils.APIKeyHeader)
+		if rawKey == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, err := db.ServiceAccounts().GetAPIKeyByHash(ctx, utils.HashAPIKey(rawKey))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate API key"})
+			c.Abort()
+			return
+		}
+
+		now := time.Now().UTC()
+		if key == nil || !key.IsUsable(now) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, revoked or expired API key"})
+			c.Abort()
+			return
+		}
+
+		account, err := db.ServiceAccounts().GetServiceAccountByID(ctx, key.ServiceAccountID)
+		if err != nil || account.IsDisabled {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Service account is disabled"})
+			c.Abort()
+			return
+		}
+
+		requiredScope := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			requiredScope = resource + ":read"
+		}
+		// A key only keeps the scopes its account still has
+		scopes := utils.EffectiveScopes(key.Scopes, account.Scopes)
+		if !utils.HasScope(scopes, requiredScope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing scope " + requiredScope})
+			c.Abort()
+			return
+		}
+
+		// Only write the last used time once a minute to keep busy kiosks cheap
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+			go func(keyID string) {
+				touchCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := db.ServiceAccounts().TouchAPIKey(touchCtx, keyID, now); err != nil {
+					slog.Warn("Failed to update API key last used", "error", err)
+				}
+			}(key.ID)
+		}
+
+		principal := &utils.APIKeyPrincipal{
+			KeyID:              key.ID,
+			KeyName:            key.Name,
+			ServiceAccountID:   account.ID,
+			ServiceAccountName: account.Name,
+			Scopes:             scopes,
+		}
+		c.Request = c.Request.WithContext(utils.WithAPIKeyPrincipal(ctx, principal))
+
+		c.Set("apiKeyID", key.ID)
+		c.Set("serviceAccountID", account.ID)
+		c.Set("userID", account.ID)
+		c.Set("username", "service:"+account.Name)
+		c.Set("accessLevel", int(models.ADMIN))
+
+		c.Next()
+	}
+}
+
 // RequireAdmin checks if user has admin access level (1)
 func RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -6,15 +6,17 @@ import (
 )
 
 type AuthUser struct {
-	ID          string               `json:"id" bson:"_id,omitempty"`
-	VolunteerID string               `json:"volunteerId" bson:"volunteerId"` // ref to volunteer
-	Username    string               `json:"username" bson:"username"`       //login 1
-	Password    string               `json:"password" bson:"password"`       // login 2
-	AccessLevel AuthLevel            `json:"accessLevel" bson:"accessLevel"`
-	ThirdAuth   sub_model.OAuthToken `json:"thirdAuth" bson:"thirdAuth"`
-	CreatedAt   time.Time            `json:"createdAt" bson:"createdAt"`
-	LastUpdated time.Time            `json:"lastUpdated" bson:"lastUpdated"`
-	IsDisabled  bool                 `json:"isDisabled" bson:"isDisabled"`
+	ID            string                 `json:"id" bson:"_id,omitempty"`
+	VolunteerID   string                 `json:"volunteerId" bson:"volunteerId"` // ref to volunteer
+	Username      string                 `json:"username" bson:"username"`       //login 1
+	Password      string                 `json:"password" bson:"password"`       // login 2
+	AccessLevel   AuthLevel              `json:"accessLevel" bson:"accessLevel"`
+	OAuthAccounts []sub_model.OAuthToken `json:"oauthAccounts" bson:"oauthAccounts"` // linked accounts, at most one per provider
+	OAuthKeys     []string               `json:"-" bson:"oauthKeys"`                 // OAuthKey of each linked account, for lookups
+	ThirdAuth     sub_model.OAuthToken   `json:"-" bson:"thirdAuth"`                 // single link of older records, moved to OAuthAccounts when read
+	CreatedAt     time.Time              `json:"createdAt" bson:"createdAt"`
+	LastUpdated   time.Time              `json:"lastUpdated" bson:"lastUpdated"`
+	IsDisabled    bool                   `json:"isDisabled" bson:"isDisabled"`
 }
 
 type AuthLevel int
@@ -23,3 +25,53 @@ const (
 	ADMIN    AuthLevel = 1
 	DEPTHEAD AuthLevel = 2
 )
+
+// OAuthKey identifies an account at a provider by its stable subject
+func OAuthKey(provider sub_model.OAuthProvider, subject string) string {
+	return string(provider) + ":" + subject
+}
+
+// LegacyOAuthKey identifies a link stored before subjects were, by its email
+// It only matches until the next login through that provider stores the subject
+func LegacyOAuthKey(provider sub_model.OAuthProvider, email string) string {
+	return string(provider) + ":email:" + email
+}
+
+// OAuthAccount returns the account linked for a provider, nil when none is
+func (u *AuthUser) OAuthAccount(provider sub_model.OAuthProvider) *sub_model.OAuthToken {
+	for i := range u.OAuthAccounts {
+		if u.OAuthAccounts[i].Provider == provider {
+			return &u.OAuthAccounts[i]
+		}
+	}
+	return nil
+}
+
+// SetOAuthAccount links an account, replacing the one linked for the same provider
+func (u *AuthUser) SetOAuthAccount(account sub_model.OAuthToken) {
+	if existing := u.OAuthAccount(account.Provider); existing != nil {
+		*existing = account
+	} else {
+		u.OAuthAccounts = append(u.OAuthAccounts, account)
+	}
+
+	u.OAuthKeys = make([]string, 0, len(u.OAuthAccounts))
+	for _, linked := range u.OAuthAccounts {
+		if linked.Subject != "" {
+			u.OAuthKeys = append(u.OAuthKeys, OAuthKey(linked.Provider, linked.Subject))
+		} else {
+			u.OAuthKeys = append(u.OAuthKeys, LegacyOAuthKey(linked.Provider, linked.Email))
+		}
+	}
+}
+
+// MigrateThirdAuth moves the single link of older records into OAuthAccounts
+func (u *AuthUser) MigrateThirdAuth() {
+	if u.ThirdAuth.Provider == "" {
+		return
+	}
+	if u.OAuthAccount(u.ThirdAuth.Provider) == nil {
+		u.SetOAuthAccount(u.ThirdAuth)
+	}
+	u.ThirdAuth = sub_model.OAuthToken{}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	sub_model "sheduling-server/models/sub_models"
+	"time"
+)
+
+// ImportSessionStatus tracks whether a previewed import is still waiting to be executed
+type ImportSessionStatus string
+
+const (
+	ImportSessionPending   ImportSessionStatus = "pending"
+	ImportSessionExecuting ImportSessionStatus = "executing"
+	ImportSessionCompleted ImportSessionStatus = "completed" // imported, left until it is deleted and never executed again
+)
+
+// ImportKind is the layout of the imported file
+type ImportKind string
+
+const (
+	ImportKindDepartments ImportKind = "departments" // one department per column
+	ImportKindEvents      ImportKind = "events"      // one event per row
+)
+
+// ImportMode decides what happens to departments that already exist
+type ImportMode string
+
+const (
+	ImportModeCreate ImportMode = "create" // always create new departments
+	ImportModeMerge  ImportMode = "merge"  // update the membership of departments with the same name
+)
+
+// ImportSession is a previewed batch import, stored until it is executed, discarded or expires
+type ImportSession struct {
+	ID           string                             `json:"id"`
+	CreatedBy    string                             `json:"createdBy"` // only this admin can resume or execute it
+	FileName     string                             `json:"fileName"`
+	Kind         ImportKind                         `json:"kind"` // empty on sessions stored before events could be imported
+	Mode         ImportMode                         `json:"mode,omitempty"`
+	Status       ImportSessionStatus                `json:"status"`
+	ClaimedUntil *time.Time                         `json:"claimedUntil,omitempty"` // an executing session can be claimed again after this, when its import crashed
+	Departments  []sub_model.ImportDepartment       `json:"departments,omitempty"`
+	Events       []sub_model.ImportEvent            `json:"events,omitempty"`
+	Profiles     []sub_model.ImportVolunteerProfile `json:"profiles,omitempty"` // mapped department imports only
+	CreatedAt    time.Time                          `json:"createdAt"`
+	ExpiresAt    time.Time                          `json:"expiresAt"`
+	LastUpdated  time.Time                          `json:"lastUpdated"`
+}
+
+// ImportKindOrDefault returns the session kind, older sessions are always department imports
+func (s *ImportSession) ImportKindOrDefault() ImportKind {
+	if s.Kind == "" {
+		return ImportKindDepartments
+	}
+	return s.Kind
+}
+
+// IsClaimed checks if an import of the session is running, one whose claim ran out is taken to have crashed
+func (s *ImportSession) IsClaimed(now time.Time) bool {
+	return s.Status == ImportSessionExecuting && s.ClaimedUntil != nil && s.ClaimedUntil.After(now)
+}
+
+// IsExpired checks if the session can no longer be used
+func (s *ImportSession) IsExpired(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
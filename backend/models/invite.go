@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type InviteStatus string
+
+const (
+	InvitePending  InviteStatus = "pending"
+	InviteRedeemed InviteStatus = "redeemed"
+	InviteRevoked  InviteStatus = "revoked"
+)
+
+// Invite allows a specific email to create an account on its first OAuth login
+type Invite struct {
+	ID          string       `json:"id"`
+	Email       string       `json:"email"` // lowercased, must match the provider's verified email
+	VolunteerID string       `json:"volunteerId"`
+	AccessLevel AuthLevel    `json:"accessLevel"`
+	Status      InviteStatus `json:"status"`
+	CreatedBy   string       `json:"createdBy"` // admin user ID
+	CreatedAt   time.Time    `json:"createdAt"`
+	ExpiresAt   time.Time    `json:"expiresAt"`
+	RedeemedBy  string       `json:"redeemedBy,omitempty"` // auth user created from this invite
+	RedeemedAt  *time.Time   `json:"redeemedAt,omitempty"`
+	RevokedBy   string       `json:"revokedBy,omitempty"`
+	RevokedAt   *time.Time   `json:"revokedAt,omitempty"`
+}
+
+// IsUsable reports whether the invite can still be redeemed
+func (i *Invite) IsUsable(now time.Time) bool {
+	return i.Status == InvitePending && now.Before(i.ExpiresAt)
+}
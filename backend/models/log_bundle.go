@@ -0,0 +1,44 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// LogBundle is a compressed export of archived logs that were purged from the logs collection
+// The same record is written next to the bundle as its manifest, so the store describes itself without the database
+type LogBundle struct {
+	ID             string        `json:"id"`
+	Format         string        `json:"format"`      // "ndjson+gzip", one SystemLog per line
+	Store          string        `json:"store"`       // where the bundle was written, e.g. "local:/var/log-bundles" or "s3:bucket"
+	DataKey        string        `json:"dataKey"`     // object key of the bundle in the store
+	ManifestKey    string        `json:"manifestKey"` // object key of the manifest in the store
+	SHA256         string        `json:"sha256"`      // checksum of the bundle file
+	Size           int64         `json:"size"`        // bytes of the bundle file
+	LogCount       int           `json:"logCount"`
+	ArchivedBefore time.Time     `json:"archivedBefore"` // logs archived before this were included
+	OldestLog      time.Time     `json:"oldestLog"`      // TimeDetected of the oldest log
+	NewestLog      time.Time     `json:"newestLog"`      // TimeDetected of the newest log
+	SeqRanges      []LogSeqRange `json:"seqRanges"`      // chained logs in the bundle, so the chain can be verified across them
+	CreatedAt      time.Time     `json:"createdAt"`
+	RestoredAt     *time.Time    `json:"restoredAt,omitempty"`
+	RestoredBy     string        `json:"restoredBy,omitempty"`
+
+	// The volunteers the logs are about, so their export and erasure find the bundles holding their logs
+	VolunteerIDs      []string   `json:"volunteerIds,omitempty"`
+	VolunteersIndexed bool       `json:"volunteersIndexed"`     // false on bundles written before VolunteerIDs, those are read instead
+	RewrittenAt       *time.Time `json:"rewrittenAt,omitempty"` // when an erasure last pseudonymized logs in the bundle
+	StaleKeys         []string   `json:"staleKeys,omitempty"`   // replaced files still to be deleted from the store
+}
+
+// MayHoldVolunteer reports whether the bundle can hold logs about a volunteer
+func (b *LogBundle) MayHoldVolunteer(volunteerID string) bool {
+	return !b.VolunteersIndexed || slices.Contains(b.VolunteerIDs, volunteerID)
+}
+
+// LogSeqRange is a run of consecutive chain sequence numbers, LastHash is the Hash of the log at To
+type LogSeqRange struct {
+	From     int64  `json:"from"`
+	To       int64  `json:"to"`
+	LastHash string `json:"lastHash"`
+}
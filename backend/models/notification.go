@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type NotificationKind string
+
+const (
+	NotifyAssigned       NotificationKind = "assigned"        // added to an event, directly or through a department
+	NotifyEventChanged   NotificationKind = "event_changed"   // the time or location of an event changed
+	NotifyEventCancelled NotificationKind = "event_cancelled" // an event was deleted
+	NotifyReminder       NotificationKind = "reminder"        // an event starts soon
+)
+
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	NotificationFailed  NotificationStatus = "failed"  // gave up after the last attempt
+	NotificationSkipped NotificationStatus = "skipped" // opted out or no address, kept for the delivery log
+)
+
+// Channels notifications are delivered on
+const (
+	NotificationEmail   = "email"   // to the volunteer's address
+	NotificationWebhook = "webhook" // JSON to the configured webhook, one per volunteer
+	NotificationChat    = "chat"    // text to a chat bot webhook, one per event
+)
+
+// Notification is a message queued for a volunteer, once handled it stays as the delivery log entry
+type Notification struct {
+	ID            string             `json:"id"` // reminders use their idempotency key, so they are never queued twice
+	Kind          NotificationKind   `json:"kind"`
+	Channel       string             `json:"channel"`
+	VolunteerID   string             `json:"volunteerId,omitempty"` // empty for chat notifications
+	Recipient     string             `json:"recipient,omitempty"`   // email address at the time it was queued
+	EventID       string             `json:"eventId,omitempty"`
+	Subject       string             `json:"subject"`
+	Body          string             `json:"body"`
+	Status        NotificationStatus `json:"status"`
+	Attempts      int                `json:"attempts"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt"` // when a pending notification is sent next
+	LastError     string             `json:"lastError,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt"`
+	SentAt        *time.Time         `json:"sentAt,omitempty"`
+}
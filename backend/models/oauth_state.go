@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// OAuthStatePurpose tells the callback what the login attempt was started for
+type OAuthStatePurpose string
+
+const (
+	OAuthStateLogin OAuthStatePurpose = "login"
+	OAuthStateLink  OAuthStatePurpose = "link"
+)
+
+// OAuthState is a pending OAuth login attempt, stored server-side until the callback consumes it
+type OAuthState struct {
+	ID           string            `json:"id"` // the state value sent to the provider
+	Provider     string            `json:"provider"`
+	Purpose      OAuthStatePurpose `json:"purpose"`
+	UserID       string            `json:"userId"`       // user that started a link attempt, empty for login
+	CodeVerifier string            `json:"codeVerifier"` // PKCE verifier, only the S256 challenge leaves the server
+	Nonce        string            `json:"nonce"`        // must be echoed back in the ID token
+	CreatedAt    time.Time         `json:"createdAt"`
+	ExpiresAt    time.Time         `json:"expiresAt"`
+}
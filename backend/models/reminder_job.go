@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type ReminderJobStatus string
+
+const (
+	ReminderJobRunning ReminderJobStatus = "running"
+	ReminderJobDone    ReminderJobStatus = "done"
+)
+
+// ReminderJob records that the reminders of an event for one offset were queued
+// Its ID is derived from the event, its time and the offset, so each reminder is queued by one instance once
+type ReminderJob struct {
+	ID          string            `json:"id"`
+	EventID     string            `json:"eventId"`
+	EventTime   time.Time         `json:"eventTime"` // a rescheduled event gets new jobs
+	Offset      string            `json:"offset"`    // how long before the event, e.g. "24h0m0s"
+	Status      ReminderJobStatus `json:"status"`
+	LeaseUntil  time.Time         `json:"leaseUntil"` // a running job whose lease passed is taken over by the next run
+	Queued      int               `json:"queued"`     // notifications queued
+	CreatedAt   time.Time         `json:"createdAt"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+}
+
+// ReminderJobID is the ID of the job for an event at a time and an offset
+func ReminderJobID(eventID string, eventTime time.Time, offset time.Duration) string {
+	return fmt.Sprintf("%s_%d_%d", eventID, eventTime.Unix(), int64(offset.Seconds()))
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	sub_model "sheduling-server/models/sub_models"
+	"time"
+)
+
+// RetentionRule sets how many days matching logs stay active before they are archived
+// Empty Category, Type and Severity match any value, a rule with none of them set replaces LOG_RETENTION_DAYS
+// A log follows the most specific rule matching it: a type beats a category, which beats a severity
+type RetentionRule struct {
+	ID            string            `json:"id"` // the MatchKey, so there is one rule per match
+	Category      string            `json:"category,omitempty"`
+	Type          sub_model.LogType `json:"type,omitempty"`
+	Severity      string            `json:"severity,omitempty"`
+	RetentionDays int               `json:"retentionDays"`
+	Description   string            `json:"description,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	CreatedBy     string            `json:"createdBy,omitempty"`
+	LastUpdated   time.Time         `json:"lastUpdated"`
+	UpdatedBy     string            `json:"updatedBy,omitempty"`
+}
+
+// MatchKey identifies what a rule matches, e.g. "*.USER_LOGIN.*" or "attendance.*.ERROR"
+func (r *RetentionRule) MatchKey() string {
+	key := func(value string) string {
+		if value == "" {
+			return "*"
+		}
+		return value
+	}
+	return key(r.Category) + "." + key(string(r.Type)) + "." + key(r.Severity)
+}
+
+// Matches reports whether the rule applies to a log
+func (r *RetentionRule) Matches(log *SystemLog) bool {
+	return (r.Category == "" || r.Category == log.Category) &&
+		(r.Type == "" || r.Type == log.Type) &&
+		(r.Severity == "" || r.Severity == log.Severity)
+}
+
+// Specificity orders rules matching the same log, the higher one applies
+func (r *RetentionRule) Specificity() int {
+	specificity := 0
+	if r.Type != "" {
+		specificity += 4
+	}
+	if r.Category != "" {
+		specificity += 2
+	}
+	if r.Severity != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// EffectiveRetentionRule returns the rule a log follows, nil when no rule matches it
+func EffectiveRetentionRule(rules []*RetentionRule, log *SystemLog) *RetentionRule {
+	var effective *RetentionRule
+	for _, rule := range rules {
+		if rule.Matches(log) && (effective == nil || rule.Specificity() > effective.Specificity()) {
+			effective = rule
+		}
+	}
+	return effective
+}
@@ -5,6 +5,23 @@ import (
 	"time"
 )
 
+// PersonalMetadataKeys are the metadata values that identify a volunteer, at the top level or in changes
+var PersonalMetadataKeys = []string{
+	sub_model.META_VOLUNTEER_NAME,
+	sub_model.META_OLD_VOLUNTEER_NAME,
+	sub_model.META_NEW_VOLUNTEER_NAME,
+	sub_model.META_EMAIL,
+	sub_model.META_INVITE_EMAIL,
+	// profile changes recorded by volunteer updates
+	"oldEmail", "newEmail",
+	"oldPhone", "newPhone",
+	"oldStudentId", "newStudentId",
+	"oldYearLevel", "newYearLevel",
+	"oldSection", "newSection",
+	"oldSkills", "newSkills",
+	"oldTags", "newTags",
+}
+
 // SystemLog represents an audit log entry for system operations
 // Standard Metadata Fields:
 // - userId, username: Actor performing the action
@@ -26,4 +43,47 @@ type SystemLog struct {
 	Severity     string     // Severity level: "INFO", "WARNING", "ERROR"
 	IsArchived   bool       // Whether the log has been archived (for retention policy)
 	ArchiveDate  *time.Time // Date when the log was archived
+
+	// Hash chain, linked shortly after the log is created so edits, deletions and reordering can be detected
+	// Archival fields and LastUpdated are not hashed, they change by design
+	Seq          int64  // position in the chain, 0 for logs written outside it or still pending
+	ChainPending bool   // stored but not linked yet, the chain links it in the background
+	PrevHash     string // Hash of the log before this one
+	ContentHash  string // hash of the canonical ID, time, type, category, severity, erased metadata and PersonalHash
+	Hash         string // hash of PrevHash, Seq and ContentHash
+
+	// The personal metadata values are committed to separately, so a pseudonymized log still verifies exactly
+	PersonalHash string // hash of PersonalSalt and the personal values, empty when the log has none
+	PersonalSalt string // removed when the log is pseudonymized, so the erased values can't be guessed from PersonalHash
+}
+
+// Pseudonymize replaces the personal values of the log and drops its PersonalSalt, returns false if it had none
+// The volunteer ID stays so the audit trail keeps its shape, and the log still verifies through PersonalHash
+// Without the salt the erased values can't be confirmed by guessing them against PersonalHash
+func (log *SystemLog) Pseudonymize(erasedAt time.Time) bool {
+	changed := erasePersonalValues(log.Metadata)
+	if changes, ok := log.Metadata[sub_model.META_CHANGES].(map[string]interface{}); ok {
+		changed = erasePersonalValues(changes) || changed
+	}
+	if !changed {
+		return false
+	}
+	log.Metadata[sub_model.META_ERASED_AT] = erasedAt.UTC().Format(time.RFC3339)
+	log.PersonalSalt = ""
+	log.LastUpdated = erasedAt.UTC()
+	return true
+}
+
+// erasePersonalValues replaces the personal values in metadata, returns true if any were replaced
+func erasePersonalValues(metadata map[string]interface{}) bool {
+	changed := false
+	for _, key := range PersonalMetadataKeys {
+		value, ok := metadata[key]
+		if !ok || value == nil || value == "" || value == sub_model.ERASED_VALUE {
+			continue
+		}
+		metadata[key] = sub_model.ERASED_VALUE
+		changed = true
+	}
+	return changed
 }
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// API key scopes, "<resource>:read" or "<resource>:write" (write also allows read)
+const (
+	ScopeEventsRead       = "events:read"
+	ScopeEventsWrite      = "events:write"
+	ScopeVolunteersRead   = "volunteers:read"
+	ScopeVolunteersWrite  = "volunteers:write"
+	ScopeDepartmentsRead  = "departments:read"
+	ScopeDepartmentsWrite = "departments:write"
+	ScopeLogsRead         = "logs:read"
+)
+
+// APIKeyScopes lists every scope that can be granted to a service account
+var APIKeyScopes = []string{
+	ScopeEventsRead,
+	ScopeEventsWrite,
+	ScopeVolunteersRead,
+	ScopeVolunteersWrite,
+	ScopeDepartmentsRead,
+	ScopeDepartmentsWrite,
+	ScopeLogsRead,
+}
+
+// ServiceAccount is a non-human principal (kiosk, reporting script) that authenticates with API keys
+type ServiceAccount struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Scopes      []string  `json:"scopes"` // maximum scopes its keys can be granted
+	IsDisabled  bool      `json:"isDisabled"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// APIKey is a hashed credential for a service account, the plaintext key is only shown once
+type APIKey struct {
+	ID               string     `json:"id"`
+	ServiceAccountID string     `json:"serviceAccountId"`
+	Name             string     `json:"name"`
+	Prefix           string     `json:"prefix"` // public part of the key, used to identify it in the UI
+	KeyHash          string     `json:"-"`      // SHA-256 of the full key
+	Scopes           []string   `json:"scopes"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	LastUsedAt       *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedBy        string     `json:"createdBy"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	RevokedBy        string     `json:"revokedBy,omitempty"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+}
+
+// IsUsable reports whether the key can still authenticate
+func (k *APIKey) IsUsable(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
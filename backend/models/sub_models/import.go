@@ -0,0 +1,41 @@
+package sub_model
+
+import "time"
+
+// ImportDepartment is a department parsed from an import file, waiting to be created
+type ImportDepartment struct {
+	DepartmentName string   `json:"departmentName"`
+	HeadName       string   `json:"headName"`
+	Members        []string `json:"members"`
+	ColumnIndex    int      `json:"columnIndex"`
+	// Rows of the head and members, only set for mapped layouts
+	HeadRowIndex     *int  `json:"headRowIndex,omitempty"`
+	MemberRowIndexes []int `json:"memberRowIndexes,omitempty"`
+}
+
+// ImportEvent is an event row parsed from an import file, names are matched when the import is executed
+type ImportEvent struct {
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	TimeAndDate       time.Time `json:"timeAndDate"`
+	Location          string    `json:"location"`
+	Departments       []string  `json:"departments"`
+	Volunteers        []string  `json:"volunteers"`
+	RowIndex          int       `json:"rowIndex"`
+	DepartmentsColumn int       `json:"departmentsColumn"`
+	VolunteersColumn  int       `json:"volunteersColumn"`
+}
+
+// ImportVolunteerProfile is the profile read for a volunteer from mapped profile columns
+// It is applied to the volunteer if the import creates one
+type ImportVolunteerProfile struct {
+	Name      string   `json:"name"`
+	Email     string   `json:"email,omitempty"`
+	Phone     string   `json:"phone,omitempty"`
+	StudentID string   `json:"studentId,omitempty"`
+	YearLevel string   `json:"yearLevel,omitempty"`
+	Section   string   `json:"section,omitempty"`
+	Skills    []string `json:"skills,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	RowIndex  int      `json:"rowIndex"` // first row the profile was read from
+}
@@ -24,6 +24,24 @@ const (
 	META_PROVIDER           = "provider"
 	META_IS_NEW_USER        = "isNewUser"
 	META_ATTEMPTED_USERNAME = "attemptedUsername"
+	META_EMAIL              = "email"
+)
+
+// API key metadata keys (set on every log made with an API key)
+const (
+	META_AUTH_METHOD          = "authMethod"
+	META_API_KEY_ID           = "apiKeyId"
+	META_API_KEY_NAME         = "apiKeyName"
+	META_SERVICE_ACCOUNT_ID   = "serviceAccountId"
+	META_SERVICE_ACCOUNT_NAME = "serviceAccountName"
+	META_SCOPES               = "scopes"
+)
+
+// Invite metadata keys
+const (
+	META_INVITE_ID         = "inviteId"
+	META_INVITE_EMAIL      = "inviteEmail"
+	META_INVITE_EXPIRES_AT = "inviteExpiresAt"
 )
 
 // User management metadata keys
@@ -44,6 +62,12 @@ const (
 	META_NEW_VOLUNTEER_NAME = "newVolunteerName"
 )
 
+// Volunteer erasure metadata keys
+const (
+	META_ERASED_AT = "erasedAt" // set on logs whose personal data was removed
+	ERASED_VALUE   = "[erased]" // replaces personal values in pseudonymized logs
+)
+
 // Event metadata keys
 const (
 	META_EVENT_ID        = "eventId"
@@ -89,6 +113,8 @@ const (
 	META_ERROR_COUNT                  = "errorCount"
 	META_CREATED_VOLUNTEERS           = "createdVolunteers"
 	META_CREATED_DEPARTMENTS          = "createdDepartments"
+	META_CREATED_EVENTS               = "createdEvents"
+	META_IMPORT_TYPE                  = "importType"
 	META_CONFLICT_RESOLUTION_STRATEGY = "conflictResolutionStrategy"
 	META_DURATION                     = "duration"
 	META_ERROR_MESSAGE                = "errorMessage"
@@ -107,6 +133,21 @@ const (
 	META_RECORD_COUNT  = "recordCount"
 )
 
+// Request metadata keys, set on logs written while handling an HTTP request
+const (
+	META_REQUEST_ID = "requestId"
+	META_CLIENT_IP  = "clientIp"
+	META_USER_AGENT = "userAgent"
+)
+
+// Log cold storage metadata keys
+const (
+	META_BUNDLE_ID       = "bundleId"
+	META_BUNDLE_STORE    = "bundleStore"
+	META_BUNDLE_SHA256   = "bundleSha256"
+	META_ARCHIVED_BEFORE = "archivedBefore"
+)
+
 // Severity levels
 const (
 	SEVERITY_INFO    = "INFO"
@@ -23,6 +23,17 @@ const (
 	OAUTH_LINKED LogType = "OAUTH_LINKED"
 	OAUTH_LOGIN  LogType = "OAUTH_LOGIN"
 
+	// Invites
+	INVITE_CREATED  LogType = "INVITE_CREATED"
+	INVITE_REVOKED  LogType = "INVITE_REVOKED"
+	INVITE_REDEEMED LogType = "INVITE_REDEEMED"
+
+	// Service Accounts & API Keys
+	SERVICE_ACCOUNT_CREATED LogType = "SERVICE_ACCOUNT_CREATED"
+	SERVICE_ACCOUNT_UPDATED LogType = "SERVICE_ACCOUNT_UPDATED"
+	API_KEY_CREATED         LogType = "API_KEY_CREATED"
+	API_KEY_REVOKED         LogType = "API_KEY_REVOKED"
+
 	// Attendance & Scheduling
 	VOLUNTEER_TIMED_IN        LogType = "VOLUNTEER_TIMED_IN"
 	VOLUNTEER_TIMED_OUT       LogType = "VOLUNTEER_TIMED_OUT"
@@ -36,6 +47,7 @@ const (
 	VOLUNTEER_DELETED  LogType = "VOLUNTEER_DELETED"
 	VOLUNTEER_DISABLED LogType = "VOLUNTEER_DISABLED"
 	VOLUNTEER_ENABLED  LogType = "VOLUNTEER_ENABLED"
+	VOLUNTEER_ERASED   LogType = "VOLUNTEER_ERASED" // tombstone of a hard delete, holds no personal data
 
 	// Event Management
 	EVENT_CREATED            LogType = "EVENT_CREATED"
@@ -63,8 +75,39 @@ const (
 	SENSITIVE_DATA_ACCESSED LogType = "SENSITIVE_DATA_ACCESSED"
 	SYSTEM_ERROR            LogType = "SYSTEM_ERROR"
 	CONFIGURATION_CHANGED   LogType = "CONFIGURATION_CHANGED"
+	LOGS_PURGED             LogType = "LOGS_PURGED"   // archived logs moved to a cold storage bundle
+	LOGS_RESTORED           LogType = "LOGS_RESTORED" // bundle imported back into the logs collection
 )
 
+// AllLogTypes lists every log type, for stats and filters
+var AllLogTypes = []LogType{
+	CLEANLOG,
+	USER_LOGIN, USER_LOGIN_FAILED, USER_LOGOUT,
+	USER_CREATED, USER_UPDATED, USER_DISABLED, USER_ENABLED, ACCESS_LEVEL_CHANGED, PASSWORD_CHANGED,
+	OAUTH_LINKED, OAUTH_LOGIN,
+	INVITE_CREATED, INVITE_REVOKED, INVITE_REDEEMED,
+	SERVICE_ACCOUNT_CREATED, SERVICE_ACCOUNT_UPDATED, API_KEY_CREATED, API_KEY_REVOKED,
+	VOLUNTEER_TIMED_IN, VOLUNTEER_TIMED_OUT, ATTENDANCE_STATUS_UPDATED, VOLUNTEER_SCHEDULED, VOLUNTEER_UNSCHEDULED,
+	VOLUNTEER_CREATED, VOLUNTEER_UPDATED, VOLUNTEER_DELETED, VOLUNTEER_DISABLED, VOLUNTEER_ENABLED, VOLUNTEER_ERASED,
+	EVENT_CREATED, EVENT_UPDATED, EVENT_DELETED, EVENT_CANCELLED, EVENT_DEPARTMENT_ADDED, EVENT_DEPARTMENT_REMOVED,
+	DEPARTMENT_CREATED, DEPARTMENT_UPDATED, DEPARTMENT_DELETED, DEPARTMENT_MEMBER_ADDED, DEPARTMENT_MEMBER_REMOVED, DEPARTMENT_ROLE_CHANGED,
+	BATCH_IMPORT_STARTED, BATCH_IMPORT_COMPLETED, BATCH_IMPORT_FAILED, BATCH_CONFLICT_RESOLVED,
+	SENSITIVE_DATA_ACCESSED, SYSTEM_ERROR, CONFIGURATION_CHANGED, LOGS_PURGED, LOGS_RESTORED,
+}
+
+// LogCategories lists every category returned by GetLogTypeCategory
+var LogCategories = []string{
+	"authentication",
+	"user_management",
+	"oauth",
+	"attendance",
+	"volunteer_management",
+	"event_management",
+	"department_management",
+	"batch_operations",
+	"system",
+}
+
 // GetLogTypeCategory returns the category for a given log type
 func GetLogTypeCategory(logType LogType) string {
 	switch logType {
@@ -74,9 +117,13 @@ func GetLogTypeCategory(logType LogType) string {
 		return "user_management"
 	case OAUTH_LINKED, OAUTH_LOGIN:
 		return "oauth"
+	case INVITE_CREATED, INVITE_REVOKED, INVITE_REDEEMED:
+		return "user_management"
+	case SERVICE_ACCOUNT_CREATED, SERVICE_ACCOUNT_UPDATED, API_KEY_CREATED, API_KEY_REVOKED:
+		return "user_management"
 	case VOLUNTEER_TIMED_IN, VOLUNTEER_TIMED_OUT, ATTENDANCE_STATUS_UPDATED, VOLUNTEER_SCHEDULED, VOLUNTEER_UNSCHEDULED:
 		return "attendance"
-	case VOLUNTEER_CREATED, VOLUNTEER_UPDATED, VOLUNTEER_DELETED, VOLUNTEER_DISABLED, VOLUNTEER_ENABLED:
+	case VOLUNTEER_CREATED, VOLUNTEER_UPDATED, VOLUNTEER_DELETED, VOLUNTEER_DISABLED, VOLUNTEER_ENABLED, VOLUNTEER_ERASED:
 		return "volunteer_management"
 	case EVENT_CREATED, EVENT_UPDATED, EVENT_DELETED, EVENT_CANCELLED, EVENT_DEPARTMENT_ADDED, EVENT_DEPARTMENT_REMOVED:
 		return "event_management"
@@ -84,7 +131,7 @@ func GetLogTypeCategory(logType LogType) string {
 		return "department_management"
 	case BATCH_IMPORT_STARTED, BATCH_IMPORT_COMPLETED, BATCH_IMPORT_FAILED, BATCH_CONFLICT_RESOLVED:
 		return "batch_operations"
-	case SENSITIVE_DATA_ACCESSED, SYSTEM_ERROR, CONFIGURATION_CHANGED:
+	case SENSITIVE_DATA_ACCESSED, SYSTEM_ERROR, CONFIGURATION_CHANGED, LOGS_PURGED, LOGS_RESTORED:
 		return "system"
 	case CLEANLOG:
 		return "system"
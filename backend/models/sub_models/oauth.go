@@ -4,16 +4,32 @@ import "time"
 
 type OAuthProvider string
 
+// Built-in providers, generic OIDC providers use their configured name
 const (
-	Google OAuthProvider = "google"
+	Google    OAuthProvider = "google"
+	Microsoft OAuthProvider = "microsoft"
 )
 
 type OAuthToken struct {
-	Provider     OAuthProvider `json:"provider" bson:"provider"`         // google, etc
-	Email        string        `json:"email" bson:"email"`               // email from provider
-	AccessToken  string        `json:"accessToken" bson:"accessToken"`   // OAuth access token
-	RefreshToken string        `json:"refreshToken" bson:"refreshToken"` // OAuth refresh token
-	TokenType    string        `json:"tokenType" bson:"tokenType"`       // Bearer, etc
-	Expiry       time.Time     `json:"expiry" bson:"expiry"`             // token expiration
-	LinkedAt     time.Time     `json:"linkedAt" bson:"linkedAt"`         // when account was linked
+	Provider  OAuthProvider `json:"provider" bson:"provider"`   // google, microsoft, or a configured OIDC provider
+	Email     string        `json:"email" bson:"email"`         // email from provider
+	Subject   string        `json:"subject" bson:"subject"`     // stable user ID at the provider
+	TokenType string        `json:"tokenType" bson:"tokenType"` // Bearer, etc
+	Expiry    time.Time     `json:"expiry" bson:"expiry"`       // token expiration
+	LinkedAt  time.Time     `json:"linkedAt" bson:"linkedAt"`   // when account was linked
+
+	// Plaintext tokens only live in memory, the repository encrypts them into Secrets on write
+	// (records written before encryption may still have them stored until they are rewritten)
+	AccessToken  string           `json:"-" bson:"accessToken"`
+	RefreshToken string           `json:"-" bson:"refreshToken"`
+	Secrets      *EncryptedSecret `json:"-" bson:"secrets"` // encrypted access/refresh tokens
+}
+
+// EncryptedSecret is an envelope encrypted value, the data key is wrapped by the key named KeyID
+type EncryptedSecret struct {
+	KeyID      string // key encryption key used to wrap DataKey
+	DataKey    []byte // per-record AES-256 key, encrypted with the key encryption key
+	KeyNonce   []byte
+	Nonce      []byte
+	Ciphertext []byte
 }
@@ -8,4 +8,16 @@ type VolunteerModel struct {
 	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
 	LastUpdated time.Time `json:"lastUpdated" bson:"lastUpdated"`
 	IsDisabled  bool      `json:"isDisabled" bson:"isDisabled"`
+
+	// Profile, all optional
+	Email     string   `json:"email,omitempty" bson:"email,omitempty"`
+	Phone     string   `json:"phone,omitempty" bson:"phone,omitempty"`
+	StudentID string   `json:"studentId,omitempty" bson:"studentId,omitempty"` // student or employee ID, unique across volunteers
+	YearLevel string   `json:"yearLevel,omitempty" bson:"yearLevel,omitempty"`
+	Section   string   `json:"section,omitempty" bson:"section,omitempty"`
+	Skills    []string `json:"skills,omitempty" bson:"skills,omitempty"`
+	Tags      []string `json:"tags,omitempty" bson:"tags,omitempty"`
+
+	// Schedule emails are not sent when set
+	EmailOptOut bool `json:"emailOptOut,omitempty" bson:"emailOptOut,omitempty"`
 }
@@ -0,0 +1,9 @@
+package repository
+
+import "errors"
+
+// ErrStudentIDTaken is returned when a volunteer's student ID is already used by another volunteer
+var ErrStudentIDTaken = errors.New("student ID is already used by another volunteer")
+
+// ErrRetentionRuleExists is returned when a rule with the same category, type and severity already exists
+var ErrRetentionRuleExists = errors.New("a retention rule for this category, type and severity already exists")
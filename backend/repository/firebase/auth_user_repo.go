@@ -2,9 +2,13 @@ package firebase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/utils"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
@@ -26,7 +30,12 @@ func (r *authUserRepo) CreateUser(ctx context.Context, user *models.AuthUser) er
 		user.ID = docRef.ID
 	}
 
-	_, err := r.firestore.Collection(authUsersCollection).Doc(user.ID).Set(ctx, user)
+	stored, err := storedAuthUser(user)
+	if err != nil {
+		return fmt.Errorf("failed to create auth user: %v", err)
+	}
+
+	_, err = r.firestore.Collection(authUsersCollection).Doc(user.ID).Set(ctx, stored)
 	if err != nil {
 		return fmt.Errorf("failed to create auth user: %v", err)
 	}
@@ -55,6 +64,7 @@ func (r *authUserRepo) GetByUsername(ctx context.Context, username string) (*mod
 	}
 
 	user.ID = doc.Ref.ID
+	user.MigrateThirdAuth()
 	return &user, nil
 }
 
@@ -71,38 +81,55 @@ func (r *authUserRepo) GetUserByID(ctx context.Context, id string) (*models.Auth
 	}
 
 	user.ID = docSnap.Ref.ID
+	user.MigrateThirdAuth()
 	return &user, nil
 }
 
-// GetByGoogleEmail retrieves an auth user by their Google email
-func (r *authUserRepo) GetByGoogleEmail(ctx context.Context, email string) (*models.AuthUser, error) {
-	iter := r.firestore.Collection(authUsersCollection).
-		Where("ThirdAuth.Email", "==", email).
-		Where("ThirdAuth.Provider", "==", "google").
-		Limit(1).
-		Documents(ctx)
-	defer iter.Stop()
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
-		return nil, nil // Return nil instead of error when not found
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to query user by Google email: %v", err)
+// GetByOAuthAccount retrieves the auth user an OAuth account is linked to, by its subject
+// Links stored before subjects were are matched by email, nil is returned when no user has the account
+func (r *authUserRepo) GetByOAuthAccount(ctx context.Context, provider sub_model.OAuthProvider, subject, email string) (*models.AuthUser, error) {
+	queries := []firestore.Query{
+		r.firestore.Collection(authUsersCollection).Where("OAuthKeys", "array-contains", models.OAuthKey(provider, subject)),
+		r.firestore.Collection(authUsersCollection).Where("OAuthKeys", "array-contains", models.LegacyOAuthKey(provider, email)),
+		// Records not written since links moved to OAuthAccounts
+		r.firestore.Collection(authUsersCollection).
+			Where("ThirdAuth.Email", "==", email).
+			Where("ThirdAuth.Provider", "==", string(provider)),
 	}
 
-	var user models.AuthUser
-	if err := doc.DataTo(&user); err != nil {
-		return nil, fmt.Errorf("failed to parse auth user data: %v", err)
-	}
+	for _, query := range queries {
+		docs, err := query.Limit(1).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query user by %s account: %v", provider, err)
+		}
+		if len(docs) == 0 {
+			continue
+		}
 
-	user.ID = doc.Ref.ID
-	return &user, nil
+		var user models.AuthUser
+		if err := docs[0].DataTo(&user); err != nil {
+			return nil, fmt.Errorf("failed to parse auth user data: %v", err)
+		}
+
+		user.ID = docs[0].Ref.ID
+		user.MigrateThirdAuth()
+		// A legacy link of this provider is only taken when it has no subject yet
+		if account := user.OAuthAccount(provider); account == nil || (account.Subject != "" && account.Subject != subject) {
+			continue
+		}
+		return &user, nil
+	}
+	return nil, nil
 }
 
 // UpdateUser updates an existing auth user
 func (r *authUserRepo) UpdateUser(ctx context.Context, user *models.AuthUser) error {
-	_, err := r.firestore.Collection(authUsersCollection).Doc(user.ID).Set(ctx, user)
+	stored, err := storedAuthUser(user)
+	if err != nil {
+		return fmt.Errorf("failed to update auth user: %v", err)
+	}
+
+	_, err = r.firestore.Collection(authUsersCollection).Doc(user.ID).Set(ctx, stored)
 	if err != nil {
 		return fmt.Errorf("failed to update auth user: %v", err)
 	}
@@ -130,8 +157,28 @@ func (r *authUserRepo) ListUsers(ctx context.Context) ([]*models.AuthUser, error
 		}
 
 		user.ID = doc.Ref.ID
+		user.MigrateThirdAuth()
 		users = append(users, &user)
 	}
 
 	return users, nil
 }
+
+// storedAuthUser returns a copy of the user with its OAuth tokens encrypted for storage
+// Without a configured key the tokens are dropped rather than stored in plaintext
+func storedAuthUser(user *models.AuthUser) (*models.AuthUser, error) {
+	stored := *user
+	stored.OAuthAccounts = slices.Clone(stored.OAuthAccounts)
+	stored.MigrateThirdAuth()
+	for i := range stored.OAuthAccounts {
+		account := &stored.OAuthAccounts[i]
+		if err := utils.SealOAuthToken(account); err != nil {
+			if !errors.Is(err, utils.ErrNoTokenKey) {
+				return nil, fmt.Errorf("failed to encrypt oauth tokens: %v", err)
+			}
+			account.AccessToken = ""
+			account.RefreshToken = ""
+		}
+	}
+	return &stored, nil
+}
@@ -2,11 +2,13 @@ package firebase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -227,3 +229,68 @@ func (r *departmentRepo) RemoveMemberFromDepartment(ctx context.Context, departm
 
 	return nil
 }
+
+// departmentSortFields maps sort options to stored fields
+var departmentSortFields = map[string]sortField{
+	repository.SortByName:      {path: "DepartmentName"},
+	repository.SortByCreatedAt: {path: "CreatedAt", isTime: true},
+}
+
+// QueryDepartments returns a page of departments matching the query, sorted by name unless asked otherwise
+func (r *departmentRepo) QueryDepartments(ctx context.Context, q repository.DepartmentQuery) (repository.Page[*models.DepartmentModel], error) {
+	if q.SortBy == "" {
+		q.SortBy = repository.SortByName
+	}
+	field, ok := departmentSortFields[q.SortBy]
+	if !ok {
+		return repository.Page[*models.DepartmentModel]{}, fmt.Errorf("unsupported sort: %s", q.SortBy)
+	}
+
+	query := r.firestore.Collection(departmentsCollection).Query
+	if q.IsDisabled != nil {
+		query = query.Where("IsDisabled", "==", *q.IsDisabled)
+	}
+	if q.SortBy == repository.SortByCreatedAt {
+		if q.CreatedAfter != nil {
+			query = query.Where("CreatedAt", ">=", *q.CreatedAfter)
+		}
+		if q.CreatedBefore != nil {
+			query = query.Where("CreatedAt", "<", *q.CreatedBefore)
+		}
+	}
+
+	names := newNameFilter(q.Search, q.Prefix)
+	page, err := queryPage(ctx, query, field, q.ListOptions, decodeDepartment, func(dept *models.DepartmentModel) bool {
+		if q.VolunteerID != "" && !hasMember(dept, q.VolunteerID) {
+			return false
+		}
+		if !timeInRange(dept.CreatedAt, q.CreatedAfter, q.CreatedBefore) {
+			return false
+		}
+		return names.matches(dept.DepartmentName)
+	})
+	if err != nil && !errors.Is(err, repository.ErrInvalidCursor) {
+		return page, fmt.Errorf("failed to query departments: %v", err)
+	}
+	return page, err
+}
+
+// decodeDepartment reads a department document
+func decodeDepartment(doc *firestore.DocumentSnapshot) (*models.DepartmentModel, error) {
+	var dept models.DepartmentModel
+	if err := doc.DataTo(&dept); err != nil {
+		return nil, fmt.Errorf("failed to parse department data: %v", err)
+	}
+	dept.ID = doc.Ref.ID
+	return &dept, nil
+}
+
+// hasMember checks if the volunteer is a member of the department
+func hasMember(dept *models.DepartmentModel, volunteerID string) bool {
+	for _, member := range dept.VolunteerMembers {
+		if member.VolunteerID == volunteerID {
+			return true
+		}
+	}
+	return false
+}
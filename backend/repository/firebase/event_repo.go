@@ -2,11 +2,13 @@ package firebase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -346,15 +348,17 @@ func (r *eventScheduleRepo) RemoveVolunteerFromEvent(ctx context.Context, eventI
 	}
 
 	// Remove from voluntaryVolunteers (in case they're there)
+	voluntaryFound := false
 	newVoluntaryVolunteers := []string{}
 	for _, vID := range event.VoluntaryVolunteers {
 		if vID == volunteerID {
+			voluntaryFound = true
 			continue // Skip this volunteer
 		}
 		newVoluntaryVolunteers = append(newVoluntaryVolunteers, vID)
 	}
 
-	if !statusFound && !scheduledFound {
+	if !statusFound && !scheduledFound && !voluntaryFound {
 		return fmt.Errorf("volunteer %s not found in event %s", volunteerID, eventID)
 	}
 
@@ -370,3 +374,85 @@ func (r *eventScheduleRepo) RemoveVolunteerFromEvent(ctx context.Context, eventI
 
 	return nil
 }
+
+// eventSortFields maps sort options to stored fields
+var eventSortFields = map[string]sortField{
+	repository.SortByTimeAndDate: {path: "TimeAndDate", isTime: true},
+	repository.SortByName:        {path: "Name"},
+	repository.SortByCreatedAt:   {path: "CreateAt", isTime: true},
+}
+
+// QueryEvents returns a page of events matching the query, sorted by date unless asked otherwise
+func (r *eventScheduleRepo) QueryEvents(ctx context.Context, q repository.EventQuery) (repository.Page[*models.EventSchedule], error) {
+	if q.SortBy == "" {
+		q.SortBy = repository.SortByTimeAndDate
+	}
+	field, ok := eventSortFields[q.SortBy]
+	if !ok {
+		return repository.Page[*models.EventSchedule]{}, fmt.Errorf("unsupported sort: %s", q.SortBy)
+	}
+
+	query := r.firestore.Collection(eventsCollection).Query
+	if q.IsDisabled != nil {
+		query = query.Where("IsDisabled", "==", *q.IsDisabled)
+	}
+	if q.SortBy == repository.SortByTimeAndDate {
+		if q.From != nil {
+			query = query.Where("TimeAndDate", ">=", *q.From)
+		}
+		if q.To != nil {
+			query = query.Where("TimeAndDate", "<", *q.To)
+		}
+	}
+
+	names := newNameFilter(q.Search, q.Prefix)
+	page, err := queryPage(ctx, query, field, q.ListOptions, decodeEvent, func(event *models.EventSchedule) bool {
+		if q.DepartmentID != "" && !containsID(event.AssignedGroups, q.DepartmentID) {
+			return false
+		}
+		if q.VolunteerID != "" && !isScheduled(event, q.VolunteerID) {
+			return false
+		}
+		if !timeInRange(event.TimeAndDate, q.From, q.To) {
+			return false
+		}
+		return names.matches(event.Name)
+	})
+	if err != nil && !errors.Is(err, repository.ErrInvalidCursor) {
+		return page, fmt.Errorf("failed to query events: %v", err)
+	}
+	return page, err
+}
+
+// decodeEvent reads an event document
+func decodeEvent(doc *firestore.DocumentSnapshot) (*models.EventSchedule, error) {
+	var event models.EventSchedule
+	if err := doc.DataTo(&event); err != nil {
+		return nil, fmt.Errorf("failed to parse event data: %v", err)
+	}
+	event.ID = doc.Ref.ID
+	return &event, nil
+}
+
+// isScheduled checks if the volunteer is scheduled, volunteered or has a status in the event
+func isScheduled(event *models.EventSchedule, volunteerID string) bool {
+	if containsID(event.ScheduledVolunteers, volunteerID) || containsID(event.VoluntaryVolunteers, volunteerID) {
+		return true
+	}
+	for _, status := range event.Statuses {
+		if status.VolunteerID == volunteerID {
+			return true
+		}
+	}
+	return false
+}
+
+// containsID checks if ids has id
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ type FirebaseDB struct {
 	app       *firebase.App
 	firestore *firestore.Client
 	auth      *auth.Client
+	logChain  *logChainer
 }
 
 // NewFirebaseDB initializes a new Firebase connection
@@ -55,6 +56,7 @@ func NewFirebaseDB(ctx context.Context, credentialsPath, credentialsJSON, projec
 		app:       app,
 		firestore: firestoreClient,
 		auth:      authClient,
+		logChain:  newLogChainer(firestoreClient),
 	}, nil
 }
 
@@ -91,10 +93,75 @@ func (db *FirebaseDB) EventSchedules() repository.EventScheduleRepository {
 func (db *FirebaseDB) Logs() repository.LogRepository {
 	return &logRepo{
 		firestore: db.firestore,
+		chain:     db.logChain,
+	}
+}
+
+// OAuthStates returns the OAuth state repository implementation
+func (db *FirebaseDB) OAuthStates() repository.OAuthStateRepository {
+	return &oauthStateRepo{
+		firestore: db.firestore,
+	}
+}
+
+// Invites returns the invite repository implementation
+func (db *FirebaseDB) Invites() repository.InviteRepository {
+	return &inviteRepo{
+		firestore: db.firestore,
+	}
+}
+
+// ServiceAccounts returns the service account repository implementation
+func (db *FirebaseDB) ServiceAccounts() repository.ServiceAccountRepository {
+	return &serviceAccountRepo{
+		firestore: db.firestore,
+	}
+}
+
+// LogBundles returns the log bundle repository implementation
+func (db *FirebaseDB) LogBundles() repository.LogBundleRepository {
+	return &logBundleRepo{
+		firestore: db.firestore,
+	}
+}
+
+// RetentionRules returns the retention rule repository implementation
+func (db *FirebaseDB) RetentionRules() repository.RetentionRuleRepository {
+	return &retentionRuleRepo{
+		firestore: db.firestore,
+	}
+}
+
+// Notifications returns the notification repository implementation
+func (db *FirebaseDB) Notifications() repository.NotificationRepository {
+	return &notificationRepo{
+		firestore: db.firestore,
+	}
+}
+
+// ReminderJobs returns the reminder job repository implementation
+func (db *FirebaseDB) ReminderJobs() repository.ReminderJobRepository {
+	return &reminderJobRepo{
+		firestore: db.firestore,
+	}
+}
+
+// ImportSessions returns the import session repository implementation
+func (db *FirebaseDB) ImportSessions() repository.ImportSessionRepository {
+	return &importSessionRepo{
+		firestore: db.firestore,
+	}
+}
+
+// NewUnitOfWork starts a unit of work backed by Firestore batched writes
+func (db *FirebaseDB) NewUnitOfWork() repository.UnitOfWork {
+	return &unitOfWork{
+		firestore: db.firestore,
 	}
 }
 
 // Close closes all Firebase connections
 func (db *FirebaseDB) Close() error {
+	db.logChain.close()
 	return db.firestore.Close()
 }
@@ -0,0 +1,167 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sheduling-server/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+type importSessionRepo struct {
+	firestore *firestore.Client
+}
+
+const importSessionsCollection = "import_sessions"
+
+// CreateSession stores a previewed import
+func (r *importSessionRepo) CreateSession(ctx context.Context, session *models.ImportSession) error {
+	if session.ID == "" {
+		docRef := r.firestore.Collection(importSessionsCollection).NewDoc()
+		session.ID = docRef.ID
+	}
+
+	_, err := r.firestore.Collection(importSessionsCollection).Doc(session.ID).Create(ctx, session)
+	if err != nil {
+		return fmt.Errorf("failed to create import session: %v", err)
+	}
+	return nil
+}
+
+// GetSession retrieves an import session by its ID
+func (r *importSessionRepo) GetSession(ctx context.Context, id string) (*models.ImportSession, error) {
+	docSnap, err := r.firestore.Collection(importSessionsCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import session: %v", err)
+	}
+
+	var session models.ImportSession
+	if err := docSnap.DataTo(&session); err != nil {
+		return nil, fmt.Errorf("failed to parse import session data: %v", err)
+	}
+
+	session.ID = docSnap.Ref.ID
+	return &session, nil
+}
+
+// ListSessionsByUser retrieves all import sessions created by a user
+func (r *importSessionRepo) ListSessionsByUser(ctx context.Context, userID string) ([]*models.ImportSession, error) {
+	iter := r.firestore.Collection(importSessionsCollection).
+		Where("CreatedBy", "==", userID).
+		Documents(ctx)
+	defer iter.Stop()
+
+	sessions := []*models.ImportSession{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate import sessions: %v", err)
+		}
+
+		var session models.ImportSession
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("failed to parse import session data: %v", err)
+		}
+
+		session.ID = doc.Ref.ID
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+// ClaimSession moves a pending session to executing in a transaction, with a claim that lasts for lease
+// Returns false if the session is already being executed and its claim hasn't run out, or was imported
+func (r *importSessionRepo) ClaimSession(ctx context.Context, id string, now time.Time, lease time.Duration) (bool, error) {
+	docRef := r.firestore.Collection(importSessionsCollection).Doc(id)
+	claimed := false
+
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		docSnap, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+
+		var session models.ImportSession
+		if err := docSnap.DataTo(&session); err != nil {
+			return err
+		}
+		if session.IsClaimed(now) || session.Status == models.ImportSessionCompleted {
+			return nil
+		}
+
+		claimed = true
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "Status", Value: string(models.ImportSessionExecuting)},
+			{Path: "ClaimedUntil", Value: now.Add(lease)},
+			{Path: "LastUpdated", Value: now},
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim import session: %v", err)
+	}
+	return claimed, nil
+}
+
+// ReleaseSession moves a session back to pending so a failed import can be retried
+func (r *importSessionRepo) ReleaseSession(ctx context.Context, id string) error {
+	_, err := r.firestore.Collection(importSessionsCollection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "Status", Value: string(models.ImportSessionPending)},
+		{Path: "ClaimedUntil", Value: firestore.Delete},
+		{Path: "LastUpdated", Value: time.Now().UTC()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release import session: %v", err)
+	}
+	return nil
+}
+
+// DeleteSession removes an import session
+func (r *importSessionRepo) DeleteSession(ctx context.Context, id string) error {
+	_, err := r.firestore.Collection(importSessionsCollection).Doc(id).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete import session: %v", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes import sessions that expired before the given time
+func (r *importSessionRepo) DeleteExpiredSessions(ctx context.Context, before time.Time) (int, error) {
+	docs, err := r.firestore.Collection(importSessionsCollection).
+		Where("ExpiresAt", "<", before).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired import sessions: %v", err)
+	}
+
+	deletedCount := 0
+	batch := r.firestore.Batch()
+	for i, doc := range docs {
+		batch.Delete(doc.Ref)
+
+		if (i+1)%500 == 0 {
+			_, err := batch.Commit(ctx)
+			if err != nil {
+				return deletedCount, fmt.Errorf("failed to commit batch: %v", err)
+			}
+			batch = r.firestore.Batch()
+		}
+		deletedCount++
+	}
+
+	if deletedCount%500 != 0 {
+		_, err := batch.Commit(ctx)
+		if err != nil {
+			return deletedCount, fmt.Errorf("failed to commit final batch: %v", err)
+		}
+	}
+
+	return deletedCount, nil
+}
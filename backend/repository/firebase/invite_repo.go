@@ -0,0 +1,199 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"sheduling-server/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+type inviteRepo struct {
+	firestore *firestore.Client
+}
+
+const invitesCollection = "invites"
+
+// CreateInvite adds a new invite to Firestore
+func (r *inviteRepo) CreateInvite(ctx context.Context, invite *models.Invite) error {
+	if invite.ID == "" {
+		docRef := r.firestore.Collection(invitesCollection).NewDoc()
+		invite.ID = docRef.ID
+	}
+
+	_, err := r.firestore.Collection(invitesCollection).Doc(invite.ID).Set(ctx, invite)
+	if err != nil {
+		return fmt.Errorf("failed to create invite: %v", err)
+	}
+	return nil
+}
+
+// GetInviteByID retrieves an invite by its ID
+func (r *inviteRepo) GetInviteByID(ctx context.Context, id string) (*models.Invite, error) {
+	docSnap, err := r.firestore.Collection(invitesCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %v", err)
+	}
+
+	var invite models.Invite
+	if err := docSnap.DataTo(&invite); err != nil {
+		return nil, fmt.Errorf("failed to parse invite data: %v", err)
+	}
+
+	invite.ID = docSnap.Ref.ID
+	return &invite, nil
+}
+
+// ListInvites retrieves all invites, newest first
+func (r *inviteRepo) ListInvites(ctx context.Context) ([]*models.Invite, error) {
+	iter := r.firestore.Collection(invitesCollection).
+		OrderBy("CreatedAt", firestore.Desc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	invites := []*models.Invite{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate invites: %v", err)
+		}
+
+		var invite models.Invite
+		if err := doc.DataTo(&invite); err != nil {
+			return nil, fmt.Errorf("failed to parse invite data: %v", err)
+		}
+
+		invite.ID = doc.Ref.ID
+		invites = append(invites, &invite)
+	}
+
+	return invites, nil
+}
+
+// GetUsableInviteByEmail retrieves the newest pending, unexpired invite for an email
+// Returns nil when there is no usable invite
+func (r *inviteRepo) GetUsableInviteByEmail(ctx context.Context, email string) (*models.Invite, error) {
+	docs, err := r.firestore.Collection(invitesCollection).
+		Where("Email", "==", email).
+		Where("Status", "==", string(models.InvitePending)).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invites by email: %v", err)
+	}
+
+	now := time.Now().UTC()
+	invites := []*models.Invite{}
+	for _, doc := range docs {
+		var invite models.Invite
+		if err := doc.DataTo(&invite); err != nil {
+			return nil, fmt.Errorf("failed to parse invite data: %v", err)
+		}
+		invite.ID = doc.Ref.ID
+		if invite.IsUsable(now) {
+			invites = append(invites, &invite)
+		}
+	}
+
+	if len(invites) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.After(invites[j].CreatedAt)
+	})
+	return invites[0], nil
+}
+
+// RedeemInvite marks an invite redeemed and creates the invited user atomically
+// so an invite can never create more than one account
+func (r *inviteRepo) RedeemInvite(ctx context.Context, inviteID string, user *models.AuthUser) error {
+	inviteRef := r.firestore.Collection(invitesCollection).Doc(inviteID)
+	userRef := r.firestore.Collection(authUsersCollection).NewDoc()
+	if user.ID != "" {
+		userRef = r.firestore.Collection(authUsersCollection).Doc(user.ID)
+	}
+
+	stored, err := storedAuthUser(user)
+	if err != nil {
+		return fmt.Errorf("failed to redeem invite: %v", err)
+	}
+
+	err = r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(inviteRef)
+		if err != nil {
+			return err
+		}
+
+		var invite models.Invite
+		if err := docSnap.DataTo(&invite); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if !invite.IsUsable(now) {
+			return fmt.Errorf("invite is no longer valid")
+		}
+
+		user.ID = userRef.ID
+		stored.ID = userRef.ID
+		if err := tx.Create(userRef, stored); err != nil {
+			return err
+		}
+
+		return tx.Update(inviteRef, []firestore.Update{
+			{Path: "Status", Value: string(models.InviteRedeemed)},
+			{Path: "RedeemedBy", Value: userRef.ID},
+			{Path: "RedeemedAt", Value: now},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to redeem invite: %v", err)
+	}
+	return nil
+}
+
+// RevokeInvite marks a pending invite as revoked
+func (r *inviteRepo) RevokeInvite(ctx context.Context, inviteID string, revokedBy string) error {
+	inviteRef := r.firestore.Collection(invitesCollection).Doc(inviteID)
+
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(inviteRef)
+		if err != nil {
+			return err
+		}
+
+		var invite models.Invite
+		if err := docSnap.DataTo(&invite); err != nil {
+			return err
+		}
+		if invite.Status != models.InvitePending {
+			return fmt.Errorf("invite is already %s", invite.Status)
+		}
+
+		return tx.Update(inviteRef, []firestore.Update{
+			{Path: "Status", Value: string(models.InviteRevoked)},
+			{Path: "RevokedBy", Value: revokedBy},
+			{Path: "RevokedAt", Value: time.Now().UTC()},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite: %v", err)
+	}
+	return nil
+}
+
+// DeleteInvite removes an invite from Firestore
+func (r *inviteRepo) DeleteInvite(ctx context.Context, inviteID string) error {
+	_, err := r.firestore.Collection(invitesCollection).Doc(inviteID).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete invite: %v", err)
+	}
+	return nil
+}
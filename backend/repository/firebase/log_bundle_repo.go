@@ -0,0 +1,98 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sheduling-server/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+type logBundleRepo struct {
+	firestore *firestore.Client
+}
+
+const logBundlesCollection = "log_bundles"
+
+// CreateBundle stores the record of a written bundle
+func (r *logBundleRepo) CreateBundle(ctx context.Context, bundle *models.LogBundle) error {
+	if bundle.ID == "" {
+		docRef := r.firestore.Collection(logBundlesCollection).NewDoc()
+		bundle.ID = docRef.ID
+	}
+
+	_, err := r.firestore.Collection(logBundlesCollection).Doc(bundle.ID).Create(ctx, bundle)
+	if err != nil {
+		return fmt.Errorf("failed to create log bundle: %v", err)
+	}
+	return nil
+}
+
+// GetBundle retrieves a bundle record by its ID
+func (r *logBundleRepo) GetBundle(ctx context.Context, id string) (*models.LogBundle, error) {
+	docSnap, err := r.firestore.Collection(logBundlesCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log bundle: %v", err)
+	}
+
+	var bundle models.LogBundle
+	if err := docSnap.DataTo(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse log bundle data: %v", err)
+	}
+
+	bundle.ID = docSnap.Ref.ID
+	return &bundle, nil
+}
+
+// ListBundles retrieves all bundle records, newest first
+func (r *logBundleRepo) ListBundles(ctx context.Context) ([]*models.LogBundle, error) {
+	iter := r.firestore.Collection(logBundlesCollection).
+		OrderBy("CreatedAt", firestore.Desc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	bundles := []*models.LogBundle{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate log bundles: %v", err)
+		}
+
+		var bundle models.LogBundle
+		if err := doc.DataTo(&bundle); err != nil {
+			return nil, fmt.Errorf("failed to parse log bundle data: %v", err)
+		}
+
+		bundle.ID = doc.Ref.ID
+		bundles = append(bundles, &bundle)
+	}
+
+	return bundles, nil
+}
+
+// UpdateBundle overwrites the record of a bundle
+func (r *logBundleRepo) UpdateBundle(ctx context.Context, bundle *models.LogBundle) error {
+	_, err := r.firestore.Collection(logBundlesCollection).Doc(bundle.ID).Set(ctx, bundle)
+	if err != nil {
+		return fmt.Errorf("failed to update log bundle: %v", err)
+	}
+	return nil
+}
+
+// MarkBundleRestored records when and by whom a bundle was imported back
+func (r *logBundleRepo) MarkBundleRestored(ctx context.Context, id string, restoredBy string) error {
+	_, err := r.firestore.Collection(logBundlesCollection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "RestoredAt", Value: time.Now().UTC()},
+		{Path: "RestoredBy", Value: restoredBy},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark log bundle restored: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,621 @@
+package firebase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The chain head holds the sequence number and hash of the newest chained log
+const (
+	logChainCollection = "log_chain"
+	logChainHeadID     = "head"
+)
+
+type logChainHead struct {
+	Seq       int64
+	Hash      string
+	UpdatedAt time.Time
+}
+
+// logChainContent is what ContentHash covers, as canonical JSON
+// Metadata is in the form pseudonymization leaves it in, the personal values are covered through PersonalHash
+type logChainContent struct {
+	ID           string                 `json:"id"`
+	TimeDetected string                 `json:"timeDetected"`
+	Type         string                 `json:"type"`
+	Category     string                 `json:"category"`
+	Severity     string                 `json:"severity"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	PersonalHash string                 `json:"personalHash,omitempty"`
+}
+
+// canonicalLog brings a log into the form it has after being read back from Firestore,
+// so the hash computed before writing matches the one computed when verifying
+// Metadata is stored as its JSON form (numbers as floats, times as strings, structs as maps)
+func canonicalLog(log *models.SystemLog) error {
+	log.TimeDetected = log.TimeDetected.UTC().Truncate(time.Microsecond)
+	if log.Metadata == nil {
+		return nil
+	}
+	data, err := json.Marshal(log.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode log metadata: %v", err)
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("failed to decode log metadata: %v", err)
+	}
+	log.Metadata = metadata
+	return nil
+}
+
+// sealLogContent sets the content hash of a new log, and the salt and hash of its personal values
+func sealLogContent(log *models.SystemLog) error {
+	if err := canonicalLog(log); err != nil {
+		return err
+	}
+	_, personal := splitPersonalMetadata(log.Metadata)
+	if len(personal) > 0 {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate log salt: %v", err)
+		}
+		log.PersonalSalt = hex.EncodeToString(salt)
+		personalHash, err := personalValuesHash(log.PersonalSalt, personal)
+		if err != nil {
+			return err
+		}
+		log.PersonalHash = personalHash
+	}
+
+	contentHash, err := logContentHash(log, log.PersonalHash)
+	if err != nil {
+		return err
+	}
+	log.ContentHash = contentHash
+	return nil
+}
+
+// verifyLogContent checks the content of a log against its ContentHash
+// erased reports that it only verifies as pseudonymized, which takes an erasure record for its volunteer
+func verifyLogContent(log *models.SystemLog) (ok, erased bool, err error) {
+	_, personal := splitPersonalMetadata(log.Metadata)
+	personalHash := ""
+	switch {
+	case log.PersonalSalt != "":
+		if personalHash, err = personalValuesHash(log.PersonalSalt, personal); err != nil {
+			return false, false, err
+		}
+	case log.PersonalHash != "":
+		// Pseudonymization removed the salt, so every personal value has to be erased
+		if len(personal) > 0 {
+			return false, false, nil
+		}
+		personalHash, erased = log.PersonalHash, true
+	}
+
+	contentHash, err := logContentHash(log, personalHash)
+	if err != nil {
+		return false, false, err
+	}
+	return contentHash == log.ContentHash, erased, nil
+}
+
+// splitPersonalMetadata returns the metadata as pseudonymization leaves it, without its erasedAt marker,
+// and the personal values it replaces keyed by their path
+func splitPersonalMetadata(metadata map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	personal := make(map[string]interface{})
+	redacted := erasedCopy(metadata, "", personal)
+	delete(redacted, sub_model.META_ERASED_AT)
+	if changes, ok := metadata[sub_model.META_CHANGES].(map[string]interface{}); ok {
+		redacted[sub_model.META_CHANGES] = erasedCopy(changes, sub_model.META_CHANGES+".", personal)
+	}
+	return redacted, personal
+}
+
+// erasedCopy copies metadata with the values erasePersonalValues replaces erased, collecting them in personal
+func erasedCopy(metadata map[string]interface{}, prefix string, personal map[string]interface{}) map[string]interface{} {
+	erased := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		erased[key] = value
+	}
+	for _, key := range models.PersonalMetadataKeys {
+		value, ok := metadata[key]
+		if !ok || value == nil || value == "" || value == sub_model.ERASED_VALUE {
+			continue
+		}
+		erased[key] = sub_model.ERASED_VALUE
+		personal[prefix+key] = value
+	}
+	return erased
+}
+
+// personalValuesHash hashes the personal values of a log with its salt, empty when there are none
+func personalValuesHash(salt string, personal map[string]interface{}) (string, error) {
+	if len(personal) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(personal)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode personal log values: %v", err)
+	}
+	sum := sha256.Sum256(append([]byte(salt+":"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// logContentHash hashes the content of a log in its erased form, map keys are sorted by encoding/json
+func logContentHash(log *models.SystemLog, personalHash string) (string, error) {
+	redacted, _ := splitPersonalMetadata(log.Metadata)
+	data, err := json.Marshal(logChainContent{
+		ID:           log.ID,
+		TimeDetected: log.TimeDetected.UTC().Format(time.RFC3339Nano),
+		Type:         string(log.Type),
+		Category:     log.Category,
+		Severity:     log.Severity,
+		Metadata:     redacted,
+		PersonalHash: personalHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode log content: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// logLinkHash links a log's content to the log before it
+func logLinkHash(prevHash string, seq int64, contentHash string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", prevHash, seq, contentHash)))
+	return hex.EncodeToString(sum[:])
+}
+
+const (
+	// logChainBatchSize is how many logs one transaction links, well under the 500 writes a transaction allows
+	logChainBatchSize = 200
+	// logChainSweepInterval is how often logs left pending, by another instance or a failed attempt, are looked for
+	logChainSweepInterval = 30 * time.Second
+	// logChainMaxBackoff caps the wait after failed attempts
+	logChainMaxBackoff = time.Minute
+)
+
+// logChainer links stored logs into the chain in the background, one transaction on the head per batch
+// so a burst of logs from any number of instances doesn't contend on the head once per log.
+// Logs are stored before they are linked, marked ChainPending, so an attempt that fails on contention
+// or an instance that stops never loses one, the next attempt or another instance's sweep links it
+type logChainer struct {
+	firestore *firestore.Client
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newLogChainer(client *firestore.Client) *logChainer {
+	lc := &logChainer{
+		firestore: client,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go lc.run()
+	return lc
+}
+
+// notify asks for the pending logs to be linked without waiting for the next sweep
+func (lc *logChainer) notify() {
+	select {
+	case lc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close links what is pending one last time and stops
+func (lc *logChainer) close() {
+	lc.closeOnce.Do(func() {
+		close(lc.stop)
+		<-lc.done
+	})
+}
+
+func (lc *logChainer) run() {
+	defer close(lc.done)
+	ticker := time.NewTicker(logChainSweepInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), logChainSweepInterval)
+		err := lc.linkPending(ctx)
+		cancel()
+
+		wait := (<-chan time.Time)(nil)
+		if err != nil {
+			failures++
+			backoff := min(time.Second<<min(failures, 6), logChainMaxBackoff)
+			slog.Warn("Failed to link logs into the chain, they stay pending and are retried", "attempts", failures, "retryIn", backoff.String(), "error", err)
+			wait = time.After(backoff)
+		} else {
+			failures = 0
+		}
+
+		if wait != nil {
+			// New logs wait for the backoff too, they are linked with the ones already pending
+			select {
+			case <-wait:
+				continue
+			case <-lc.stop:
+			}
+		} else {
+			select {
+			case <-lc.wake:
+				continue
+			case <-ticker.C:
+				continue
+			case <-lc.stop:
+			}
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		if err := lc.linkPending(ctx); err != nil {
+			slog.Warn("Failed to link logs into the chain before stopping, the next start links them", "error", err)
+		}
+		cancel()
+		return
+	}
+}
+
+// linkPending links pending logs, oldest first, until none are left
+func (lc *logChainer) linkPending(ctx context.Context) error {
+	for {
+		docs, err := lc.firestore.Collection(logsCollection).
+			Where("ChainPending", "==", true).
+			OrderBy("TimeDetected", firestore.Asc).
+			Limit(logChainBatchSize).
+			Documents(ctx).GetAll()
+		if err != nil {
+			return fmt.Errorf("failed to query pending logs: %v", err)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		refs := make([]*firestore.DocumentRef, len(docs))
+		for i, doc := range docs {
+			refs[i] = doc.Ref
+		}
+		if err := lc.linkBatch(ctx, refs); err != nil {
+			return err
+		}
+		if len(docs) < logChainBatchSize {
+			return nil
+		}
+	}
+}
+
+// linkBatch gives the logs consecutive sequence numbers after the head, in one transaction
+// Logs another instance linked meanwhile are no longer pending and left alone
+func (lc *logChainer) linkBatch(ctx context.Context, refs []*firestore.DocumentRef) error {
+	headRef := lc.firestore.Collection(logChainCollection).Doc(logChainHeadID)
+	err := lc.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var head logChainHead
+		headSnap, err := tx.Get(headRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := headSnap.DataTo(&head); err != nil {
+				return err
+			}
+		}
+
+		snaps, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+
+		linked := 0
+		for _, snap := range snaps {
+			if !snap.Exists() {
+				continue
+			}
+			var log models.SystemLog
+			if err := snap.DataTo(&log); err != nil {
+				return err
+			}
+			if !log.ChainPending {
+				continue
+			}
+
+			seq := head.Seq + 1
+			hash := logLinkHash(head.Hash, seq, log.ContentHash)
+			if err := tx.Update(snap.Ref, []firestore.Update{
+				{Path: "Seq", Value: seq},
+				{Path: "PrevHash", Value: head.Hash},
+				{Path: "Hash", Value: hash},
+				{Path: "ChainPending", Value: false},
+			}); err != nil {
+				return err
+			}
+			head.Seq, head.Hash = seq, hash
+			linked++
+		}
+		if linked == 0 {
+			return nil
+		}
+		return tx.Set(headRef, logChainHead{Seq: head.Seq, Hash: head.Hash, UpdatedAt: time.Now().UTC()})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to link logs: %v", err)
+	}
+	return nil
+}
+
+// VerifyLogChain walks the chain from fromSeq and reports the first broken link
+// A log whose personal values were erased only verifies if its volunteer has an erasure record,
+// any other change, a missing or extra sequence number or a head that doesn't match the last log is a break
+// Sequence numbers missing because they were purged are checked log by log in the bundle holding them,
+// read with loadBundle. Without it they link through the hash their bundle record holds and count as unverified
+func (r *logRepo) VerifyLogChain(ctx context.Context, fromSeq int64, loadBundle repository.LogBundleLoader) (*repository.LogChainReport, error) {
+	if fromSeq < 1 {
+		fromSeq = 1
+	}
+	report := &repository.LogChainReport{FromSeq: fromSeq}
+	addBreak := func(seq int64, logID, reason string) {
+		report.Breaks++
+		if report.FirstBreak == nil || seq < report.FirstBreak.Seq {
+			report.FirstBreak = &repository.LogChainBreak{Seq: seq, LogID: logID, Reason: reason}
+		}
+	}
+
+	var head logChainHead
+	headSnap, err := r.firestore.Collection(logChainCollection).Doc(logChainHeadID).Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, fmt.Errorf("failed to get log chain head: %v", err)
+	}
+	if err == nil {
+		if err := headSnap.DataTo(&head); err != nil {
+			return nil, fmt.Errorf("failed to parse log chain head: %v", err)
+		}
+	}
+	report.HeadSeq = head.Seq
+
+	// Purged logs are gone from the collection, their bundles record where the chain continues
+	bundles, err := (&logBundleRepo{firestore: r.firestore}).ListBundles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var bundled []bundledRange
+	for _, bundle := range bundles {
+		for _, seqRange := range bundle.SeqRanges {
+			bundled = append(bundled, bundledRange{bundle: bundle, LogSeqRange: seqRange})
+		}
+	}
+
+	expected := fromSeq
+	prevHash := ""
+	trustPrev := fromSeq > 1 // verification from the middle trusts the PrevHash of its first log
+	redacted := make(map[int64]*models.SystemLog)
+	erasedVolunteers := make(map[string]bool)
+
+	// checkLog checks a log against the one before it and its own content
+	checkLog := func(log *models.SystemLog) error {
+		if trustPrev {
+			prevHash = log.PrevHash
+			trustPrev = false
+		}
+
+		if log.PrevHash != prevHash {
+			addBreak(log.Seq, log.ID, "previous hash doesn't match the log before it")
+		} else if logLinkHash(log.PrevHash, log.Seq, log.ContentHash) != log.Hash {
+			addBreak(log.Seq, log.ID, "hash was modified")
+		} else {
+			ok, erased, err := verifyLogContent(log)
+			switch {
+			case err != nil:
+				return err
+			case !ok:
+				addBreak(log.Seq, log.ID, "content was modified")
+			case erased:
+				redacted[log.Seq] = log // checked against the erasure records once all are read
+			}
+		}
+
+		if log.Type == sub_model.VOLUNTEER_ERASED {
+			if volunteerID, ok := log.Metadata[sub_model.META_VOLUNTEER_ID].(string); ok {
+				erasedVolunteers[volunteerID] = true
+			}
+		}
+		prevHash = log.Hash
+		expected = log.Seq + 1
+		return nil
+	}
+
+	// The bundle read last, a gap is usually covered by one bundle
+	var loaded *models.LogBundle
+	var loadedLogs map[int64]*models.SystemLog
+	bundleLogs := func(bundle *models.LogBundle) (map[int64]*models.SystemLog, error) {
+		if loaded == bundle {
+			return loadedLogs, nil
+		}
+		logs, err := loadBundle(ctx, bundle)
+		if err != nil {
+			return nil, err
+		}
+		loaded, loadedLogs = bundle, make(map[int64]*models.SystemLog, len(logs))
+		for _, log := range logs {
+			if log.Seq > 0 {
+				loadedLogs[log.Seq] = log
+			}
+		}
+		return loadedLogs, nil
+	}
+
+	// linkBundled checks the purged logs from..to, false when no bundle records cover them
+	linkBundled := func(from, to int64) (bool, error) {
+		segments, ok := bundledGap(bundled, from, to)
+		if !ok {
+			return false, nil
+		}
+		report.Bundled += int(to - from + 1)
+
+		for _, segment := range segments {
+			if loadBundle == nil {
+				report.Unverified += int(segment.To - segment.From + 1)
+				prevHash = segment.LastHash
+				trustPrev = false
+				continue
+			}
+
+			logs, err := bundleLogs(segment.bundle)
+			if err != nil {
+				addBreak(segment.From, "", fmt.Sprintf("bundle %s can't be read: %v", segment.bundle.ID, err))
+				trustPrev = true
+				continue
+			}
+			for seq := segment.From; seq <= segment.To; seq++ {
+				log, ok := logs[seq]
+				if !ok {
+					addBreak(seq, "", fmt.Sprintf("bundle %s doesn't hold log %d", segment.bundle.ID, seq))
+					trustPrev = true
+					continue
+				}
+				if err := checkLog(log); err != nil {
+					return false, err
+				}
+			}
+			if !trustPrev && prevHash != segment.LastHash {
+				addBreak(segment.To, "", fmt.Sprintf("bundle %s record doesn't match the logs in it", segment.bundle.ID))
+			}
+		}
+		expected = to + 1
+		return true, nil
+	}
+
+	iter := r.firestore.Collection(logsCollection).
+		Where("Seq", ">=", fromSeq).
+		OrderBy("Seq", firestore.Asc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate logs: %v", err)
+		}
+		log, err := decodeLog(doc)
+		if err != nil {
+			return nil, err
+		}
+		log.ID = doc.Ref.ID
+		report.Checked++
+
+		switch {
+		case log.Seq > expected:
+			linked, err := linkBundled(expected, log.Seq-1)
+			if err != nil {
+				return nil, err
+			}
+			if !linked {
+				addBreak(expected, "", fmt.Sprintf("logs %d to %d are missing", expected, log.Seq-1))
+				trustPrev = true // the rest is still checked against each other
+			}
+		case log.Seq < expected:
+			addBreak(log.Seq, doc.Ref.ID, "sequence number is used more than once")
+			continue
+		}
+		if err := checkLog(log); err != nil {
+			return nil, err
+		}
+	}
+
+	for seq, log := range redacted {
+		volunteerID, _ := log.Metadata[sub_model.META_VOLUNTEER_ID].(string)
+		if !erasedVolunteers[volunteerID] {
+			addBreak(seq, log.ID, "personal values were erased without an erasure record")
+			continue
+		}
+		report.Redacted++
+	}
+
+	// Logs deleted from the end of the chain only show against the head
+	linked := report.Checked > 0
+	if expected <= head.Seq {
+		ok, err := linkBundled(expected, head.Seq)
+		if err != nil {
+			return nil, err
+		}
+		linked = linked || ok
+	}
+	switch last := expected - 1; {
+	case last < head.Seq:
+		addBreak(expected, "", fmt.Sprintf("logs %d to %d are missing", expected, head.Seq))
+	case report.Checked > 0 && last > head.Seq:
+		addBreak(head.Seq+1, "", "logs exist after the chain head")
+	case head.Seq > 0 && linked && !trustPrev && prevHash != head.Hash:
+		addBreak(head.Seq, "", "chain head doesn't match the last log")
+	}
+
+	total, err := countQuery(ctx, r.firestore.Collection(logsCollection).Query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count logs: %v", err)
+	}
+	chained, err := countQuery(ctx, r.firestore.Collection(logsCollection).Where("Seq", ">", 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chained logs: %v", err)
+	}
+	report.Pending, err = countQuery(ctx, r.firestore.Collection(logsCollection).Where("ChainPending", "==", true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending logs: %v", err)
+	}
+	report.Unchained = total - chained - report.Pending
+
+	report.Valid = report.Breaks == 0
+	return report, nil
+}
+
+// bundledRange is a run of purged sequence numbers and the bundle holding them
+type bundledRange struct {
+	bundle *models.LogBundle
+	models.LogSeqRange
+}
+
+// bundledGap reports whether the bundles cover every sequence number from..to,
+// and returns the ranges covering them in order, each starting where the one before it ended
+func bundledGap(ranges []bundledRange, from, to int64) ([]bundledRange, bool) {
+	var segments []bundledRange
+	for next := from; next <= to; {
+		var covering *bundledRange
+		for i := range ranges {
+			r := &ranges[i]
+			if r.From <= next && next <= r.To && r.To <= to && (covering == nil || r.To > covering.To) {
+				covering = r
+			}
+		}
+		if covering == nil {
+			return nil, false
+		}
+		segment := *covering
+		segment.From = next
+		segments = append(segments, segment)
+		next = covering.To + 1
+	}
+	return segments, from <= to
+}
@@ -8,18 +8,22 @@ import (
 
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
 )
 
 type logRepo struct {
 	firestore *firestore.Client
+	chain     *logChainer // links new logs into the chain, pending logs wait for the next sweep when nil
 }
 
 const logsCollection = "logs"
 
 // CreateLog adds a new system log to Firestore
+// It is stored right away and linked into the chain shortly after, see logChainer
 func (r *logRepo) CreateLog(ctx context.Context, log *models.SystemLog) error {
 	if log.ID == "" {
 		// Auto-generate ID if not provided
@@ -33,10 +37,16 @@ func (r *logRepo) CreateLog(ctx context.Context, log *models.SystemLog) error {
 
 	log.LastUpdated = time.Now().UTC()
 
-	_, err := r.firestore.Collection(logsCollection).Doc(log.ID).Set(ctx, log)
-	if err != nil {
+	if err := sealLogContent(log); err != nil {
+		return fmt.Errorf("failed to create log: %v", err)
+	}
+	log.ChainPending = true
+	if _, err := r.firestore.Collection(logsCollection).Doc(log.ID).Create(ctx, log); err != nil {
 		return fmt.Errorf("failed to create log: %v", err)
 	}
+	if r.chain != nil {
+		r.chain.notify()
+	}
 	return nil
 }
 
@@ -623,6 +633,133 @@ func (r *logRepo) ArchiveLogsOlderThan(ctx context.Context, beforeDate string) (
 	return archivedCount, nil
 }
 
+// ApplyRetentionRules archives the active logs each rule has kept for its retention period
+// Every rule queries the logs it matches from before its cutoff, leaving out the types or categories
+// more specific rules take over (see retentionExclusion). Logs still following a more specific rule
+// are skipped there and archived by the query of their own rule instead
+func (r *logRepo) ApplyRetentionRules(ctx context.Context, rules []*models.RetentionRule, now time.Time, dryRun bool) (*repository.RetentionReport, error) {
+	report := &repository.RetentionReport{
+		DryRun: dryRun,
+		RanAt:  now,
+		Rules:  []repository.RetentionRuleResult{},
+	}
+	archiveDate := time.Now().UTC()
+
+	for _, rule := range rules {
+		result := repository.RetentionRuleResult{
+			Rule:    rule,
+			Default: rule.ID == "",
+			Cutoff:  now.AddDate(0, 0, -rule.RetentionDays),
+		}
+
+		query := r.firestore.Collection(logsCollection).Where("IsArchived", "==", false)
+		if rule.Type != "" {
+			query = query.Where("Type", "==", rule.Type)
+		}
+		if rule.Category != "" {
+			query = query.Where("Category", "==", rule.Category)
+		}
+		if rule.Severity != "" {
+			query = query.Where("Severity", "==", rule.Severity)
+		}
+		if field, values := retentionExclusion(rules, rule); len(values) > 0 {
+			query = query.Where(field, "not-in", values)
+		}
+		query = query.Where("TimeDetected", "<", result.Cutoff)
+
+		iter := query.Documents(ctx)
+		batch := r.firestore.Batch()
+		pending := 0
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				iter.Stop()
+				return report, fmt.Errorf("failed to query logs for retention rule %s: %v", rule.MatchKey(), err)
+			}
+			log, err := decodeLog(doc)
+			if err != nil {
+				iter.Stop()
+				return report, err
+			}
+			if models.EffectiveRetentionRule(rules, log) != rule {
+				continue
+			}
+
+			result.Archived++
+			if result.Oldest == nil || log.TimeDetected.Before(*result.Oldest) {
+				result.Oldest = &log.TimeDetected
+			}
+			if dryRun {
+				continue
+			}
+
+			batch.Update(doc.Ref, []firestore.Update{
+				{Path: "IsArchived", Value: true},
+				{Path: "ArchiveDate", Value: archiveDate},
+				{Path: "LastUpdated", Value: archiveDate},
+			})
+			if pending++; pending == 500 {
+				if _, err := batch.Commit(ctx); err != nil {
+					iter.Stop()
+					return report, fmt.Errorf("failed to commit batch: %v", err)
+				}
+				batch = r.firestore.Batch()
+				pending = 0
+			}
+		}
+		iter.Stop()
+
+		if pending > 0 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return report, fmt.Errorf("failed to commit final batch: %v", err)
+			}
+		}
+		report.Rules = append(report.Rules, result)
+		report.TotalArchived += result.Archived
+	}
+
+	return report, nil
+}
+
+// maxNotInValues is the most values Firestore accepts in a not-in filter
+const maxNotInValues = 10
+
+// retentionExclusion returns the type or category values no log following rule can have, for a not-in filter
+// A more specific rule that matches every log of rule with a given type (or category) takes over all of them.
+// Firestore allows one not-in per query, so the field with more such values is used and the other logs
+// of more specific rules are still skipped after reading them
+func retentionExclusion(rules []*models.RetentionRule, rule *models.RetentionRule) (string, []interface{}) {
+	covers := func(other, value string) bool { return other == "" || other == value }
+
+	var types, categories []interface{}
+	seen := map[string]bool{}
+	for _, other := range rules {
+		if other.Specificity() <= rule.Specificity() || !covers(other.Severity, rule.Severity) {
+			continue
+		}
+		switch {
+		case rule.Type == "" && other.Type != "" && covers(other.Category, rule.Category) && !seen["type:"+string(other.Type)]:
+			seen["type:"+string(other.Type)] = true
+			types = append(types, string(other.Type))
+		case rule.Category == "" && other.Category != "" && covers(string(other.Type), string(rule.Type)) && !seen["category:"+other.Category]:
+			seen["category:"+other.Category] = true
+			categories = append(categories, other.Category)
+		}
+	}
+
+	field, values := "Type", types
+	if len(categories) > len(types) {
+		field, values = "Category", categories
+	}
+	if len(values) > maxNotInValues {
+		values = values[:maxNotInValues]
+	}
+	return field, values
+}
+
 // GetArchivedLogs retrieves logs that have been archived
 func (r *logRepo) GetArchivedLogs(ctx context.Context, limit int, offset int) ([]*models.SystemLog, int, error) {
 	if limit <= 0 {
@@ -666,131 +803,221 @@ func (r *logRepo) GetArchivedLogs(ctx context.Context, limit int, offset int) ([
 	return logs, totalCount, nil
 }
 
-// GetLogsWithEnhancedFilters retrieves logs with comprehensive filter support
-func (r *logRepo) GetLogsWithEnhancedFilters(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]*models.SystemLog, int, error) {
-	if limit <= 0 {
-		limit = 50
+// logSortField is the only order logs are listed in
+var logSortField = sortField{path: "TimeDetected", isTime: true}
+
+// filteredLogsQuery applies every filter of q to the logs collection
+// Each equality filter has a composite index with TimeDetected, Firestore merges them for combined filters
+func (r *logRepo) filteredLogsQuery(q repository.LogQuery) firestore.Query {
+	query := r.firestore.Collection(logsCollection).Query
+	equals := []struct {
+		path  string
+		value string
+	}{
+		{"Type", string(q.Type)},
+		{"Category", q.Category},
+		{"Severity", q.Severity},
+		{"Metadata." + sub_model.META_USER_ID, q.UserID},
+		{"Metadata." + sub_model.META_VOLUNTEER_ID, q.VolunteerID},
+		{"Metadata." + sub_model.META_EVENT_ID, q.EventID},
+		{"Metadata." + sub_model.META_DEPARTMENT_ID, q.DepartmentID},
+		{"Metadata." + sub_model.META_TARGET_USER_ID, q.TargetUserID},
+	}
+	for _, filter := range equals {
+		if filter.value != "" {
+			query = query.Where(filter.path, "==", filter.value)
+		}
+	}
+	if !q.IncludeArchived {
+		query = query.Where("IsArchived", "==", false)
+	}
+	if q.From != nil {
+		query = query.Where(logSortField.path, ">=", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where(logSortField.path, "<=", *q.To)
+	}
+	return query
+}
+
+// QueryLogs returns a page of logs matching the query, newest first
+func (r *logRepo) QueryLogs(ctx context.Context, q repository.LogQuery) (repository.Page[*models.SystemLog], error) {
+	q.SortBy = repository.SortByTimeDetected
+	q.Desc = true
+
+	query := r.filteredLogsQuery(q)
+	if q.Cursor == "" && q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+	if q.Limit > 0 {
+		// One extra to know if there is a next page
+		query = query.Limit(q.Limit + 1)
 	}
 
-	// Start with base query - just order by TimeDetected to avoid index requirements
-	query := r.firestore.Collection(logsCollection).OrderBy("TimeDetected", firestore.Desc)
+	return queryPage(ctx, query, logSortField, q.ListOptions, decodeLog, func(*models.SystemLog) bool { return true })
+}
 
-	// We'll apply most filters in memory to avoid Firestore composite index requirements
-	// Only apply simple filters that don't require indexes when combined with OrderBy
+// CountLogs counts the logs matching the query with an aggregation, paging options are ignored
+func (r *logRepo) CountLogs(ctx context.Context, q repository.LogQuery) (int, error) {
+	total, err := countQuery(ctx, r.filteredLogsQuery(q))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count logs: %v", err)
+	}
+	return total, nil
+}
 
-	// Execute query with pagination
-	query = query.Offset(offset).Limit(limit * 2) // Fetch more to account for filtering
+// countQuery counts the documents matching query with an aggregation
+func countQuery(ctx context.Context, query firestore.Query) (int, error) {
+	result, err := query.NewAggregationQuery().WithCount("total").Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total, ok := result["total"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result %v", result["total"])
+	}
+	return int(total.GetIntegerValue()), nil
+}
 
-	iter := query.Documents(ctx)
-	defer iter.Stop()
+// decodeLog converts a log document
+func decodeLog(doc *firestore.DocumentSnapshot) (*models.SystemLog, error) {
+	var log models.SystemLog
+	if err := doc.DataTo(&log); err != nil {
+		return nil, fmt.Errorf("failed to convert log data: %v", err)
+	}
+	return &log, nil
+}
 
-	var allLogs []*models.SystemLog
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to iterate logs: %v", err)
-		}
+// PseudonymizeVolunteerLogs replaces the personal values in logs about a volunteer, see SystemLog.Pseudonymize
+func (r *logRepo) PseudonymizeVolunteerLogs(ctx context.Context, volunteerID string) (int, error) {
+	docs, err := r.firestore.Collection(logsCollection).
+		Where("Metadata.volunteerId", "==", volunteerID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query logs: %v", err)
+	}
+
+	erasedAt := time.Now().UTC()
+	pseudonymizedCount := 0
 
+	batch := r.firestore.Batch()
+	pending := 0
+	for _, doc := range docs {
 		var log models.SystemLog
 		if err := doc.DataTo(&log); err != nil {
-			return nil, 0, fmt.Errorf("failed to convert log data: %v", err)
+			return pseudonymizedCount, fmt.Errorf("failed to convert log data: %v", err)
+		}
+		if !log.Pseudonymize(erasedAt) {
+			continue
 		}
-		allLogs = append(allLogs, &log)
-	}
 
-	// Apply all filters in memory
-	includeArchived, _ := filters["includeArchived"].(bool)
-	logTypeFilter, _ := filters["type"].(string)
-	categoryFilter, _ := filters["category"].(string)
-	severityFilter, _ := filters["severity"].(string)
-	userIDFilter, _ := filters["userId"].(string)
-	volunteerIDFilter, _ := filters["volunteerId"].(string)
-	eventIDFilter, _ := filters["eventId"].(string)
-	departmentIDFilter, _ := filters["departmentId"].(string)
+		batch.Update(doc.Ref, []firestore.Update{
+			{Path: "Metadata", Value: log.Metadata},
+			{Path: "PersonalSalt", Value: firestore.Delete},
+			{Path: "LastUpdated", Value: erasedAt},
+		})
+		pending++
+		pseudonymizedCount++
 
-	var startDate, endDate time.Time
-	if startDateStr, ok := filters["startDate"].(string); ok && startDateStr != "" {
-		parsed, err := time.Parse(time.RFC3339, startDateStr)
-		if err == nil {
-			startDate = parsed
+		if pending == 500 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return pseudonymizedCount - pending, fmt.Errorf("failed to commit batch: %v", err)
+			}
+			batch = r.firestore.Batch()
+			pending = 0
 		}
 	}
-	if endDateStr, ok := filters["endDate"].(string); ok && endDateStr != "" {
-		parsed, err := time.Parse(time.RFC3339, endDateStr)
-		if err == nil {
-			endDate = parsed
+
+	if pending > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return pseudonymizedCount - pending, fmt.Errorf("failed to commit final batch: %v", err)
 		}
 	}
 
-	var filtered []*models.SystemLog
-	for _, log := range allLogs {
-		// Check archived filter
-		if !includeArchived && log.IsArchived {
-			continue
-		}
+	return pseudonymizedCount, nil
+}
 
-		// Check type filter
-		if logTypeFilter != "" && string(log.Type) != logTypeFilter {
-			continue
-		}
+// ListLogsArchivedBefore retrieves up to limit logs archived before the given time, oldest archive first
+// Only archived logs have an ArchiveDate, so the range filter alone selects them
+func (r *logRepo) ListLogsArchivedBefore(ctx context.Context, before time.Time, limit int) ([]*models.SystemLog, error) {
+	query := r.firestore.Collection(logsCollection).
+		Where("ArchiveDate", "<", before).
+		OrderBy("ArchiveDate", firestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
 
-		// Check category filter
-		if categoryFilter != "" && log.Category != categoryFilter {
-			continue
-		}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived logs: %v", err)
+	}
 
-		// Check severity filter
-		if severityFilter != "" && log.Severity != severityFilter {
-			continue
+	logs := make([]*models.SystemLog, 0, len(docs))
+	for _, doc := range docs {
+		log, err := decodeLog(doc)
+		if err != nil {
+			return nil, err
 		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
 
-		// Check date range
-		if !startDate.IsZero() && log.TimeDetected.Before(startDate) {
-			continue
+// DeleteLogs permanently deletes logs in batches
+func (r *logRepo) DeleteLogs(ctx context.Context, ids []string) (int, error) {
+	deletedCount := 0
+	for start := 0; start < len(ids); start += 500 {
+		end := min(start+500, len(ids))
+		batch := r.firestore.Batch()
+		for _, id := range ids[start:end] {
+			batch.Delete(r.firestore.Collection(logsCollection).Doc(id))
 		}
-		if !endDate.IsZero() && log.TimeDetected.After(endDate) {
-			continue
+		if _, err := batch.Commit(ctx); err != nil {
+			return deletedCount, fmt.Errorf("failed to delete logs: %v", err)
 		}
+		deletedCount += end - start
+	}
+	return deletedCount, nil
+}
 
-		// Check metadata filters
-		if userIDFilter != "" {
-			if val, ok := log.Metadata["userId"].(string); !ok || val != userIDFilter {
-				continue
-			}
+// RestoreLogs writes logs back under their own IDs, writing one twice just overwrites it
+func (r *logRepo) RestoreLogs(ctx context.Context, logs []*models.SystemLog) (int, error) {
+	restoredCount := 0
+	for start := 0; start < len(logs); start += 500 {
+		end := min(start+500, len(logs))
+		batch := r.firestore.Batch()
+		for _, log := range logs[start:end] {
+			batch.Set(r.firestore.Collection(logsCollection).Doc(log.ID), log)
 		}
-
-		if volunteerIDFilter != "" {
-			if val, ok := log.Metadata["volunteerId"].(string); !ok || val != volunteerIDFilter {
-				continue
-			}
+		if _, err := batch.Commit(ctx); err != nil {
+			return restoredCount, fmt.Errorf("failed to restore logs: %v", err)
 		}
+		restoredCount += end - start
+	}
+	return restoredCount, nil
+}
 
-		if eventIDFilter != "" {
-			if val, ok := log.Metadata["eventId"].(string); !ok || val != eventIDFilter {
-				continue
-			}
+// ErasedVolunteers reports which of the volunteers have an erasure tombstone, looked up 30 at a time
+func (r *logRepo) ErasedVolunteers(ctx context.Context, volunteerIDs []string) (map[string]bool, error) {
+	erased := make(map[string]bool)
+	for start := 0; start < len(volunteerIDs); start += 30 {
+		end := min(start+30, len(volunteerIDs))
+		docs, err := r.firestore.Collection(logsCollection).
+			Where("Type", "==", string(sub_model.VOLUNTEER_ERASED)).
+			Where("Metadata.volunteerId", "in", volunteerIDs[start:end]).
+			Documents(ctx).GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query erasure records: %v", err)
 		}
-
-		if departmentIDFilter != "" {
-			if val, ok := log.Metadata["departmentId"].(string); !ok || val != departmentIDFilter {
-				continue
+		for _, doc := range docs {
+			log, err := decodeLog(doc)
+			if err != nil {
+				return nil, err
+			}
+			if volunteerID, ok := log.Metadata[sub_model.META_VOLUNTEER_ID].(string); ok {
+				erased[volunteerID] = true
 			}
 		}
-
-		filtered = append(filtered, log)
 	}
-
-	// Get total count by fetching all and filtering
-	// For performance, we'll use the filtered count as approximate
-	totalCount := len(filtered)
-
-	// Apply limit
-	if len(filtered) > limit {
-		filtered = filtered[:limit]
-	}
-
-	return filtered, totalCount, nil
+	return erased, nil
 }
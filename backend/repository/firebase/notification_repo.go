@@ -0,0 +1,189 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sheduling-server/models"
+	"sheduling-server/repository"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type notificationRepo struct {
+	firestore *firestore.Client
+}
+
+const notificationsCollection = "notifications"
+
+// notificationSortField orders the delivery log
+var notificationSortField = sortField{path: "CreatedAt", isTime: true}
+
+// CreateNotifications queues notifications in batches of 500
+func (r *notificationRepo) CreateNotifications(ctx context.Context, notifications []*models.Notification) error {
+	batch := r.firestore.Batch()
+	for i, notification := range notifications {
+		docRef := r.firestore.Collection(notificationsCollection).NewDoc()
+		if notification.ID != "" {
+			docRef = r.firestore.Collection(notificationsCollection).Doc(notification.ID)
+		}
+		notification.ID = docRef.ID
+		batch.Set(docRef, notification)
+
+		if (i+1)%500 == 0 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return fmt.Errorf("failed to queue notifications: %v", err)
+			}
+			batch = r.firestore.Batch()
+		}
+	}
+
+	if len(notifications)%500 != 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to queue notifications: %v", err)
+		}
+	}
+	return nil
+}
+
+// CreateNotificationOnce creates the document with the notification's ID, which fails if it exists
+func (r *notificationRepo) CreateNotificationOnce(ctx context.Context, notification *models.Notification) (bool, error) {
+	_, err := r.firestore.Collection(notificationsCollection).Doc(notification.ID).Create(ctx, notification)
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to queue notification: %v", err)
+	}
+	return true, nil
+}
+
+// ClaimDueNotifications claims each due notification in its own transaction,
+// one another instance claimed first is no longer due and left out
+func (r *notificationRepo) ClaimDueNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.Notification, error) {
+	docs, err := r.firestore.Collection(notificationsCollection).
+		Where("Status", "==", string(models.NotificationPending)).
+		Where("NextAttemptAt", "<=", now).
+		OrderBy("NextAttemptAt", firestore.Asc).
+		Limit(limit).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due notifications: %v", err)
+	}
+
+	claimed := []*models.Notification{}
+	for _, doc := range docs {
+		var notification models.Notification
+		err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			docSnap, err := tx.Get(doc.Ref)
+			if err != nil {
+				return err
+			}
+			notification = models.Notification{}
+			if err := docSnap.DataTo(&notification); err != nil {
+				return err
+			}
+			if notification.Status != models.NotificationPending || notification.NextAttemptAt.After(now) {
+				notification.ID = ""
+				return nil
+			}
+
+			notification.ID = doc.Ref.ID
+			notification.Attempts++
+			notification.NextAttemptAt = now.Add(lease)
+			return tx.Update(doc.Ref, []firestore.Update{
+				{Path: "Attempts", Value: notification.Attempts},
+				{Path: "NextAttemptAt", Value: notification.NextAttemptAt},
+			})
+		})
+		if err != nil {
+			return claimed, fmt.Errorf("failed to claim notification: %v", err)
+		}
+		if notification.ID != "" {
+			claimed = append(claimed, &notification)
+		}
+	}
+	return claimed, nil
+}
+
+// UpdateNotification saves the outcome fields of the notification
+// One deleted meanwhile, by erasing its volunteer, is left deleted
+func (r *notificationRepo) UpdateNotification(ctx context.Context, notification *models.Notification) error {
+	_, err := r.firestore.Collection(notificationsCollection).Doc(notification.ID).Update(ctx, []firestore.Update{
+		{Path: "Status", Value: string(notification.Status)},
+		{Path: "Attempts", Value: notification.Attempts},
+		{Path: "NextAttemptAt", Value: notification.NextAttemptAt},
+		{Path: "LastError", Value: notification.LastError},
+		{Path: "SentAt", Value: notification.SentAt},
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update notification: %v", err)
+	}
+	return nil
+}
+
+// DeleteVolunteerNotifications deletes every notification queued for a volunteer in batches of 500
+func (r *notificationRepo) DeleteVolunteerNotifications(ctx context.Context, volunteerID string) (int, error) {
+	docs, err := r.firestore.Collection(notificationsCollection).
+		Where("VolunteerID", "==", volunteerID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query notifications: %v", err)
+	}
+
+	deleted := 0
+	batch := r.firestore.Batch()
+	for i, doc := range docs {
+		batch.Delete(doc.Ref)
+		if (i+1)%500 == 0 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return deleted, fmt.Errorf("failed to delete notifications: %v", err)
+			}
+			batch = r.firestore.Batch()
+			deleted = i + 1
+		}
+	}
+	if len(docs)%500 != 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return deleted, fmt.Errorf("failed to delete notifications: %v", err)
+		}
+	}
+	return len(docs), nil
+}
+
+// QueryNotifications returns a page of the delivery log, newest first
+func (r *notificationRepo) QueryNotifications(ctx context.Context, q repository.NotificationQuery) (repository.Page[*models.Notification], error) {
+	q.SortBy = repository.SortByCreatedAt
+	q.Desc = true
+
+	// The first filter runs in Firestore, the others while streaming
+	query := r.firestore.Collection(notificationsCollection).Query
+	switch {
+	case q.VolunteerID != "":
+		query = query.Where("VolunteerID", "==", q.VolunteerID)
+	case q.EventID != "":
+		query = query.Where("EventID", "==", q.EventID)
+	case q.Status != "":
+		query = query.Where("Status", "==", string(q.Status))
+	}
+
+	return queryPage(ctx, query, notificationSortField, q.ListOptions, decodeNotification, func(n *models.Notification) bool {
+		return (q.EventID == "" || n.EventID == q.EventID) && (q.Status == "" || n.Status == q.Status)
+	})
+}
+
+// decodeNotification converts a notification document
+func decodeNotification(doc *firestore.DocumentSnapshot) (*models.Notification, error) {
+	var notification models.Notification
+	if err := doc.DataTo(&notification); err != nil {
+		return nil, fmt.Errorf("failed to convert notification data: %v", err)
+	}
+	notification.ID = doc.Ref.ID
+	return &notification, nil
+}
@@ -0,0 +1,88 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sheduling-server/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type oauthStateRepo struct {
+	firestore *firestore.Client
+}
+
+const oauthStatesCollection = "oauth_states"
+
+// CreateState stores a pending OAuth login attempt
+func (r *oauthStateRepo) CreateState(ctx context.Context, state *models.OAuthState) error {
+	_, err := r.firestore.Collection(oauthStatesCollection).Doc(state.ID).Create(ctx, state)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth state: %v", err)
+	}
+	return nil
+}
+
+// ConsumeState reads and deletes an OAuth state in a transaction so it cannot be replayed
+func (r *oauthStateRepo) ConsumeState(ctx context.Context, id string) (*models.OAuthState, error) {
+	var state models.OAuthState
+	docRef := r.firestore.Collection(oauthStatesCollection).Doc(id)
+
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		if err := docSnap.DataTo(&state); err != nil {
+			return err
+		}
+		return tx.Delete(docRef)
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("oauth state not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume oauth state: %v", err)
+	}
+
+	state.ID = id
+	return &state, nil
+}
+
+// DeleteExpiredStates removes OAuth states that expired before the given time
+func (r *oauthStateRepo) DeleteExpiredStates(ctx context.Context, before time.Time) (int, error) {
+	docs, err := r.firestore.Collection(oauthStatesCollection).
+		Where("ExpiresAt", "<", before).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired oauth states: %v", err)
+	}
+
+	deletedCount := 0
+	batch := r.firestore.Batch()
+	for i, doc := range docs {
+		batch.Delete(doc.Ref)
+
+		if (i+1)%500 == 0 {
+			_, err := batch.Commit(ctx)
+			if err != nil {
+				return deletedCount, fmt.Errorf("failed to commit batch: %v", err)
+			}
+			batch = r.firestore.Batch()
+		}
+		deletedCount++
+	}
+
+	if deletedCount%500 != 0 {
+		_, err := batch.Commit(ctx)
+		if err != nil {
+			return deletedCount, fmt.Errorf("failed to commit final batch: %v", err)
+		}
+	}
+
+	return deletedCount, nil
+}
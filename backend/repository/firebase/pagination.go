@@ -0,0 +1,165 @@
+package firebase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// sortField is the stored field a list is ordered by
+type sortField struct {
+	path   string
+	isTime bool
+}
+
+// pageCursor is the position after the last item of a page, encoded as an opaque string
+type pageCursor struct {
+	SortBy string    `json:"s"`
+	Desc   bool      `json:"d,omitempty"`
+	Text   string    `json:"n,omitempty"`
+	Time   time.Time `json:"t,omitempty"`
+	ID     string    `json:"id"`
+}
+
+// encodeCursor makes the cursor for the page after doc
+func encodeCursor(doc *firestore.DocumentSnapshot, field sortField, opts repository.ListOptions) (string, error) {
+	cursor := pageCursor{SortBy: opts.SortBy, Desc: opts.Desc, ID: doc.Ref.ID}
+	value, err := doc.DataAt(field.path)
+	if err != nil {
+		return "", err
+	}
+	if field.isTime {
+		cursor.Time, _ = value.(time.Time)
+	} else {
+		cursor.Text, _ = value.(string)
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor reads a cursor, it has to be for the same sort as the query
+func decodeCursor(value string, opts repository.ListOptions) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, repository.ErrInvalidCursor
+	}
+	if cursor.SortBy != opts.SortBy || cursor.Desc != opts.Desc {
+		return nil, repository.ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// queryPage runs query ordered by field from the cursor and keeps the documents that match until the page is full
+// Filters Firestore can't run without extra indexes (name search, arrays, ...) are applied by keep while streaming,
+// so only the documents up to the end of the page are read
+func queryPage[T any](ctx context.Context, query firestore.Query, field sortField, opts repository.ListOptions, decode func(*firestore.DocumentSnapshot) (T, error), keep func(T) bool) (repository.Page[T], error) {
+	page := repository.Page[T]{Items: []T{}}
+
+	direction := firestore.Asc
+	if opts.Desc {
+		direction = firestore.Desc
+	}
+	// The document ID breaks ties, so items with the same value are never skipped or repeated
+	query = query.OrderBy(field.path, direction).OrderBy(firestore.DocumentID, direction)
+
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor, opts)
+		if err != nil {
+			return page, err
+		}
+		if field.isTime {
+			query = query.StartAfter(cursor.Time, cursor.ID)
+		} else {
+			query = query.StartAfter(cursor.Text, cursor.ID)
+		}
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var last *firestore.DocumentSnapshot
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return page, fmt.Errorf("failed to iterate documents: %v", err)
+		}
+
+		item, err := decode(doc)
+		if err != nil {
+			return page, err
+		}
+		if !keep(item) {
+			continue
+		}
+
+		if opts.Limit > 0 && len(page.Items) == opts.Limit {
+			// One more match exists, so there is a next page
+			next, err := encodeCursor(last, field, opts)
+			if err != nil {
+				return page, fmt.Errorf("failed to encode cursor: %v", err)
+			}
+			page.NextCursor = next
+			break
+		}
+		page.Items = append(page.Items, item)
+		last = doc
+	}
+
+	return page, nil
+}
+
+// nameFilter matches names against a search term and a prefix, both folded once
+type nameFilter struct {
+	search string
+	prefix string
+}
+
+func newNameFilter(search, prefix string) nameFilter {
+	return nameFilter{search: utils.FoldName(search), prefix: utils.FoldName(prefix)}
+}
+
+// matches checks a name, an empty filter matches everything
+func (f nameFilter) matches(name string) bool {
+	if f.search == "" && f.prefix == "" {
+		return true
+	}
+	folded := utils.FoldName(name)
+	if f.search != "" && !strings.Contains(folded, f.search) {
+		return false
+	}
+	if f.prefix != "" && !utils.NameHasPrefix(folded, f.prefix) {
+		return false
+	}
+	return true
+}
+
+// timeInRange checks after <= t < before, nil bounds are open
+func timeInRange(t time.Time, after, before *time.Time) bool {
+	if after != nil && t.Before(*after) {
+		return false
+	}
+	if before != nil && !t.Before(*before) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sheduling-server/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type reminderJobRepo struct {
+	firestore *firestore.Client
+}
+
+const reminderJobsCollection = "reminder_jobs"
+
+// ClaimReminderJob creates the job or takes over one whose lease passed, in a transaction so only one instance wins
+func (r *reminderJobRepo) ClaimReminderJob(ctx context.Context, job *models.ReminderJob, now time.Time, lease time.Duration) (bool, error) {
+	docRef := r.firestore.Collection(reminderJobsCollection).Doc(job.ID)
+
+	claimed := false
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		docSnap, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			job.Status = models.ReminderJobRunning
+			job.LeaseUntil = now.Add(lease)
+			job.CreatedAt = now
+			claimed = true
+			return tx.Create(docRef, job)
+		}
+		if err != nil {
+			return err
+		}
+
+		var stored models.ReminderJob
+		if err := docSnap.DataTo(&stored); err != nil {
+			return err
+		}
+		if stored.Status == models.ReminderJobDone || stored.LeaseUntil.After(now) {
+			return nil
+		}
+
+		claimed = true
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "LeaseUntil", Value: now.Add(lease)},
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder job: %v", err)
+	}
+	return claimed, nil
+}
+
+// CompleteReminderJob marks a job done with the number of notifications it queued
+func (r *reminderJobRepo) CompleteReminderJob(ctx context.Context, id string, queued int) error {
+	_, err := r.firestore.Collection(reminderJobsCollection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "Status", Value: string(models.ReminderJobDone)},
+		{Path: "Queued", Value: queued},
+		{Path: "CompletedAt", Value: time.Now().UTC()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete reminder job: %v", err)
+	}
+	return nil
+}
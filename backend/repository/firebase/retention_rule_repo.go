@@ -0,0 +1,100 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+
+	"sheduling-server/models"
+	"sheduling-server/repository"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type retentionRuleRepo struct {
+	firestore *firestore.Client
+}
+
+const retentionRulesCollection = "retention_rules"
+
+// CreateRule stores a rule with its match key as the ID, so two rules can't match the same logs
+func (r *retentionRuleRepo) CreateRule(ctx context.Context, rule *models.RetentionRule) error {
+	rule.ID = rule.MatchKey()
+
+	_, err := r.firestore.Collection(retentionRulesCollection).Doc(rule.ID).Create(ctx, rule)
+	if status.Code(err) == codes.AlreadyExists {
+		return repository.ErrRetentionRuleExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create retention rule: %v", err)
+	}
+	return nil
+}
+
+// GetRule retrieves a rule by its ID
+func (r *retentionRuleRepo) GetRule(ctx context.Context, id string) (*models.RetentionRule, error) {
+	docSnap, err := r.firestore.Collection(retentionRulesCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention rule: %v", err)
+	}
+
+	var rule models.RetentionRule
+	if err := docSnap.DataTo(&rule); err != nil {
+		return nil, fmt.Errorf("failed to parse retention rule data: %v", err)
+	}
+
+	rule.ID = docSnap.Ref.ID
+	return &rule, nil
+}
+
+// ListRules retrieves all rules
+func (r *retentionRuleRepo) ListRules(ctx context.Context) ([]*models.RetentionRule, error) {
+	iter := r.firestore.Collection(retentionRulesCollection).Documents(ctx)
+	defer iter.Stop()
+
+	rules := []*models.RetentionRule{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate retention rules: %v", err)
+		}
+
+		var rule models.RetentionRule
+		if err := doc.DataTo(&rule); err != nil {
+			return nil, fmt.Errorf("failed to parse retention rule data: %v", err)
+		}
+
+		rule.ID = doc.Ref.ID
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// UpdateRule updates the retention and description, what a rule matches is fixed by its ID
+func (r *retentionRuleRepo) UpdateRule(ctx context.Context, rule *models.RetentionRule) error {
+	_, err := r.firestore.Collection(retentionRulesCollection).Doc(rule.ID).Update(ctx, []firestore.Update{
+		{Path: "RetentionDays", Value: rule.RetentionDays},
+		{Path: "Description", Value: rule.Description},
+		{Path: "LastUpdated", Value: rule.LastUpdated},
+		{Path: "UpdatedBy", Value: rule.UpdatedBy},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update retention rule: %v", err)
+	}
+	return nil
+}
+
+// DeleteRule deletes a rule
+func (r *retentionRuleRepo) DeleteRule(ctx context.Context, id string) error {
+	_, err := r.firestore.Collection(retentionRulesCollection).Doc(id).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention rule: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sheduling-server/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+type serviceAccountRepo struct {
+	firestore *firestore.Client
+}
+
+const (
+	serviceAccountsCollection = "service_accounts"
+	apiKeysCollection         = "api_keys"
+)
+
+// CreateServiceAccount adds a new service account to Firestore
+func (r *serviceAccountRepo) CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	if account.ID == "" {
+		docRef := r.firestore.Collection(serviceAccountsCollection).NewDoc()
+		account.ID = docRef.ID
+	}
+
+	_, err := r.firestore.Collection(serviceAccountsCollection).Doc(account.ID).Set(ctx, account)
+	if err != nil {
+		return fmt.Errorf("failed to create service account: %v", err)
+	}
+	return nil
+}
+
+// GetServiceAccountByID retrieves a service account by its ID
+func (r *serviceAccountRepo) GetServiceAccountByID(ctx context.Context, id string) (*models.ServiceAccount, error) {
+	docSnap, err := r.firestore.Collection(serviceAccountsCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service account: %v", err)
+	}
+
+	var account models.ServiceAccount
+	if err := docSnap.DataTo(&account); err != nil {
+		return nil, fmt.Errorf("failed to parse service account data: %v", err)
+	}
+
+	account.ID = docSnap.Ref.ID
+	return &account, nil
+}
+
+// ListServiceAccounts retrieves all service accounts
+func (r *serviceAccountRepo) ListServiceAccounts(ctx context.Context) ([]*models.ServiceAccount, error) {
+	iter := r.firestore.Collection(serviceAccountsCollection).Documents(ctx)
+	defer iter.Stop()
+
+	accounts := []*models.ServiceAccount{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate service accounts: %v", err)
+		}
+
+		var account models.ServiceAccount
+		if err := doc.DataTo(&account); err != nil {
+			return nil, fmt.Errorf("failed to parse service account data: %v", err)
+		}
+
+		account.ID = doc.Ref.ID
+		accounts = append(accounts, &account)
+	}
+
+	return accounts, nil
+}
+
+// UpdateServiceAccount updates an existing service account
+func (r *serviceAccountRepo) UpdateServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	_, err := r.firestore.Collection(serviceAccountsCollection).Doc(account.ID).Set(ctx, account)
+	if err != nil {
+		return fmt.Errorf("failed to update service account: %v", err)
+	}
+	return nil
+}
+
+// CreateAPIKey adds a new API key to Firestore
+func (r *serviceAccountRepo) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	if key.KeyHash == "" {
+		return fmt.Errorf("failed to create api key: key hash is required")
+	}
+	if key.ID == "" {
+		docRef := r.firestore.Collection(apiKeysCollection).NewDoc()
+		key.ID = docRef.ID
+	}
+
+	_, err := r.firestore.Collection(apiKeysCollection).Doc(key.ID).Set(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %v", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of the full key
+func (r *serviceAccountRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	iter := r.firestore.Collection(apiKeysCollection).
+		Where("KeyHash", "==", keyHash).
+		Limit(1).
+		Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %v", err)
+	}
+
+	var key models.APIKey
+	if err := doc.DataTo(&key); err != nil {
+		return nil, fmt.Errorf("failed to parse api key data: %v", err)
+	}
+
+	key.ID = doc.Ref.ID
+	return &key, nil
+}
+
+// GetAPIKeyByID retrieves an API key by its ID
+func (r *serviceAccountRepo) GetAPIKeyByID(ctx context.Context, id string) (*models.APIKey, error) {
+	docSnap, err := r.firestore.Collection(apiKeysCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %v", err)
+	}
+
+	var key models.APIKey
+	if err := docSnap.DataTo(&key); err != nil {
+		return nil, fmt.Errorf("failed to parse api key data: %v", err)
+	}
+
+	key.ID = docSnap.Ref.ID
+	return &key, nil
+}
+
+// ListAPIKeys retrieves all keys belonging to a service account
+func (r *serviceAccountRepo) ListAPIKeys(ctx context.Context, serviceAccountID string) ([]*models.APIKey, error) {
+	iter := r.firestore.Collection(apiKeysCollection).
+		Where("ServiceAccountID", "==", serviceAccountID).
+		Documents(ctx)
+	defer iter.Stop()
+
+	keys := []*models.APIKey{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate api keys: %v", err)
+		}
+
+		var key models.APIKey
+		if err := doc.DataTo(&key); err != nil {
+			return nil, fmt.Errorf("failed to parse api key data: %v", err)
+		}
+
+		key.ID = doc.Ref.ID
+		keys = append(keys, &key)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks an API key as revoked
+func (r *serviceAccountRepo) RevokeAPIKey(ctx context.Context, id string, revokedBy string) error {
+	_, err := r.firestore.Collection(apiKeysCollection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "RevokedBy", Value: revokedBy},
+		{Path: "RevokedAt", Value: time.Now().UTC()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %v", err)
+	}
+	return nil
+}
+
+// TouchAPIKey updates the last used timestamp of an API key
+func (r *serviceAccountRepo) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.firestore.Collection(apiKeysCollection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "LastUsedAt", Value: usedAt},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"sheduling-server/models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
+
+	"cloud.google.com/go/firestore"
+)
+
+// maxBatchWrites is the Firestore limit of writes in one batch
+const maxBatchWrites = 500
+
+type unitOfWork struct {
+	firestore *firestore.Client
+	writes    []pendingWrite
+	committed []pendingWrite
+}
+
+type pendingWrite struct {
+	collection string
+	id         string
+	data       interface{}
+	updates    []firestore.Update // updates an existing document instead of creating one, never rolled back
+}
+
+// CreateVolunteer queues a volunteer to be created
+func (u *unitOfWork) CreateVolunteer(volunteer *models.VolunteerModel) {
+	if volunteer.ID == "" {
+		volunteer.ID = u.firestore.Collection(volunteersCollection).NewDoc().ID
+	}
+	u.writes = append(u.writes, pendingWrite{collection: volunteersCollection, id: volunteer.ID, data: volunteer})
+
+	// Create fails on a reservation that exists, so a taken student ID fails the batch
+	if key := utils.NormalizeStudentID(volunteer.StudentID); key != "" {
+		u.writes = append(u.writes, pendingWrite{collection: studentIDsCollection, id: key, data: studentIDReservation{
+			VolunteerID: volunteer.ID,
+			StudentID:   volunteer.StudentID,
+			CreatedAt:   time.Now().UTC(),
+		}})
+	}
+}
+
+// CreateDepartment queues a department to be created
+func (u *unitOfWork) CreateDepartment(dept *models.DepartmentModel) {
+	if dept.ID == "" {
+		dept.ID = u.firestore.Collection(departmentsCollection).NewDoc().ID
+	}
+	u.writes = append(u.writes, pendingWrite{collection: departmentsCollection, id: dept.ID, data: dept})
+}
+
+// CreateEvent queues an event to be created
+func (u *unitOfWork) CreateEvent(event *models.EventSchedule) {
+	if event.ID == "" {
+		event.ID = u.firestore.Collection(eventsCollection).NewDoc().ID
+	}
+	u.writes = append(u.writes, pendingWrite{collection: eventsCollection, id: event.ID, data: event})
+}
+
+// CompleteImportSession queues the session's move to completed, it is queued last so it lands in the last batch
+func (u *unitOfWork) CompleteImportSession(sessionID string) {
+	u.writes = append(u.writes, pendingWrite{collection: importSessionsCollection, id: sessionID, updates: []firestore.Update{
+		{Path: "Status", Value: string(models.ImportSessionCompleted)},
+		{Path: "ClaimedUntil", Value: firestore.Delete},
+		{Path: "LastUpdated", Value: time.Now().UTC()},
+	}})
+}
+
+// Commit writes the queued creates
+// Up to 500 writes go in one atomic batch, larger sets are committed in chunks and
+// every document from an already committed chunk is soft-deleted if a later chunk fails
+func (u *unitOfWork) Commit(ctx context.Context) (*repository.CommitResult, error) {
+	result := &repository.CommitResult{Atomic: len(u.writes) <= maxBatchWrites}
+
+	var committed []pendingWrite
+	for start := 0; start < len(u.writes); start += maxBatchWrites {
+		end := start + maxBatchWrites
+		if end > len(u.writes) {
+			end = len(u.writes)
+		}
+		chunk := u.writes[start:end]
+
+		batch := u.firestore.Batch()
+		for _, w := range chunk {
+			if w.updates != nil {
+				batch.Update(u.firestore.Collection(w.collection).Doc(w.id), w.updates)
+				continue
+			}
+			// Create fails if the document already exists, so an import never overwrites data
+			batch.Create(u.firestore.Collection(w.collection).Doc(w.id), w.data)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			u.rollback(committed, result)
+			return result, fmt.Errorf("failed to commit batch %d: %v", result.Chunks+1, err)
+		}
+
+		committed = append(committed, chunk...)
+		result.Chunks++
+	}
+
+	u.committed = append(u.committed, u.writes...)
+	u.writes = nil
+	return result, nil
+}
+
+// Undo soft-deletes everything written by previous commits
+func (u *unitOfWork) Undo() *repository.CommitResult {
+	result := &repository.CommitResult{}
+	u.rollback(u.committed, result)
+	u.committed = nil
+	return result
+}
+
+// rollback soft-deletes documents from chunks that were already committed, student ID reservations are deleted
+// Updates are left to the caller, a completed import session is released by the handler undoing the import
+func (u *unitOfWork) rollback(committed []pendingWrite, result *repository.CommitResult) {
+	committed = slices.DeleteFunc(slices.Clone(committed), func(w pendingWrite) bool { return w.updates != nil })
+
+	// The request context may already be cancelled, the rollback has to run anyway
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for start := 0; start < len(committed); start += maxBatchWrites {
+		end := start + maxBatchWrites
+		if end > len(committed) {
+			end = len(committed)
+		}
+		chunk := committed[start:end]
+
+		batch := u.firestore.Batch()
+		for _, w := range chunk {
+			if w.collection == studentIDsCollection {
+				batch.Delete(u.firestore.Collection(w.collection).Doc(w.id))
+				continue
+			}
+			batch.Update(u.firestore.Collection(w.collection).Doc(w.id), []firestore.Update{
+				{Path: "IsDisabled", Value: true},
+				{Path: "LastUpdated", Value: time.Now().UTC()},
+			})
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			for _, w := range chunk {
+				result.RollbackErrors = append(result.RollbackErrors, fmt.Sprintf("%s/%s: %v", w.collection, w.id, err))
+			}
+			continue
+		}
+
+		for _, w := range chunk {
+			switch w.collection {
+			case volunteersCollection:
+				result.RolledBackVolunteerIDs = append(result.RolledBackVolunteerIDs, w.id)
+			case departmentsCollection:
+				result.RolledBackDepartmentIDs = append(result.RolledBackDepartmentIDs, w.id)
+			case eventsCollection:
+				result.RolledBackEventIDs = append(result.RolledBackEventIDs, w.id)
+			}
+		}
+	}
+}
@@ -2,12 +2,19 @@ package firebase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"sheduling-server/models"
+	"sheduling-server/repository"
+	"sheduling-server/utils"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type volunteerRepo struct {
@@ -16,6 +23,16 @@ type volunteerRepo struct {
 
 const volunteersCollection = "volunteers"
 
+// studentIDsCollection reserves student IDs, the document ID is the normalized student ID
+// Firestore has no unique constraints, so a volunteer write and its reservation go in one transaction
+const studentIDsCollection = "volunteer_student_ids"
+
+type studentIDReservation struct {
+	VolunteerID string
+	StudentID   string
+	CreatedAt   time.Time
+}
+
 // CreateVolunteer adds a new volunteer to Firestore
 func (r *volunteerRepo) CreateVolunteer(ctx context.Context, volunteer *models.VolunteerModel) error {
 	if volunteer.ID == "" {
@@ -24,7 +41,15 @@ func (r *volunteerRepo) CreateVolunteer(ctx context.Context, volunteer *models.V
 		volunteer.ID = docRef.ID
 	}
 
-	_, err := r.firestore.Collection(volunteersCollection).Doc(volunteer.ID).Set(ctx, volunteer)
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := r.reserveStudentID(tx, volunteer, ""); err != nil {
+			return err
+		}
+		return tx.Set(r.firestore.Collection(volunteersCollection).Doc(volunteer.ID), volunteer)
+	})
+	if errors.Is(err, repository.ErrStudentIDTaken) {
+		return repository.ErrStudentIDTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create volunteer: %v", err)
 	}
@@ -49,17 +74,100 @@ func (r *volunteerRepo) GetVolunteerByID(ctx context.Context, id string) (*model
 
 // UpdateVolunteer updates an existing volunteer
 func (r *volunteerRepo) UpdateVolunteer(ctx context.Context, volunteer *models.VolunteerModel) error {
-	_, err := r.firestore.Collection(volunteersCollection).Doc(volunteer.ID).Set(ctx, volunteer)
+	docRef := r.firestore.Collection(volunteersCollection).Doc(volunteer.ID)
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// The stored student ID, so its reservation can be released if it changed
+		previousID := ""
+		docSnap, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var previous models.VolunteerModel
+			if err := docSnap.DataTo(&previous); err != nil {
+				return err
+			}
+			previousID = previous.StudentID
+		}
+
+		if err := r.reserveStudentID(tx, volunteer, previousID); err != nil {
+			return err
+		}
+		return tx.Set(docRef, volunteer)
+	})
+	if errors.Is(err, repository.ErrStudentIDTaken) {
+		return repository.ErrStudentIDTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update volunteer: %v", err)
 	}
 	return nil
 }
 
+// reserveStudentID claims the volunteer's student ID and releases the previous one if it changed
+// Must run before any writes in the transaction
+func (r *volunteerRepo) reserveStudentID(tx *firestore.Transaction, volunteer *models.VolunteerModel, previousID string) error {
+	key := utils.NormalizeStudentID(volunteer.StudentID)
+	previousKey := utils.NormalizeStudentID(previousID)
+
+	var reservationRef *firestore.DocumentRef
+	if key != "" {
+		reservationRef = r.firestore.Collection(studentIDsCollection).Doc(key)
+		docSnap, err := tx.Get(reservationRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var reservation studentIDReservation
+			if err := docSnap.DataTo(&reservation); err != nil {
+				return err
+			}
+			if reservation.VolunteerID != volunteer.ID {
+				return repository.ErrStudentIDTaken
+			}
+		}
+	}
+
+	if previousKey != "" && previousKey != key {
+		if err := tx.Delete(r.firestore.Collection(studentIDsCollection).Doc(previousKey)); err != nil {
+			return err
+		}
+	}
+	if reservationRef != nil {
+		return tx.Set(reservationRef, studentIDReservation{
+			VolunteerID: volunteer.ID,
+			StudentID:   volunteer.StudentID,
+			CreatedAt:   time.Now().UTC(),
+		})
+	}
+	return nil
+}
+
 // DeleteVolunteer removes a volunteer from Firestore
 // THIS IS UNUSED CUZ THIS HARD DELETES
 func (r *volunteerRepo) DeleteVolunteer(ctx context.Context, id string) error {
-	_, err := r.firestore.Collection(volunteersCollection).Doc(id).Delete(ctx)
+	docRef := r.firestore.Collection(volunteersCollection).Doc(id)
+	err := r.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var volunteer models.VolunteerModel
+		if err := docSnap.DataTo(&volunteer); err != nil {
+			return err
+		}
+
+		// Free the student ID along with the volunteer
+		if key := utils.NormalizeStudentID(volunteer.StudentID); key != "" {
+			if err := tx.Delete(r.firestore.Collection(studentIDsCollection).Doc(key)); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(docRef)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete volunteer: %v", err)
 	}
@@ -92,3 +200,98 @@ func (r *volunteerRepo) ListVolunteer(ctx context.Context) ([]*models.VolunteerM
 
 	return volunteers, nil
 }
+
+// volunteerSortFields maps sort options to stored fields
+var volunteerSortFields = map[string]sortField{
+	repository.SortByName:      {path: "Name"},
+	repository.SortByCreatedAt: {path: "CreatedAt", isTime: true},
+}
+
+// QueryVolunteers returns a page of volunteers matching the query, sorted by name unless asked otherwise
+func (r *volunteerRepo) QueryVolunteers(ctx context.Context, q repository.VolunteerQuery) (repository.Page[*models.VolunteerModel], error) {
+	if q.SortBy == "" {
+		q.SortBy = repository.SortByName
+	}
+	field, ok := volunteerSortFields[q.SortBy]
+	if !ok {
+		return repository.Page[*models.VolunteerModel]{}, fmt.Errorf("unsupported sort: %s", q.SortBy)
+	}
+
+	query := r.firestore.Collection(volunteersCollection).Query
+	if q.IsDisabled != nil {
+		query = query.Where("IsDisabled", "==", *q.IsDisabled)
+	}
+	if q.SortBy == repository.SortByCreatedAt {
+		// Range on the ordered field needs no extra index
+		if q.CreatedAfter != nil {
+			query = query.Where("CreatedAt", ">=", *q.CreatedAfter)
+		}
+		if q.CreatedBefore != nil {
+			query = query.Where("CreatedAt", "<", *q.CreatedBefore)
+		}
+	}
+
+	var members map[string]bool
+	if q.DepartmentID != "" {
+		docSnap, err := r.firestore.Collection(departmentsCollection).Doc(q.DepartmentID).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return repository.Page[*models.VolunteerModel]{Items: []*models.VolunteerModel{}}, nil
+		}
+		if err != nil {
+			return repository.Page[*models.VolunteerModel]{}, fmt.Errorf("failed to get department: %v", err)
+		}
+		var dept models.DepartmentModel
+		if err := docSnap.DataTo(&dept); err != nil {
+			return repository.Page[*models.VolunteerModel]{}, fmt.Errorf("failed to parse department data: %v", err)
+		}
+		members = make(map[string]bool)
+		for _, member := range dept.VolunteerMembers {
+			members[member.VolunteerID] = true
+		}
+	}
+
+	names := newNameFilter(q.Search, q.Prefix)
+	page, err := queryPage(ctx, query, field, q.ListOptions, decodeVolunteer, func(volunteer *models.VolunteerModel) bool {
+		if members != nil && !members[volunteer.ID] {
+			return false
+		}
+		if !timeInRange(volunteer.CreatedAt, q.CreatedAfter, q.CreatedBefore) {
+			return false
+		}
+		if !hasAllLabels(volunteer.Tags, q.Tags) {
+			return false
+		}
+		return names.matches(volunteer.Name)
+	})
+	if err != nil && !errors.Is(err, repository.ErrInvalidCursor) {
+		return page, fmt.Errorf("failed to query volunteers: %v", err)
+	}
+	return page, err
+}
+
+// decodeVolunteer reads a volunteer document
+func decodeVolunteer(doc *firestore.DocumentSnapshot) (*models.VolunteerModel, error) {
+	var volunteer models.VolunteerModel
+	if err := doc.DataTo(&volunteer); err != nil {
+		return nil, fmt.Errorf("failed to parse volunteer data: %v", err)
+	}
+	volunteer.ID = doc.Ref.ID
+	return &volunteer, nil
+}
+
+// hasAllLabels checks that labels has every wanted label, ignoring case
+func hasAllLabels(labels []string, wanted []string) bool {
+	for _, want := range wanted {
+		found := false
+		for _, label := range labels {
+			if strings.EqualFold(label, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -4,20 +4,23 @@ import (
 	"context"
 	"sheduling-server/models"
 	sub_model "sheduling-server/models/sub_models"
+	"time"
 )
 
 // VolunteerRepository defines database operations for volunteers
 type VolunteerRepository interface {
-	//Creates a volunteer
+	//Creates a volunteer, returns ErrStudentIDTaken if another volunteer has the student ID
 	CreateVolunteer(ctx context.Context, volunteer *models.VolunteerModel) error
 	// Gets all volunteer info from an ID
 	GetVolunteerByID(ctx context.Context, id string) (*models.VolunteerModel, error)
-	// Updates vol info from ID and details
+	// Updates vol info from ID and details, returns ErrStudentIDTaken if another volunteer has the student ID
 	UpdateVolunteer(ctx context.Context, volunteer *models.VolunteerModel) error
-	// Temporarily Deletes a volunteer
+	// Permanently deletes a volunteer and frees its student ID
 	DeleteVolunteer(ctx context.Context, id string) error
 	// Gives all volunteers info but a summarized version (for dashboards)
 	ListVolunteer(ctx context.Context) ([]*models.VolunteerModel, error)
+	// Searches, filters and pages volunteers
+	QueryVolunteers(ctx context.Context, query VolunteerQuery) (Page[*models.VolunteerModel], error)
 }
 
 // DepartmentRepository for departments
@@ -32,6 +35,8 @@ type DepartmentRepository interface {
 	DeleteDepartment(ctx context.Context, id string) error
 	// Gives a summarized list of all the deaprtments
 	ListDepartments(ctx context.Context) ([]*models.DepartmentModel, error)
+	// Searches, filters and pages departments
+	QueryDepartments(ctx context.Context, query DepartmentQuery) (Page[*models.DepartmentModel], error)
 	// Gets all departments where the volunteer is a HEAD
 	GetUserDepartments(ctx context.Context, volunteerID string) ([]*models.DepartmentModel, error)
 	// Links a member to a Department
@@ -52,8 +57,8 @@ type AuthUserRepository interface {
 	GetUserByID(ctx context.Context, id string) (*models.AuthUser, error)
 	// gets a user by their username (for login)
 	GetByUsername(ctx context.Context, username string) (*models.AuthUser, error)
-	// gets a user by their Google email (for OAuth login)
-	GetByGoogleEmail(ctx context.Context, email string) (*models.AuthUser, error)
+	// gets the user a provider account is linked to, by its subject (for OAuth login)
+	GetByOAuthAccount(ctx context.Context, provider sub_model.OAuthProvider, subject, email string) (*models.AuthUser, error)
 	// Updates a users info (prolly for changing access level)
 	UpdateUser(ctx context.Context, user *models.AuthUser) error
 }
@@ -70,6 +75,8 @@ type EventScheduleRepository interface {
 	DeleteEvent(ctx context.Context, id string) error
 	// Gives a summarized list of all events
 	ListEvent(ctx context.Context) ([]*models.EventSchedule, error)
+	// Searches, filters and pages events
+	QueryEvents(ctx context.Context, query EventQuery) (Page[*models.EventSchedule], error)
 	// Adds a volunteer status to an event (check-in)
 	AddVolunteerStatus(ctx context.Context, eventID string, status *sub_model.ScheduleStatus) error
 	// Updates a volunteer status in an event (check-out)
@@ -116,8 +123,179 @@ type LogRepository interface {
 	ArchiveLogsOlderThan(ctx context.Context, beforeDate string) (int, error)
 	// Gets archived logs
 	GetArchivedLogs(ctx context.Context, limit int, offset int) ([]*models.SystemLog, int, error)
-	// Gets logs with enhanced filters (supports all new filter options)
-	GetLogsWithEnhancedFilters(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]*models.SystemLog, int, error)
+	// Filters and pages logs, newest first
+	QueryLogs(ctx context.Context, query LogQuery) (Page[*models.SystemLog], error)
+	// Counts the logs matching the filters of the query
+	CountLogs(ctx context.Context, query LogQuery) (int, error)
+	// Checks the log hash chain from a sequence number and reports the first broken link
+	// Purged logs are checked in their bundles read with loadBundle, when it is nil only their bundle records are
+	VerifyLogChain(ctx context.Context, fromSeq int64, loadBundle LogBundleLoader) (*LogChainReport, error)
+	// Archives the active logs older than the retention of the rule they follow, counts them without archiving on a dry run
+	// Every log should match a rule, include one without category, type and severity as the default
+	ApplyRetentionRules(ctx context.Context, rules []*models.RetentionRule, now time.Time, dryRun bool) (*RetentionReport, error)
+	// Gets up to limit logs archived before the given time, oldest archive first
+	ListLogsArchivedBefore(ctx context.Context, before time.Time, limit int) ([]*models.SystemLog, error)
+	// Permanently deletes logs, returns how many were deleted
+	DeleteLogs(ctx context.Context, ids []string) (int, error)
+	// Writes logs back exactly as they were exported (IDs and chain fields included), returns how many were written
+	RestoreLogs(ctx context.Context, logs []*models.SystemLog) (int, error)
+	// Replaces the volunteer's name and profile values in the logs about them, returns how many logs changed
+	PseudonymizeVolunteerLogs(ctx context.Context, volunteerID string) (int, error)
+	// Reports which of the volunteers were erased, by their VOLUNTEER_ERASED logs
+	ErasedVolunteers(ctx context.Context, volunteerIDs []string) (map[string]bool, error)
+}
+
+// OAuthStateRepository for pending OAuth login attempts
+type OAuthStateRepository interface {
+	// Stores a new login attempt
+	CreateState(ctx context.Context, state *models.OAuthState) error
+	// Gets and deletes a login attempt in one step so a state can only be used once
+	ConsumeState(ctx context.Context, id string) (*models.OAuthState, error)
+	// Deletes login attempts that expired before the given time
+	DeleteExpiredStates(ctx context.Context, before time.Time) (int, error)
+}
+
+// InviteRepository for account invitations
+type InviteRepository interface {
+	// Creates an invite
+	CreateInvite(ctx context.Context, invite *models.Invite) error
+	// Gets an invite by ID
+	GetInviteByID(ctx context.Context, id string) (*models.Invite, error)
+	// Lists all invites, newest first
+	ListInvites(ctx context.Context) ([]*models.Invite, error)
+	// Gets the newest invite for an email that can still be redeemed
+	GetUsableInviteByEmail(ctx context.Context, email string) (*models.Invite, error)
+	// Marks the invite redeemed and creates the user in one transaction, fails if the invite is no longer usable
+	RedeemInvite(ctx context.Context, inviteID string, user *models.AuthUser) error
+	// Revokes a pending invite
+	RevokeInvite(ctx context.Context, inviteID string, revokedBy string) error
+	// Permanently deletes an invite
+	DeleteInvite(ctx context.Context, inviteID string) error
+}
+
+// ServiceAccountRepository for service accounts and their API keys
+type ServiceAccountRepository interface {
+	// Creates a service account
+	CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error
+	// Gets a service account by ID
+	GetServiceAccountByID(ctx context.Context, id string) (*models.ServiceAccount, error)
+	// Lists all service accounts
+	ListServiceAccounts(ctx context.Context) ([]*models.ServiceAccount, error)
+	// Updates a service account (name, scopes, disabled)
+	UpdateServiceAccount(ctx context.Context, account *models.ServiceAccount) error
+	// Creates an API key (KeyHash must already be set)
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	// Gets an API key by the hash of the presented key, returns nil if not found
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// Gets an API key by ID
+	GetAPIKeyByID(ctx context.Context, id string) (*models.APIKey, error)
+	// Lists the keys of a service account
+	ListAPIKeys(ctx context.Context, serviceAccountID string) ([]*models.APIKey, error)
+	// Revokes an API key
+	RevokeAPIKey(ctx context.Context, id string, revokedBy string) error
+	// Records when an API key was last used
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// ImportSessionRepository for previewed batch imports waiting to be executed
+type ImportSessionRepository interface {
+	// Stores a previewed import
+	CreateSession(ctx context.Context, session *models.ImportSession) error
+	// Gets an import session by ID
+	GetSession(ctx context.Context, id string) (*models.ImportSession, error)
+	// Lists the import sessions created by a user
+	ListSessionsByUser(ctx context.Context, userID string) ([]*models.ImportSession, error)
+	// Marks a session as executing until now+lease, returns false if another request holds an unexpired claim
+	// or the session was already imported
+	ClaimSession(ctx context.Context, id string, now time.Time, lease time.Duration) (bool, error)
+	// Puts a claimed session back to pending after a failed import
+	ReleaseSession(ctx context.Context, id string) error
+	// Deletes an import session
+	DeleteSession(ctx context.Context, id string) error
+	// Deletes import sessions that expired before the given time
+	DeleteExpiredSessions(ctx context.Context, before time.Time) (int, error)
+}
+
+// LogBundleRepository for the records of logs moved to cold storage
+type LogBundleRepository interface {
+	// Records a written bundle
+	CreateBundle(ctx context.Context, bundle *models.LogBundle) error
+	// Gets a bundle record by ID
+	GetBundle(ctx context.Context, id string) (*models.LogBundle, error)
+	// Lists all bundle records, newest first
+	ListBundles(ctx context.Context) ([]*models.LogBundle, error)
+	// Records that a bundle was imported back
+	MarkBundleRestored(ctx context.Context, id string, restoredBy string) error
+	// Saves the whole record of a bundle whose file was rewritten
+	UpdateBundle(ctx context.Context, bundle *models.LogBundle) error
+}
+
+// NotificationRepository for queued notifications, they stay as the delivery log once handled
+type NotificationRepository interface {
+	// Queues notifications, assigns IDs if missing
+	CreateNotifications(ctx context.Context, notifications []*models.Notification) error
+	// Queues a notification whose ID is its idempotency key, reports false if it was already queued
+	CreateNotificationOnce(ctx context.Context, notification *models.Notification) (bool, error)
+	// Claims up to limit pending notifications that are due and counts the attempt
+	// Claimed notifications are not due again until lease has passed, so other instances skip them
+	ClaimDueNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.Notification, error)
+	// Saves the outcome of a delivery attempt
+	UpdateNotification(ctx context.Context, notification *models.Notification) error
+	// Deletes every notification queued for a volunteer, returns how many were deleted
+	DeleteVolunteerNotifications(ctx context.Context, volunteerID string) (int, error)
+	// Queries the delivery log, newest first
+	QueryNotifications(ctx context.Context, query NotificationQuery) (Page[*models.Notification], error)
+}
+
+// ReminderJobRepository for the record of which event reminders were queued
+type ReminderJobRepository interface {
+	// Claims a job for this run, creating it if needed
+	// Reports false if the job is done or another instance holds a lease on it
+	ClaimReminderJob(ctx context.Context, job *models.ReminderJob, now time.Time, lease time.Duration) (bool, error)
+	// Marks a claimed job done
+	CompleteReminderJob(ctx context.Context, id string, queued int) error
+}
+
+// RetentionRuleRepository for how long logs stay active, by category, type and severity
+type RetentionRuleRepository interface {
+	// Creates a rule under its match key, returns ErrRetentionRuleExists if one already exists
+	CreateRule(ctx context.Context, rule *models.RetentionRule) error
+	// Gets a rule by ID
+	GetRule(ctx context.Context, id string) (*models.RetentionRule, error)
+	// Lists all rules
+	ListRules(ctx context.Context) ([]*models.RetentionRule, error)
+	// Updates the retention and description of a rule
+	UpdateRule(ctx context.Context, rule *models.RetentionRule) error
+	// Deletes a rule
+	DeleteRule(ctx context.Context, id string) error
+}
+
+// UnitOfWork collects creates across repositories and commits them together
+type UnitOfWork interface {
+	// Queues a volunteer to be created, assigns an ID if missing and reserves its student ID
+	CreateVolunteer(volunteer *models.VolunteerModel)
+	// Queues a department to be created, assigns an ID if missing
+	CreateDepartment(dept *models.DepartmentModel)
+	// Queues an event to be created, assigns an ID if missing
+	CreateEvent(event *models.EventSchedule)
+	// Queues marking an import session completed, written with the last queued creates so it can't be imported twice
+	CompleteImportSession(sessionID string)
+	// Writes everything in the order it was queued
+	// Atomic when it fits in one batch, otherwise committed in chunks and compensated on failure
+	Commit(ctx context.Context) (*CommitResult, error)
+	// Soft-deletes everything a successful Commit wrote, for when a later step of the same operation fails
+	// Runs on its own context so it still works after the request is cancelled
+	Undo() *CommitResult
+}
+
+// CommitResult reports how a unit of work was committed and what was undone if it failed
+type CommitResult struct {
+	Atomic                  bool     // true if everything was written in a single batch
+	Chunks                  int      // number of batches committed
+	RolledBackVolunteerIDs  []string // volunteers soft-deleted after a failed chunk
+	RolledBackDepartmentIDs []string // departments soft-deleted after a failed chunk
+	RolledBackEventIDs      []string // events soft-deleted after a failed chunk
+	RollbackErrors          []string // records that could not be rolled back
 }
 
 // Database interface - manages all repositories
@@ -127,5 +305,14 @@ type Database interface {
 	AuthUsers() AuthUserRepository
 	EventSchedules() EventScheduleRepository
 	Logs() LogRepository
+	OAuthStates() OAuthStateRepository
+	Invites() InviteRepository
+	ServiceAccounts() ServiceAccountRepository
+	ImportSessions() ImportSessionRepository
+	LogBundles() LogBundleRepository
+	RetentionRules() RetentionRuleRepository
+	Notifications() NotificationRepository
+	ReminderJobs() ReminderJobRepository
+	NewUnitOfWork() UnitOfWork
 	Close() error
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"sheduling-server/models"
+)
+
+// LogBundleLoader reads the logs of a bundle from cold storage, failing when the file doesn't match its record
+type LogBundleLoader func(ctx context.Context, bundle *models.LogBundle) ([]*models.SystemLog, error)
+
+// LogChainReport is the result of verifying the log hash chain
+type LogChainReport struct {
+	FromSeq    int64          `json:"fromSeq"`    // first sequence number checked
+	HeadSeq    int64          `json:"headSeq"`    // last sequence number according to the chain head
+	Checked    int            `json:"checked"`    // chained logs read
+	Redacted   int            `json:"redacted"`   // logs pseudonymized by a recorded erasure, linked but with changed content
+	Bundled    int            `json:"bundled"`    // logs purged to cold storage, checked in their bundle
+	Unverified int            `json:"unverified"` // bundled logs linked only through their bundle record, the files weren't read
+	Unchained  int            `json:"unchained"`  // logs written outside the chain (before it existed or by other clients)
+	Pending    int            `json:"pending"`    // logs stored but not linked yet, linked within seconds unless linking keeps failing
+	Breaks     int            `json:"breaks"`     // number of broken links found
+	FirstBreak *LogChainBreak `json:"firstBreak,omitempty"`
+	Valid      bool           `json:"valid"`
+}
+
+// LogChainBreak is a place where the chain doesn't verify
+type LogChainBreak struct {
+	Seq    int64  `json:"seq"`
+	LogID  string `json:"logId,omitempty"` // empty when the log is missing
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a page cursor is malformed or was made for a different sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Sort fields for list queries
+const (
+	SortByName         = "name"
+	SortByCreatedAt    = "createdAt"
+	SortByTimeAndDate  = "timeAndDate"  // events only
+	SortByTimeDetected = "timeDetected" // logs only
+)
+
+// Page is one page of a list query, NextCursor is empty on the last page
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// ListOptions are the sorting and paging options shared by list queries
+type ListOptions struct {
+	SortBy string // one of the SortBy constants, each query has its own default
+	Desc   bool
+	Cursor string // NextCursor of the previous page
+	Limit  int    // 0 returns everything after the cursor
+}
+
+// VolunteerQuery filters volunteers, empty fields don't filter
+// Name matching ignores case and accents
+type VolunteerQuery struct {
+	Search        string // part of the name
+	Prefix        string // start of the name or of any word in it
+	DepartmentID  string // members of this department
+	IsDisabled    *bool
+	Tags          []string // has all of these tags
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	ListOptions
+}
+
+// DepartmentQuery filters departments, empty fields don't filter
+type DepartmentQuery struct {
+	Search        string
+	Prefix        string
+	VolunteerID   string // departments this volunteer is a member of
+	IsDisabled    *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	ListOptions
+}
+
+// EventQuery filters events, empty fields don't filter
+type EventQuery struct {
+	Search       string
+	Prefix       string
+	DepartmentID string // events this department is assigned to
+	VolunteerID  string // events this volunteer is scheduled for
+	IsDisabled   *bool
+	From         *time.Time // TimeAndDate on or after
+	To           *time.Time // TimeAndDate before
+	ListOptions
+}
+
+// LogQuery filters logs, empty fields don't filter
+// Every filter runs in Firestore, logs are always newest first
+type LogQuery struct {
+	Type            sub_model.LogType
+	Category        string
+	Severity        string
+	UserID          string // actor
+	VolunteerID     string
+	EventID         string
+	DepartmentID    string
+	TargetUserID    string     // user acted upon
+	From            *time.Time // TimeDetected on or after
+	To              *time.Time // TimeDetected on or before
+	IncludeArchived bool
+	Offset          int // skipped before the page when there is no cursor, for clients that jump to a page number
+	ListOptions
+}
+
+// Matches applies the filters of the query to a single log, for logs that don't come from QueryLogs
+func (q LogQuery) Matches(log *models.SystemLog) bool {
+	if (q.Type != "" && log.Type != q.Type) ||
+		(q.Category != "" && log.Category != q.Category) ||
+		(q.Severity != "" && log.Severity != q.Severity) ||
+		(!q.IncludeArchived && log.IsArchived) ||
+		(q.From != nil && log.TimeDetected.Before(*q.From)) ||
+		(q.To != nil && log.TimeDetected.After(*q.To)) {
+		return false
+	}
+
+	metadataFilters := map[string]string{
+		sub_model.META_USER_ID:        q.UserID,
+		sub_model.META_VOLUNTEER_ID:   q.VolunteerID,
+		sub_model.META_EVENT_ID:       q.EventID,
+		sub_model.META_DEPARTMENT_ID:  q.DepartmentID,
+		sub_model.META_TARGET_USER_ID: q.TargetUserID,
+	}
+	for key, value := range metadataFilters {
+		if value != "" && log.Metadata[key] != any(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// NotificationQuery filters the notification delivery log, empty fields don't filter
+// Notifications are always newest first
+type NotificationQuery struct {
+	VolunteerID string
+	EventID     string
+	Status      models.NotificationStatus
+	ListOptions
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"sheduling-server/models"
+	"time"
+)
+
+// RetentionReport is the result of applying the retention rules, or of a dry run of them
+type RetentionReport struct {
+	DryRun        bool                  `json:"dryRun"`
+	RanAt         time.Time             `json:"ranAt"`
+	Rules         []RetentionRuleResult `json:"rules"`
+	TotalArchived int                   `json:"totalArchived"` // logs archived, or that would be on a dry run
+}
+
+// RetentionRuleResult is what one rule archived
+type RetentionRuleResult struct {
+	Rule     *models.RetentionRule `json:"rule"`
+	Default  bool                  `json:"default"` // LOG_RETENTION_DAYS, no rule matching every log exists
+	Cutoff   time.Time             `json:"cutoff"`  // logs detected before this are archived
+	Archived int                   `json:"archived"`
+	Oldest   *time.Time            `json:"oldest,omitempty"` // oldest log archived
+}
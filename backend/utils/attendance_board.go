@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+)
+
+// ErrTooManyBoardViewers is returned when the broker already has its maximum number of subscribers
+var ErrTooManyBoardViewers = errors.New("too many attendance board viewers")
+
+// attendanceBoardBuffer is how many changes a viewer can fall behind before it is dropped
+const attendanceBoardBuffer = 64
+
+// AttendanceAction is what happened to a volunteer's status in an event
+type AttendanceAction string
+
+const (
+	AttendanceAdded   AttendanceAction = "ADDED"
+	AttendanceUpdated AttendanceAction = "UPDATED"
+	AttendanceRemoved AttendanceAction = "REMOVED"
+)
+
+// AttendanceChange is a change to one volunteer's status, Status is nil when the volunteer was removed
+type AttendanceChange struct {
+	EventID     string                    `json:"eventId"`
+	VolunteerID string                    `json:"volunteerId"`
+	Action      AttendanceAction          `json:"action"`
+	Status      *sub_model.ScheduleStatus `json:"status,omitempty"`
+	ChangedAt   time.Time                 `json:"changedAt"`
+}
+
+// AttendanceBroker hands status changes to the viewers of the event's board
+// It is in-process, so a viewer only sees changes made through the same server instance
+type AttendanceBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*AttendanceSubscription]struct{} // by event ID
+	count       int
+	maxViewers  int
+}
+
+// AttendanceSubscription receives the changes of one event on Changes
+// Changes is closed when the subscription ends, also when the viewer fell too far behind
+type AttendanceSubscription struct {
+	Changes <-chan AttendanceChange
+	changes chan AttendanceChange
+	eventID string
+}
+
+// NewAttendanceBroker creates a broker, maxViewers <= 0 means no limit
+func NewAttendanceBroker(maxViewers int) *AttendanceBroker {
+	return &AttendanceBroker{
+		subscribers: map[string]map[*AttendanceSubscription]struct{}{},
+		maxViewers:  maxViewers,
+	}
+}
+
+// Subscribe starts receiving the changes of an event, call Unsubscribe when done
+func (b *AttendanceBroker) Subscribe(eventID string) (*AttendanceSubscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxViewers > 0 && b.count >= b.maxViewers {
+		return nil, ErrTooManyBoardViewers
+	}
+
+	changes := make(chan AttendanceChange, attendanceBoardBuffer)
+	sub := &AttendanceSubscription{Changes: changes, changes: changes, eventID: eventID}
+	if b.subscribers[eventID] == nil {
+		b.subscribers[eventID] = map[*AttendanceSubscription]struct{}{}
+	}
+	b.subscribers[eventID][sub] = struct{}{}
+	b.count++
+	return sub, nil
+}
+
+// Unsubscribe ends a subscription, it is safe to call more than once
+func (b *AttendanceBroker) Unsubscribe(sub *AttendanceSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove drops a subscriber, the lock must be held
+func (b *AttendanceBroker) remove(sub *AttendanceSubscription) {
+	viewers := b.subscribers[sub.eventID]
+	if _, ok := viewers[sub]; !ok {
+		return
+	}
+	delete(viewers, sub)
+	if len(viewers) == 0 {
+		delete(b.subscribers, sub.eventID)
+	}
+	b.count--
+	close(sub.changes)
+}
+
+// Watching reports whether the event's board has viewers
+func (b *AttendanceBroker) Watching(eventID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers[eventID]) > 0
+}
+
+// Publish hands a change to the viewers of its event without waiting on them
+// A viewer whose buffer is full is dropped, it has to reconnect for a new snapshot
+func (b *AttendanceBroker) Publish(change AttendanceChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[change.EventID] {
+		select {
+		case sub.changes <- change:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// PublishAttendance wraps a database so every status added, updated or removed is also published to the broker
+// It works the same for any backend, changes are published once they were stored
+func PublishAttendance(db repository.Database, broker *AttendanceBroker) repository.Database {
+	return &attendanceDatabase{
+		Database: db,
+		events:   &publishingEventRepo{EventScheduleRepository: db.EventSchedules(), broker: broker},
+	}
+}
+
+type attendanceDatabase struct {
+	repository.Database
+	events repository.EventScheduleRepository
+}
+
+func (db *attendanceDatabase) EventSchedules() repository.EventScheduleRepository {
+	return db.events
+}
+
+type publishingEventRepo struct {
+	repository.EventScheduleRepository
+	broker *AttendanceBroker
+}
+
+func (r *publishingEventRepo) AddVolunteerStatus(ctx context.Context, eventID string, status *sub_model.ScheduleStatus) error {
+	if err := r.EventScheduleRepository.AddVolunteerStatus(ctx, eventID, status); err != nil {
+		return err
+	}
+	added := *status
+	r.broker.Publish(AttendanceChange{
+		EventID:     eventID,
+		VolunteerID: status.VolunteerID,
+		Action:      AttendanceAdded,
+		Status:      &added,
+		ChangedAt:   time.Now().UTC(),
+	})
+	return nil
+}
+
+// UpdateVolunteerStatus publishes the whole stored status, the update itself only carries the changed times
+func (r *publishingEventRepo) UpdateVolunteerStatus(ctx context.Context, eventID string, volunteerID string, status *sub_model.ScheduleStatus) error {
+	if err := r.EventScheduleRepository.UpdateVolunteerStatus(ctx, eventID, volunteerID, status); err != nil {
+		return err
+	}
+	if !r.broker.Watching(eventID) {
+		return nil
+	}
+
+	event, err := r.EventScheduleRepository.GetEventByID(ctx, eventID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read updated status for the attendance board", "eventId", eventID, "error", err)
+		return nil
+	}
+	for _, stored := range event.Statuses {
+		if stored.VolunteerID == volunteerID {
+			r.broker.Publish(AttendanceChange{
+				EventID:     eventID,
+				VolunteerID: volunteerID,
+				Action:      AttendanceUpdated,
+				Status:      &stored,
+				ChangedAt:   time.Now().UTC(),
+			})
+			break
+		}
+	}
+	return nil
+}
+
+func (r *publishingEventRepo) RemoveVolunteerFromEvent(ctx context.Context, eventID string, volunteerID string) error {
+	if err := r.EventScheduleRepository.RemoveVolunteerFromEvent(ctx, eventID, volunteerID); err != nil {
+		return err
+	}
+	r.broker.Publish(AttendanceChange{
+		EventID:     eventID,
+		VolunteerID: volunteerID,
+		Action:      AttendanceRemoved,
+		ChangedAt:   time.Now().UTC(),
+	})
+	return nil
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoColdStorage is returned when logs need purging or restoring but no cold storage is configured
+var ErrNoColdStorage = errors.New("no log cold storage configured")
+
+// LogBundleStore holds the files of purged log bundles
+type LogBundleStore interface {
+	Name() string // recorded on the bundle, e.g. "local:/var/log-bundles" or "s3:bucket"
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error // deleting a missing file is not an error
+}
+
+// logColdStore is the store purged logs are written to, nil when cold storage is off
+var logColdStore LogBundleStore
+
+// InitLogColdStorage configures where purged logs are written from the environment
+//
+//   - LOG_COLD_STORAGE: "local" or "s3", archived logs are never purged when unset
+//   - LOG_COLD_STORAGE_PATH: directory for "local", defaults to ./log-bundles
+//   - LOG_COLD_STORAGE_S3_ENDPOINT, LOG_COLD_STORAGE_S3_BUCKET, LOG_COLD_STORAGE_S3_REGION (us-east-1 by default),
+//     LOG_COLD_STORAGE_S3_ACCESS_KEY, LOG_COLD_STORAGE_S3_SECRET_KEY: any S3 compatible storage,
+//     e.g. https://s3.eu-west-1.amazonaws.com or a local MinIO at http://localhost:9000
+func InitLogColdStorage() error {
+	switch kind := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_COLD_STORAGE"))); kind {
+	case "":
+		logColdStore = nil
+		slog.Info("LOG_COLD_STORAGE not set, archived logs will stay in the database")
+		return nil
+	case "local":
+		dir := os.Getenv("LOG_COLD_STORAGE_PATH")
+		if dir == "" {
+			dir = "./log-bundles"
+		}
+		store, err := NewLocalBundleStore(dir)
+		if err != nil {
+			return err
+		}
+		logColdStore = store
+	case "s3":
+		store, err := NewS3BundleStore(
+			os.Getenv("LOG_COLD_STORAGE_S3_ENDPOINT"),
+			os.Getenv("LOG_COLD_STORAGE_S3_BUCKET"),
+			os.Getenv("LOG_COLD_STORAGE_S3_REGION"),
+			os.Getenv("LOG_COLD_STORAGE_S3_ACCESS_KEY"),
+			os.Getenv("LOG_COLD_STORAGE_S3_SECRET_KEY"),
+		)
+		if err != nil {
+			return err
+		}
+		logColdStore = store
+	default:
+		return fmt.Errorf("invalid LOG_COLD_STORAGE %q, expected local or s3", kind)
+	}
+
+	slog.Info("Log cold storage enabled", "store", logColdStore.Name())
+	return nil
+}
+
+// LogColdStore returns the configured cold storage, or ErrNoColdStorage
+func LogColdStore() (LogBundleStore, error) {
+	if logColdStore == nil {
+		return nil, ErrNoColdStorage
+	}
+	return logColdStore, nil
+}
+
+// localBundleStore keeps bundles in a directory, for single servers and local testing
+type localBundleStore struct {
+	dir string
+}
+
+// NewLocalBundleStore creates a store in dir, creating the directory if needed
+func NewLocalBundleStore(dir string) (LogBundleStore, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve log cold storage path: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create log cold storage directory: %v", err)
+	}
+	return &localBundleStore{dir: dir}, nil
+}
+
+func (s *localBundleStore) Name() string {
+	return "local:" + s.dir
+}
+
+// path resolves a key inside the directory, keys can't point outside it
+func (s *localBundleStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, s.dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid bundle key %q", key)
+	}
+	return path, nil
+}
+
+// Put writes to a temporary file first, so a bundle is either complete or missing
+func (s *localBundleStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write bundle file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write bundle file: %v", err)
+	}
+	return nil
+}
+
+func (s *localBundleStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle file: %v", err)
+	}
+	return data, nil
+}
+
+func (s *localBundleStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete bundle file: %v", err)
+	}
+	return nil
+}
+
+// s3BundleStore keeps bundles in an S3 compatible bucket, requests are signed with AWS Signature Version 4
+// Objects are addressed path style (endpoint/bucket/key), which every S3 compatible storage accepts
+type s3BundleStore struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3BundleStore creates a store for a bucket at an S3 compatible endpoint
+func NewS3BundleStore(endpoint, bucket, region, accessKey, secretKey string) (LogBundleStore, error) {
+	if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("LOG_COLD_STORAGE_S3_ENDPOINT, _BUCKET, _ACCESS_KEY and _SECRET_KEY are required for s3 cold storage")
+	}
+	parsed, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid LOG_COLD_STORAGE_S3_ENDPOINT %q", endpoint)
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &s3BundleStore{
+		endpoint:  parsed,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (s *s3BundleStore) Name() string {
+	return "s3:" + s.bucket
+}
+
+func (s *s3BundleStore) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload bundle file: %v", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3BundleStore) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download bundle file: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download bundle file: %v", err)
+	}
+	return data, nil
+}
+
+// Delete succeeds for a missing object too, S3 answers 204 either way
+func (s *s3BundleStore) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete bundle file: %v", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for an object and fails on any non 2xx status
+func (s *s3BundleStore) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	escapedPath := strings.TrimRight(s.endpoint.EscapedPath(), "/") + "/" + s3Escape(s.bucket) + "/" + s3Escape(key)
+	target := *s.endpoint
+	target.RawPath = escapedPath
+	target.Path, _ = url.PathUnescape(escapedPath)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapedPath,
+		"", // no query string
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// s3Escape percent-encodes everything but unreserved characters and slashes, as Signature Version 4 expects
+func s3Escape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || ch == '/' {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
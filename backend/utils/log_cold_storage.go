@@ -0,0 +1,411 @@
+package utils
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+
+	"github.com/google/uuid"
+)
+
+// logBundleSize is the most logs written to one bundle, a purge writes as many bundles as needed
+const logBundleSize = 5000
+
+// LogBundleFormat is the only bundle format, gzip compressed NDJSON with one SystemLog per line
+const LogBundleFormat = "ndjson+gzip"
+
+// PurgeArchivedLogs exports logs archived before archivedBefore to bundles in cold storage, then deletes them
+// A bundle is written and recorded before its logs are deleted, so a failed purge never loses logs,
+// at worst the next purge writes the same logs to a second bundle
+func PurgeArchivedLogs(ctx context.Context, logs repository.LogRepository, bundles repository.LogBundleRepository, archivedBefore time.Time) ([]*models.LogBundle, error) {
+	store, err := LogColdStore()
+	if err != nil {
+		return nil, err
+	}
+
+	written := []*models.LogBundle{}
+	for {
+		batch, err := logs.ListLogsArchivedBefore(ctx, archivedBefore, logBundleSize)
+		if err != nil {
+			return written, err
+		}
+		if len(batch) == 0 {
+			return written, nil
+		}
+
+		bundle, err := writeLogBundle(ctx, store, batch, archivedBefore)
+		if err != nil {
+			return written, err
+		}
+		if err := bundles.CreateBundle(ctx, bundle); err != nil {
+			return written, err
+		}
+
+		ids := make([]string, 0, len(batch))
+		for _, entry := range batch {
+			ids = append(ids, entry.ID)
+		}
+		if _, err := logs.DeleteLogs(ctx, ids); err != nil {
+			return written, err
+		}
+		written = append(written, bundle)
+		slog.InfoContext(ctx, "Purged archived logs to bundle", "bundleId", bundle.ID, "logs", bundle.LogCount)
+	}
+}
+
+// writeLogBundle compresses logs into a bundle file, then writes the manifest next to it
+func writeLogBundle(ctx context.Context, store LogBundleStore, logs []*models.SystemLog, archivedBefore time.Time) (*models.LogBundle, error) {
+	data, err := encodeLogBundle(logs)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	bundle := &models.LogBundle{
+		ID:             id,
+		Format:         LogBundleFormat,
+		Store:          store.Name(),
+		DataKey:        "logs/" + id + ".ndjson.gz",
+		ManifestKey:    "logs/" + id + ".manifest.json",
+		SHA256:         sha256Hex(data),
+		Size:           int64(len(data)),
+		LogCount:       len(logs),
+		ArchivedBefore: archivedBefore.UTC(),
+		SeqRanges:      logSeqRanges(logs),
+		CreatedAt:      time.Now().UTC(),
+
+		VolunteerIDs:      logVolunteerIDs(logs),
+		VolunteersIndexed: true,
+	}
+	for _, entry := range logs {
+		if bundle.OldestLog.IsZero() || entry.TimeDetected.Before(bundle.OldestLog) {
+			bundle.OldestLog = entry.TimeDetected
+		}
+		if entry.TimeDetected.After(bundle.NewestLog) {
+			bundle.NewestLog = entry.TimeDetected
+		}
+	}
+
+	if err := store.Put(ctx, bundle.DataKey, data); err != nil {
+		return nil, err
+	}
+	if err := writeBundleManifest(ctx, store, bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// encodeLogBundle compresses logs into the bundle format, one JSON log per line
+func encodeLogBundle(logs []*models.SystemLog) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, entry := range logs {
+		if err := encoder.Encode(entry); err != nil {
+			return nil, fmt.Errorf("failed to encode log %s: %v", entry.ID, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress log bundle: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBundleManifest writes the record of a bundle next to its file
+func writeBundleManifest(ctx context.Context, store LogBundleStore, bundle *models.LogBundle) error {
+	manifest, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %v", err)
+	}
+	return store.Put(ctx, bundle.ManifestKey, manifest)
+}
+
+// logVolunteerIDs lists the volunteers the logs are about, in the order they first appear
+func logVolunteerIDs(logs []*models.SystemLog) []string {
+	seen := make(map[string]bool)
+	volunteerIDs := []string{}
+	for _, entry := range logs {
+		if volunteerID, ok := entry.Metadata[sub_model.META_VOLUNTEER_ID].(string); ok && volunteerID != "" && !seen[volunteerID] {
+			seen[volunteerID] = true
+			volunteerIDs = append(volunteerIDs, volunteerID)
+		}
+	}
+	return volunteerIDs
+}
+
+// logSeqRanges groups the chained logs into runs of consecutive sequence numbers
+func logSeqRanges(logs []*models.SystemLog) []models.LogSeqRange {
+	chained := make([]*models.SystemLog, 0, len(logs))
+	for _, entry := range logs {
+		if entry.Seq > 0 {
+			chained = append(chained, entry)
+		}
+	}
+	slices.SortFunc(chained, func(a, b *models.SystemLog) int { return cmp.Compare(a.Seq, b.Seq) })
+
+	ranges := []models.LogSeqRange{}
+	for _, entry := range chained {
+		if n := len(ranges); n > 0 && ranges[n-1].To+1 == entry.Seq {
+			ranges[n-1].To = entry.Seq
+			ranges[n-1].LastHash = entry.Hash
+			continue
+		}
+		ranges = append(ranges, models.LogSeqRange{From: entry.Seq, To: entry.Seq, LastHash: entry.Hash})
+	}
+	return ranges
+}
+
+// RestoreLogBundle writes the logs of a bundle back to the logs collection
+// The file is checked against the recorded checksum first, restored logs count as archived now,
+// so they stay until the next purge after the purge period. Logs about volunteers erased since the
+// bundle was written are pseudonymized again before they are written
+func RestoreLogBundle(ctx context.Context, logs repository.LogRepository, bundles repository.LogBundleRepository, bundle *models.LogBundle, restoredBy string) (int, error) {
+	store, err := LogColdStore()
+	if err != nil {
+		return 0, err
+	}
+	restored, err := loadLogBundle(ctx, store, bundle)
+	if err != nil {
+		return 0, err
+	}
+
+	pseudonymized, err := pseudonymizeErasedLogs(ctx, logs, restored)
+	if err != nil {
+		return 0, err
+	}
+	if pseudonymized > 0 {
+		slog.InfoContext(ctx, "Pseudonymized restored logs of erased volunteers", "bundleId", bundle.ID, "logs", pseudonymized)
+	}
+
+	archiveDate := time.Now().UTC()
+	for _, entry := range restored {
+		entry.IsArchived = true
+		entry.ArchiveDate = &archiveDate
+	}
+	count, err := logs.RestoreLogs(ctx, restored)
+	if err != nil {
+		return count, err
+	}
+	if err := bundles.MarkBundleRestored(ctx, bundle.ID, restoredBy); err != nil {
+		return count, err
+	}
+	bundle.RestoredAt = &archiveDate
+	bundle.RestoredBy = restoredBy
+	return count, nil
+}
+
+// pseudonymizeErasedLogs pseudonymizes the logs about volunteers that have an erasure record
+func pseudonymizeErasedLogs(ctx context.Context, logs repository.LogRepository, entries []*models.SystemLog) (int, error) {
+	volunteerIDs := logVolunteerIDs(entries)
+	if len(volunteerIDs) == 0 {
+		return 0, nil
+	}
+	erased, err := logs.ErasedVolunteers(ctx, volunteerIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	erasedAt := time.Now().UTC()
+	count := 0
+	for _, entry := range entries {
+		volunteerID, _ := entry.Metadata[sub_model.META_VOLUNTEER_ID].(string)
+		if erased[volunteerID] && entry.Pseudonymize(erasedAt) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// LoadLogBundle reads the logs of a bundle from cold storage, checked against the recorded checksum and count
+func LoadLogBundle(ctx context.Context, bundle *models.LogBundle) ([]*models.SystemLog, error) {
+	store, err := LogColdStore()
+	if err != nil {
+		return nil, err
+	}
+	return loadLogBundle(ctx, store, bundle)
+}
+
+func loadLogBundle(ctx context.Context, store LogBundleStore, bundle *models.LogBundle) ([]*models.SystemLog, error) {
+	if bundle.Store != store.Name() {
+		return nil, fmt.Errorf("bundle %s is in %s, not in the configured %s", bundle.ID, bundle.Store, store.Name())
+	}
+
+	data, err := store.Get(ctx, bundle.DataKey)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256Hex(data); sum != bundle.SHA256 {
+		return nil, fmt.Errorf("bundle %s checksum mismatch: recorded %s, file has %s", bundle.ID, bundle.SHA256, sum)
+	}
+
+	logs, err := decodeLogBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) != bundle.LogCount {
+		return nil, fmt.Errorf("bundle %s holds %d logs, %d were recorded", bundle.ID, len(logs), bundle.LogCount)
+	}
+	return logs, nil
+}
+
+// volunteerBundles lists the bundles that can hold logs about a volunteer
+func volunteerBundles(ctx context.Context, bundles repository.LogBundleRepository, volunteerID string) ([]*models.LogBundle, error) {
+	all, err := bundles.ListBundles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []*models.LogBundle
+	for _, bundle := range all {
+		if bundle.MayHoldVolunteer(volunteerID) {
+			result = append(result, bundle)
+		}
+	}
+	return result, nil
+}
+
+// VolunteerBundledLogs returns the logs about a volunteer that were purged to cold storage
+// Cold storage is only needed when a bundle can hold their logs
+func VolunteerBundledLogs(ctx context.Context, bundles repository.LogBundleRepository, volunteerID string) ([]*models.SystemLog, error) {
+	candidates, err := volunteerBundles(ctx, bundles, volunteerID)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	store, err := LogColdStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*models.SystemLog
+	for _, bundle := range candidates {
+		logs, err := loadLogBundle(ctx, store, bundle)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range logs {
+			if entry.Metadata[sub_model.META_VOLUNTEER_ID] == volunteerID {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result, nil
+}
+
+// PseudonymizeBundledLogs pseudonymizes the logs about a volunteer in cold storage, returns how many changed
+// A bundle holding their logs is written again under a new key, its record moved to the new file and the old
+// file deleted. An old file that couldn't be deleted stays in StaleKeys and is deleted by the next call
+func PseudonymizeBundledLogs(ctx context.Context, bundles repository.LogBundleRepository, volunteerID string) (int, error) {
+	candidates, err := volunteerBundles(ctx, bundles, volunteerID)
+	if err != nil || len(candidates) == 0 {
+		return 0, err
+	}
+	store, err := LogColdStore()
+	if err != nil {
+		return 0, err
+	}
+
+	erasedAt := time.Now().UTC()
+	pseudonymizedCount := 0
+	for _, bundle := range candidates {
+		if err := deleteStaleBundleFiles(ctx, store, bundles, bundle); err != nil {
+			return pseudonymizedCount, err
+		}
+
+		logs, err := loadLogBundle(ctx, store, bundle)
+		if err != nil {
+			return pseudonymizedCount, err
+		}
+		changed := 0
+		for _, entry := range logs {
+			if entry.Metadata[sub_model.META_VOLUNTEER_ID] == volunteerID && entry.Pseudonymize(erasedAt) {
+				changed++
+			}
+		}
+
+		if changed == 0 {
+			if !bundle.VolunteersIndexed {
+				bundle.VolunteerIDs = logVolunteerIDs(logs)
+				bundle.VolunteersIndexed = true
+				if err := bundles.UpdateBundle(ctx, bundle); err != nil {
+					return pseudonymizedCount, err
+				}
+			}
+			continue
+		}
+
+		data, err := encodeLogBundle(logs)
+		if err != nil {
+			return pseudonymizedCount, err
+		}
+		dataKey := fmt.Sprintf("logs/%s.%d.ndjson.gz", bundle.ID, erasedAt.UnixNano())
+		if err := store.Put(ctx, dataKey, data); err != nil {
+			return pseudonymizedCount, err
+		}
+		bundle.StaleKeys = append(bundle.StaleKeys, bundle.DataKey)
+		bundle.DataKey = dataKey
+		bundle.SHA256 = sha256Hex(data)
+		bundle.Size = int64(len(data))
+		bundle.VolunteerIDs = logVolunteerIDs(logs)
+		bundle.VolunteersIndexed = true
+		bundle.RewrittenAt = &erasedAt
+		if err := bundles.UpdateBundle(ctx, bundle); err != nil {
+			return pseudonymizedCount, err
+		}
+		pseudonymizedCount += changed
+
+		if err := deleteStaleBundleFiles(ctx, store, bundles, bundle); err != nil {
+			return pseudonymizedCount, err
+		}
+		slog.InfoContext(ctx, "Pseudonymized bundled logs", "bundleId", bundle.ID, "logs", changed)
+	}
+	return pseudonymizedCount, nil
+}
+
+// deleteStaleBundleFiles deletes the files a bundle was moved away from and rewrites its manifest
+func deleteStaleBundleFiles(ctx context.Context, store LogBundleStore, bundles repository.LogBundleRepository, bundle *models.LogBundle) error {
+	if len(bundle.StaleKeys) == 0 {
+		return nil
+	}
+	for _, key := range bundle.StaleKeys {
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	bundle.StaleKeys = nil
+	if err := bundles.UpdateBundle(ctx, bundle); err != nil {
+		return err
+	}
+	return writeBundleManifest(ctx, store, bundle)
+}
+
+// decodeLogBundle reads the logs out of a bundle file
+func decodeLogBundle(data []byte) ([]*models.SystemLog, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress log bundle: %v", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	logs := []*models.SystemLog{}
+	for {
+		var entry models.SystemLog
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode log bundle: %v", err)
+		}
+		logs = append(logs, &entry)
+	}
+	return logs, nil
+}
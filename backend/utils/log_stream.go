@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"sheduling-server/models"
+	"sheduling-server/repository"
+)
+
+// ErrTooManyLogStreams is returned when the broker already has its maximum number of subscribers
+var ErrTooManyLogStreams = errors.New("too many log streams open")
+
+// logStreamBuffer is how many logs a subscriber can fall behind before it is dropped
+const logStreamBuffer = 256
+
+// LogBroker hands newly created logs to the subscribers whose filters match them
+// It is in-process, so a subscriber only sees logs created by the same server instance
+type LogBroker struct {
+	mu             sync.Mutex
+	subscribers    map[*LogSubscription]struct{}
+	maxSubscribers int
+}
+
+// LogSubscription receives the logs matching its query on Logs
+// Logs is closed when the subscription ends, also when the subscriber fell too far behind
+type LogSubscription struct {
+	Logs  <-chan *models.SystemLog
+	logs  chan *models.SystemLog
+	query repository.LogQuery
+}
+
+// NewLogBroker creates a broker, maxSubscribers <= 0 means no limit
+func NewLogBroker(maxSubscribers int) *LogBroker {
+	return &LogBroker{
+		subscribers:    map[*LogSubscription]struct{}{},
+		maxSubscribers: maxSubscribers,
+	}
+}
+
+// Subscribe starts receiving the logs matching the query, call Unsubscribe when done
+func (b *LogBroker) Subscribe(query repository.LogQuery) (*LogSubscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxSubscribers > 0 && len(b.subscribers) >= b.maxSubscribers {
+		return nil, ErrTooManyLogStreams
+	}
+
+	logs := make(chan *models.SystemLog, logStreamBuffer)
+	sub := &LogSubscription{Logs: logs, logs: logs, query: query}
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe ends a subscription, it is safe to call more than once
+func (b *LogBroker) Unsubscribe(sub *LogSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.logs)
+	}
+}
+
+// Publish hands a log to the matching subscribers without waiting on them
+// A subscriber whose buffer is full is dropped, it has to reconnect and catch up from the database
+func (b *LogBroker) Publish(log *models.SystemLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if !sub.query.Matches(log) {
+			continue
+		}
+		select {
+		case sub.logs <- log:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.logs)
+		}
+	}
+}
+
+// PublishLogs wraps a database so every log it creates is also published to the broker
+// It works the same for any backend, logs are published once they were stored
+func PublishLogs(db repository.Database, broker *LogBroker) repository.Database {
+	return &publishingDatabase{
+		Database: db,
+		logs:     &publishingLogRepo{LogRepository: db.Logs(), broker: broker},
+	}
+}
+
+type publishingDatabase struct {
+	repository.Database
+	logs repository.LogRepository
+}
+
+func (db *publishingDatabase) Logs() repository.LogRepository {
+	return db.logs
+}
+
+type publishingLogRepo struct {
+	repository.LogRepository
+	broker *LogBroker
+}
+
+func (r *publishingLogRepo) CreateLog(ctx context.Context, log *models.SystemLog) error {
+	if err := r.LogRepository.CreateLog(ctx, log); err != nil {
+		return err
+	}
+	r.broker.Publish(log)
+	return nil
+}
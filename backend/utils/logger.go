@@ -2,8 +2,7 @@ package utils
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"sheduling-server/models"
@@ -29,6 +28,9 @@ func CreateAuditLog(c *gin.Context, db repository.Database, logType sub_model.Lo
 	}
 
 	// Create the log
+	addAPIKeyMetadata(c.Request.Context(), metadata)
+	addRequestMetadata(c.Request.Context(), metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -41,7 +43,7 @@ func CreateAuditLog(c *gin.Context, db repository.Database, logType sub_model.Lo
 	err := db.Logs().CreateLog(c.Request.Context(), systemLog)
 	if err != nil {
 		// Log the error but don't fail the main operation
-		log.Printf("WARNING: Failed to create audit log (type: %s): %v", logType, err)
+		slog.WarnContext(c.Request.Context(), "Failed to create audit log", "type", logType, "error", err)
 		return err
 	}
 
@@ -60,6 +62,9 @@ func CreateAuditLogWithUserInfo(ctx context.Context, db repository.Database, log
 	}
 
 	// Create the log
+	addAPIKeyMetadata(ctx, metadata)
+	addRequestMetadata(ctx, metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -72,7 +77,7 @@ func CreateAuditLogWithUserInfo(ctx context.Context, db repository.Database, log
 	err := db.Logs().CreateLog(ctx, systemLog)
 	if err != nil {
 		// Log the error but don't fail the main operation
-		log.Printf("WARNING: Failed to create audit log (type: %s): %v", logType, err)
+		slog.WarnContext(ctx, "Failed to create audit log", "type", logType, "error", err)
 		return err
 	}
 
@@ -81,6 +86,9 @@ func CreateAuditLogWithUserInfo(ctx context.Context, db repository.Database, log
 
 // CreateSystemLog creates a system log without user context (for automated processes)
 func CreateSystemLog(ctx context.Context, db repository.Database, logType sub_model.LogType, metadata map[string]interface{}) error {
+	addAPIKeyMetadata(ctx, metadata)
+	addRequestMetadata(ctx, metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -91,7 +99,7 @@ func CreateSystemLog(ctx context.Context, db repository.Database, logType sub_mo
 
 	err := db.Logs().CreateLog(ctx, systemLog)
 	if err != nil {
-		log.Printf("WARNING: Failed to create system log (type: %s): %v", logType, err)
+		slog.WarnContext(ctx, "Failed to create system log", "type", logType, "error", err)
 		return err
 	}
 
@@ -102,7 +110,7 @@ func CreateSystemLog(ctx context.Context, db repository.Database, logType sub_mo
 func LogError(c *gin.Context, message string, err error) {
 	userID, _ := c.Get("userID")
 	username, _ := c.Get("username")
-	log.Printf("ERROR [User: %v (%v)]: %s - %v", username, userID, message, err)
+	slog.ErrorContext(c.Request.Context(), message, "userId", userID, "username", username, "error", err)
 }
 
 // SafeLog safely attempts to create a log and prints error if it fails
@@ -110,7 +118,6 @@ func LogError(c *gin.Context, message string, err error) {
 func SafeLog(c *gin.Context, db repository.Database, logType sub_model.LogType, metadata map[string]interface{}) bool {
 	err := CreateAuditLog(c, db, logType, metadata)
 	if err != nil {
-		fmt.Printf("Failed to create audit log: %v\n", err)
 		return false
 	}
 	return true
@@ -118,6 +125,9 @@ func SafeLog(c *gin.Context, db repository.Database, logType sub_model.LogType,
 
 // CreateLogWithSeverity creates a log with automatic category and specified severity
 func CreateLogWithSeverity(ctx context.Context, db repository.Database, logType sub_model.LogType, severity string, metadata map[string]interface{}) error {
+	addAPIKeyMetadata(ctx, metadata)
+	addRequestMetadata(ctx, metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -132,7 +142,7 @@ func CreateLogWithSeverity(ctx context.Context, db repository.Database, logType
 
 	err := db.Logs().CreateLog(ctx, systemLog)
 	if err != nil {
-		log.Printf("WARNING: Failed to create log (type: %s): %v", logType, err)
+		slog.WarnContext(ctx, "Failed to create log", "type", logType, "error", err)
 		return err
 	}
 
@@ -164,6 +174,9 @@ func CreateAttendanceLog(c *gin.Context, db repository.Database, logType sub_mod
 		metadata[sub_model.META_USERNAME] = username
 	}
 
+	addAPIKeyMetadata(c.Request.Context(), metadata)
+	addRequestMetadata(c.Request.Context(), metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -178,7 +191,7 @@ func CreateAttendanceLog(c *gin.Context, db repository.Database, logType sub_mod
 
 	err := db.Logs().CreateLog(c.Request.Context(), systemLog)
 	if err != nil {
-		log.Printf("WARNING: Failed to create attendance log (type: %s): %v", logType, err)
+		slog.WarnContext(c.Request.Context(), "Failed to create attendance log", "type", logType, "error", err)
 		return err
 	}
 
@@ -202,6 +215,9 @@ func CreateEntityChangeLog(c *gin.Context, db repository.Database, logType sub_m
 		metadata[sub_model.META_USERNAME] = username
 	}
 
+	addAPIKeyMetadata(c.Request.Context(), metadata)
+	addRequestMetadata(c.Request.Context(), metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -216,7 +232,7 @@ func CreateEntityChangeLog(c *gin.Context, db repository.Database, logType sub_m
 
 	err := db.Logs().CreateLog(c.Request.Context(), systemLog)
 	if err != nil {
-		log.Printf("WARNING: Failed to create entity change log (type: %s): %v", logType, err)
+		slog.WarnContext(c.Request.Context(), "Failed to create entity change log", "type", logType, "error", err)
 		return err
 	}
 
@@ -236,6 +252,9 @@ func CreateEnhancedLog(c *gin.Context, db repository.Database, logType sub_model
 		metadata[sub_model.META_USERNAME] = username
 	}
 
+	addAPIKeyMetadata(c.Request.Context(), metadata)
+	addRequestMetadata(c.Request.Context(), metadata)
+
 	systemLog := &models.SystemLog{
 		ID:           uuid.New().String(),
 		TimeDetected: time.Now().UTC(),
@@ -250,9 +269,35 @@ func CreateEnhancedLog(c *gin.Context, db repository.Database, logType sub_model
 
 	err := db.Logs().CreateLog(c.Request.Context(), systemLog)
 	if err != nil {
-		log.Printf("WARNING: Failed to create enhanced log (type: %s): %v", logType, err)
+		slog.WarnContext(c.Request.Context(), "Failed to create enhanced log", "type", logType, "error", err)
 		return err
 	}
 
 	return nil
 }
+
+// addAPIKeyMetadata attributes the log to the API key and service account that made the request
+func addAPIKeyMetadata(ctx context.Context, metadata map[string]interface{}) {
+	principal, ok := APIKeyPrincipalFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	metadata[sub_model.META_AUTH_METHOD] = "api_key"
+	metadata[sub_model.META_API_KEY_ID] = principal.KeyID
+	metadata[sub_model.META_API_KEY_NAME] = principal.KeyName
+	metadata[sub_model.META_SERVICE_ACCOUNT_ID] = principal.ServiceAccountID
+	metadata[sub_model.META_SERVICE_ACCOUNT_NAME] = principal.ServiceAccountName
+}
+
+// addRequestMetadata ties the log to the HTTP request it was written for
+func addRequestMetadata(ctx context.Context, metadata map[string]interface{}) {
+	info, ok := RequestInfoFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	metadata[sub_model.META_REQUEST_ID] = info.ID
+	metadata[sub_model.META_CLIENT_IP] = info.ClientIP
+	metadata[sub_model.META_USER_AGENT] = info.UserAgent
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PossibleMatchThreshold is the lowest similarity reported as a possible match
+const PossibleMatchThreshold = 0.85
+
+// nameSuffixes are dropped before comparing, so "Juan Dela Cruz Jr." matches "Juan Dela Cruz"
+var nameSuffixes = map[string]bool{
+	"jr": true, "sr": true, "ii": true, "iii": true, "iv": true,
+}
+
+// FoldName lowercases a name, strips diacritics and collapses spaces, keeping the word order
+// Used for search, where "jose" should find "José Dela Cruz"
+func FoldName(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+	return strings.Join(strings.Fields(strings.ToLower(stripped)), " ")
+}
+
+// NameHasPrefix checks if a folded name, or any word in it, starts with the folded prefix
+func NameHasPrefix(foldedName, foldedPrefix string) bool {
+	return strings.HasPrefix(foldedName, foldedPrefix) || strings.Contains(foldedName, " "+foldedPrefix)
+}
+
+// NormalizeName lowercases a name, strips diacritics, punctuation and suffixes, and sorts its words
+// "Cruz, José Dela Jr." and "jose dela cruz" both become "cruz dela jose"
+func NormalizeName(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+
+	tokens := strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !nameSuffixes[token] {
+			words = append(words, token)
+		}
+	}
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// NameCandidate is a known name that is similar to the one being matched
+type NameCandidate struct {
+	ID         string
+	Name       string
+	Confidence float64
+}
+
+// NameMatcher compares names against a fixed set, normalizing the set once
+type NameMatcher struct {
+	ids        []string
+	names      []string
+	normalized []string
+}
+
+// NewNameMatcher prepares a matcher over names keyed by ID
+func NewNameMatcher() *NameMatcher {
+	return &NameMatcher{}
+}
+
+// Add adds a known name
+func (m *NameMatcher) Add(id, name string) {
+	m.ids = append(m.ids, id)
+	m.names = append(m.names, name)
+	m.normalized = append(m.normalized, NormalizeName(name))
+}
+
+// Candidates returns the known names scoring at least the threshold, best first, at most limit
+func (m *NameMatcher) Candidates(name string, threshold float64, limit int) []NameCandidate {
+	target := NormalizeName(name)
+	candidates := []NameCandidate{}
+	for i, known := range m.normalized {
+		score := normalizedSimilarity(target, known)
+		if score >= threshold {
+			candidates = append(candidates, NameCandidate{ID: m.ids[i], Name: m.names[i], Confidence: score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// normalizedSimilarity compares two already normalized names
+func normalizedSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	score := 1 - float64(levenshtein(ra, rb))/float64(longest)
+
+	// A missing middle name or second given name shouldn't sink the score
+	if wordsSubset(a, b) || wordsSubset(b, a) {
+		wordsA, wordsB := len(strings.Fields(a)), len(strings.Fields(b))
+		shorter, longer := wordsA, wordsB
+		if shorter > longer {
+			shorter, longer = longer, shorter
+		}
+		if shorter >= 2 {
+			subsetScore := 0.85 + 0.1*float64(shorter)/float64(longer)
+			if subsetScore > score {
+				score = subsetScore
+			}
+		}
+	}
+
+	// Round so scores are stable in responses
+	return float64(int(score*100+0.5)) / 100
+}
+
+// wordsSubset checks if every word of a is in b
+func wordsSubset(a, b string) bool {
+	words := make(map[string]int)
+	for _, w := range strings.Fields(b) {
+		words[w]++
+	}
+	for _, w := range strings.Fields(a) {
+		if words[w] == 0 {
+			return false
+		}
+		words[w]--
+	}
+	return true
+}
+
+// levenshtein returns the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
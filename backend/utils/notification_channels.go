@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"sheduling-server/models"
+)
+
+// NotificationChannel delivers queued notifications of one channel
+type NotificationChannel interface {
+	Send(ctx context.Context, notification *models.Notification) error
+}
+
+// emailChannel sends notifications to the volunteer's address
+type emailChannel struct {
+	mailer Mailer
+}
+
+func (ch *emailChannel) Send(ctx context.Context, notification *models.Notification) error {
+	return ch.mailer.Send(ctx, notification.Recipient, notification.Subject, notification.Body)
+}
+
+// NotificationChannelsFromEnv creates the webhook channels that are configured
+//
+//   - NOTIFICATION_WEBHOOK_URL: receives every webhook notification as JSON
+//   - NOTIFICATION_WEBHOOK_SECRET: optional, signs the body as X-Signature-256: sha256=<hex HMAC>
+//   - NOTIFICATION_CHAT_WEBHOOK_URL: incoming webhook of a chat bot that takes {"text": ...},
+//     like Slack, Google Chat or Mattermost
+func NotificationChannelsFromEnv() (map[string]NotificationChannel, error) {
+	channels := map[string]NotificationChannel{}
+	if rawURL := strings.TrimSpace(os.Getenv("NOTIFICATION_WEBHOOK_URL")); rawURL != "" {
+		if err := validWebhookURL(rawURL); err != nil {
+			return nil, fmt.Errorf("invalid NOTIFICATION_WEBHOOK_URL: %v", err)
+		}
+		channels[models.NotificationWebhook] = &webhookChannel{
+			url:    rawURL,
+			secret: os.Getenv("NOTIFICATION_WEBHOOK_SECRET"),
+			client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	if rawURL := strings.TrimSpace(os.Getenv("NOTIFICATION_CHAT_WEBHOOK_URL")); rawURL != "" {
+		if err := validWebhookURL(rawURL); err != nil {
+			return nil, fmt.Errorf("invalid NOTIFICATION_CHAT_WEBHOOK_URL: %v", err)
+		}
+		channels[models.NotificationChat] = &chatChannel{
+			url:    rawURL,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	return channels, nil
+}
+
+func validWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("expected an http or https URL")
+	}
+	return nil
+}
+
+// webhookPayload is the JSON posted to the notification webhook
+type webhookPayload struct {
+	ID          string    `json:"id"` // the same on retries, receivers can drop duplicates with it
+	Kind        string    `json:"kind"`
+	EventID     string    `json:"eventId,omitempty"`
+	VolunteerID string    `json:"volunteerId,omitempty"`
+	Subject     string    `json:"subject"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// webhookChannel posts notifications as JSON, for other systems like SMS gateways to deliver
+type webhookChannel struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (ch *webhookChannel) Send(ctx context.Context, notification *models.Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		ID:          notification.ID,
+		Kind:        string(notification.Kind),
+		EventID:     notification.EventID,
+		VolunteerID: notification.VolunteerID,
+		Subject:     notification.Subject,
+		Body:        notification.Body,
+		CreatedAt:   notification.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"X-Notification-ID": notification.ID}
+	if ch.secret != "" {
+		mac := hmac.New(sha256.New, []byte(ch.secret))
+		mac.Write(body)
+		headers["X-Signature-256"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return postJSON(ctx, ch.client, ch.url, body, headers)
+}
+
+// chatChannel posts notifications as a chat message
+type chatChannel struct {
+	url    string
+	client *http.Client
+}
+
+func (ch *chatChannel) Send(ctx context.Context, notification *models.Notification) error {
+	body, err := json.Marshal(map[string]string{
+		"text": notification.Subject + "\n" + notification.Body,
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, ch.client, ch.url, body, nil)
+}
+
+// postJSON posts a JSON body and fails on any non 2xx status
+func postJSON(ctx context.Context, client *http.Client, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Without the URL, webhook URLs often carry their token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to post webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
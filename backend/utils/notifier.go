@@ -0,0 +1,468 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"sheduling-server/models"
+	"sheduling-server/repository"
+)
+
+const (
+	// notificationSendTimeout bounds one delivery on any channel
+	notificationSendTimeout = 2 * time.Minute
+	// notificationLease is how long a claimed notification is left to its instance, longer than notificationSendTimeout
+	notificationLease = 5 * time.Minute
+	// notificationMaxBackoff caps the wait between attempts
+	notificationMaxBackoff = 6 * time.Hour
+	// notifierStopTimeout is how long Stop waits for a delivery in progress to finish
+	notifierStopTimeout = 30 * time.Second
+)
+
+// Notifier queues notifications to volunteers and delivers them in the background on their channel
+// The queue lives in the database, so deliveries survive restarts and are shared between instances
+type Notifier struct {
+	db           repository.Database
+	channels     map[string]NotificationChannel
+	location     *time.Location
+	pollInterval time.Duration
+	maxAttempts  int
+	wake         chan struct{}
+	stopChan     chan struct{}
+	stopOnce     sync.Once
+	done         chan struct{} // closed when the delivery loop returns
+}
+
+// NotifierConfig provides configuration for the notifier
+type NotifierConfig struct {
+	Mailer       Mailer                         // schedule emails are off when nil
+	Channels     map[string]NotificationChannel // other channels by name, see NotificationChannelsFromEnv
+	Location     *time.Location                 // timezone of event times in emails, UTC when nil
+	PollInterval time.Duration                  // how often due notifications are looked for, 30 seconds by default
+	MaxAttempts  int                            // attempts before a notification is marked failed, 6 by default
+}
+
+// NewNotifier creates a notifier, call Start to deliver the queue
+func NewNotifier(db repository.Database, config NotifierConfig) *Notifier {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 30 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 6
+	}
+
+	channels := map[string]NotificationChannel{}
+	for name, channel := range config.Channels {
+		channels[name] = channel
+	}
+	if config.Mailer != nil {
+		channels[models.NotificationEmail] = &emailChannel{mailer: config.Mailer}
+	}
+
+	return &Notifier{
+		db:           db,
+		channels:     channels,
+		location:     config.Location,
+		pollInterval: config.PollInterval,
+		maxAttempts:  config.MaxAttempts,
+		wake:         make(chan struct{}, 1),
+		stopChan:     make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Enabled reports whether any channel is configured
+func (n *Notifier) Enabled() bool {
+	return len(n.channels) > 0
+}
+
+// HasChannel reports whether a channel is configured
+func (n *Notifier) HasChannel(name string) bool {
+	return n.channels[name] != nil
+}
+
+// Start delivers due notifications in the background until Stop
+func (n *Notifier) Start(ctx context.Context) {
+	if !n.Enabled() {
+		slog.Info("No notification channel configured, notifications are off")
+		return
+	}
+	if !n.HasChannel(models.NotificationEmail) {
+		slog.Info("SMTP_HOST not set, schedule emails are off")
+	}
+	slog.Info("Starting notifier", "channels", len(n.channels), "pollInterval", n.pollInterval.String(), "maxAttempts", n.maxAttempts)
+
+	go func() {
+		defer close(n.done)
+		ticker := time.NewTicker(n.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			n.deliverDue(ctx)
+			select {
+			case <-ticker.C:
+			case <-n.wake:
+			case <-n.stopChan:
+				slog.Info("Notifier stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the notifier, notifications still queued are sent after the next start
+// It waits up to notifierStopTimeout for a delivery in progress, an unfinished one is retried once its lease runs out
+func (n *Notifier) Stop() {
+	if !n.Enabled() {
+		return
+	}
+	n.stopOnce.Do(func() {
+		slog.Info("Stopping notifier")
+		close(n.stopChan)
+	})
+	select {
+	case <-n.done:
+	case <-time.After(notifierStopTimeout):
+		slog.Warn("Notifier did not stop in time", "timeout", notifierStopTimeout.String())
+	}
+}
+
+// NotifyAssigned tells volunteers they were scheduled for an event
+func (n *Notifier) NotifyAssigned(ctx context.Context, event *models.EventSchedule, volunteerIDs []string) {
+	if len(volunteerIDs) == 0 {
+		return
+	}
+	n.queue(ctx, models.NotifyAssigned, event, volunteerIDs, nil)
+}
+
+// NotifyDepartmentsAssigned tells the members of departments newly assigned to an event that they were scheduled
+// event is the event before the departments were added, its volunteers were already told and are left out
+func (n *Notifier) NotifyDepartmentsAssigned(ctx context.Context, event *models.EventSchedule, departmentIDs []string) {
+	if !n.HasChannel(models.NotificationEmail) || len(departmentIDs) == 0 || event.TimeAndDate.Before(time.Now()) {
+		return
+	}
+	snapshot := *event
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		already := map[string]bool{}
+		for _, volunteerID := range n.eventVolunteers(ctx, &snapshot) {
+			already[volunteerID] = true
+		}
+
+		volunteerIDs := []string{}
+		for _, volunteerID := range n.eventVolunteers(ctx, &models.EventSchedule{AssignedGroups: departmentIDs}) {
+			if !already[volunteerID] {
+				volunteerIDs = append(volunteerIDs, volunteerID)
+			}
+		}
+		if err := n.enqueue(ctx, models.NotifyAssigned, &snapshot, volunteerIDs, nil); err != nil {
+			slog.ErrorContext(ctx, "Failed to queue notifications", "kind", models.NotifyAssigned, "eventId", snapshot.ID, "error", err)
+		}
+	}()
+}
+
+// NotifyEventChanged tells everyone scheduled that the time or location of an event changed
+// oldTime and oldLocation are the values before the change, empty when they didn't change
+func (n *Notifier) NotifyEventChanged(ctx context.Context, event *models.EventSchedule, oldTime *time.Time, oldLocation *string) {
+	n.queue(ctx, models.NotifyEventChanged, event, nil, func(data *notificationData) {
+		if oldTime != nil {
+			data.OldTime = n.formatTime(*oldTime)
+		}
+		if oldLocation != nil {
+			data.OldLocation = *oldLocation
+			if data.OldLocation == "" {
+				data.OldLocation = "no location"
+			}
+		}
+	})
+}
+
+// NotifyEventCancelled tells everyone scheduled that an event was deleted
+func (n *Notifier) NotifyEventCancelled(ctx context.Context, event *models.EventSchedule) {
+	n.queue(ctx, models.NotifyEventCancelled, event, nil, nil)
+}
+
+// queue renders and stores the notifications in the background, so requests don't wait on the volunteer lookups
+// Without volunteer IDs everyone scheduled for the event is notified, nobody is for events that are over
+func (n *Notifier) queue(ctx context.Context, kind models.NotificationKind, event *models.EventSchedule, volunteerIDs []string, extra func(*notificationData)) {
+	if len(n.volunteerChannels()) == 0 || event.TimeAndDate.Before(time.Now()) {
+		return
+	}
+	snapshot := *event
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if volunteerIDs == nil {
+			volunteerIDs = n.eventVolunteers(ctx, &snapshot)
+		}
+		if err := n.enqueue(ctx, kind, &snapshot, volunteerIDs, extra); err != nil {
+			slog.ErrorContext(ctx, "Failed to queue notifications", "kind", kind, "eventId", snapshot.ID, "error", err)
+		}
+	}()
+}
+
+// enqueue stores one notification per volunteer on each configured volunteer channel
+// Volunteers who opted out or have no email address get a skipped email entry, so the delivery log shows why
+func (n *Notifier) enqueue(ctx context.Context, kind models.NotificationKind, event *models.EventSchedule, volunteerIDs []string, extra func(*notificationData)) error {
+	now := time.Now().UTC()
+	seen := map[string]bool{}
+	notifications := []*models.Notification{}
+
+	for _, volunteerID := range volunteerIDs {
+		if volunteerID == "" || seen[volunteerID] {
+			continue
+		}
+		seen[volunteerID] = true
+
+		volunteer, err := n.db.Volunteers().GetVolunteerByID(ctx, volunteerID)
+		if err != nil {
+			slog.WarnContext(ctx, "Volunteer to notify not found", "volunteerId", volunteerID, "error", err)
+			continue
+		}
+		if volunteer.IsDisabled {
+			continue
+		}
+
+		data := n.eventData(event)
+		data.VolunteerName = volunteer.Name
+		if extra != nil {
+			extra(&data)
+		}
+		subject, body, err := renderNotification(string(kind), data)
+		if err != nil {
+			return err
+		}
+
+		for _, channel := range n.volunteerChannels() {
+			notification := &models.Notification{
+				Kind:          kind,
+				Channel:       channel,
+				VolunteerID:   volunteerID,
+				EventID:       event.ID,
+				Subject:       subject,
+				Body:          body,
+				Status:        models.NotificationPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			}
+			if channel == models.NotificationEmail {
+				notification.Recipient = volunteer.Email
+				skipEmail(notification, volunteer)
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := n.db.Notifications().CreateNotifications(ctx, notifications); err != nil {
+		return err
+	}
+	n.wakeUp()
+	return nil
+}
+
+// volunteerChannels lists the configured channels that reach a single volunteer, chat only gets event summaries
+func (n *Notifier) volunteerChannels() []string {
+	channels := []string{}
+	for _, channel := range []string{models.NotificationEmail, models.NotificationWebhook} {
+		if n.HasChannel(channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// skipEmail marks an email skipped when the volunteer opted out or has no address
+func skipEmail(notification *models.Notification, volunteer *models.VolunteerModel) {
+	switch {
+	case volunteer.EmailOptOut:
+		notification.Status = models.NotificationSkipped
+		notification.LastError = "volunteer opted out of emails"
+	case volunteer.Email == "":
+		notification.Status = models.NotificationSkipped
+		notification.LastError = "volunteer has no email address"
+	}
+}
+
+// stopping reports whether Stop was called, so a long queue is not drained after it
+func (n *Notifier) stopping() bool {
+	select {
+	case <-n.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// wakeUp delivers newly queued notifications without waiting for the next poll
+func (n *Notifier) wakeUp() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// eventVolunteers lists everyone scheduled for an event, directly or as a member of an assigned department
+func (n *Notifier) eventVolunteers(ctx context.Context, event *models.EventSchedule) []string {
+	volunteerIDs := append([]string{}, event.ScheduledVolunteers...)
+	volunteerIDs = append(volunteerIDs, event.VoluntaryVolunteers...)
+	for _, status := range event.Statuses {
+		volunteerIDs = append(volunteerIDs, status.VolunteerID)
+	}
+	for _, departmentID := range event.AssignedGroups {
+		dept, err := n.db.Departments().GetByID(ctx, departmentID)
+		if err != nil {
+			slog.WarnContext(ctx, "Department to notify not found", "departmentId", departmentID, "error", err)
+			continue
+		}
+		for _, member := range dept.VolunteerMembers {
+			volunteerIDs = append(volunteerIDs, member.VolunteerID)
+		}
+	}
+	return volunteerIDs
+}
+
+// deliverDue sends due notifications until none are left
+// Each one is claimed just before it is sent, so its lease never runs out while others are being sent
+func (n *Notifier) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil && !n.stopping() {
+		claimed, err := n.db.Notifications().ClaimDueNotifications(ctx, time.Now().UTC(), notificationLease, 1)
+		if err != nil {
+			slog.Error("Failed to claim notifications", "error", err)
+		}
+		if len(claimed) == 0 {
+			return
+		}
+		n.deliver(ctx, claimed[0])
+	}
+}
+
+// deliver sends one notification and records the outcome, failures are retried with a growing wait
+func (n *Notifier) deliver(ctx context.Context, notification *models.Notification) {
+	err := fmt.Errorf("channel %s is not configured", notification.Channel)
+	if channel := n.channels[notification.Channel]; channel != nil {
+		sendCtx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+		err = channel.Send(sendCtx, notification)
+		cancel()
+	}
+	now := time.Now().UTC()
+	switch {
+	case err == nil:
+		notification.Status = models.NotificationSent
+		notification.SentAt = &now
+		notification.LastError = ""
+	case notification.Attempts >= n.maxAttempts:
+		notification.Status = models.NotificationFailed
+		notification.LastError = err.Error()
+		slog.Warn("Giving up on notification", "notificationId", notification.ID, "attempts", notification.Attempts, "error", err)
+	default:
+		notification.LastError = err.Error()
+		notification.NextAttemptAt = now.Add(notificationBackoff(notification.Attempts))
+		slog.Info("Notification failed, retrying later", "notificationId", notification.ID, "attempts", notification.Attempts, "error", err)
+	}
+
+	if err := n.db.Notifications().UpdateNotification(ctx, notification); err != nil {
+		slog.Error("Failed to save notification outcome", "notificationId", notification.ID, "error", err)
+	}
+}
+
+// notificationBackoff is the wait after a failed attempt: 1, 4, 16, 64 minutes and so on
+func notificationBackoff(attempts int) time.Duration {
+	wait := time.Minute
+	for i := 1; i < attempts && wait < notificationMaxBackoff; i++ {
+		wait *= 4
+	}
+	return min(wait, notificationMaxBackoff)
+}
+
+// notificationData is what the templates can use
+type notificationData struct {
+	VolunteerName string
+	EventName     string
+	EventTime     string
+	Location      string
+	OldTime       string // event_changed only, empty when the time didn't change
+	OldLocation   string // event_changed only, empty when the location didn't change
+	StartsIn      string // reminder only, e.g. "24 hours"
+	Departments   string // reminder only, the assigned departments the volunteer heads, all of them in the summary
+	Scheduled     int    // reminder summary only, volunteers scheduled directly
+}
+
+func (n *Notifier) eventData(event *models.EventSchedule) notificationData {
+	data := notificationData{
+		EventName: event.Name,
+		EventTime: n.formatTime(event.TimeAndDate),
+	}
+	if event.Location != nil {
+		data.Location = event.Location.Address
+	}
+	return data
+}
+
+func (n *Notifier) formatTime(t time.Time) string {
+	return t.In(n.location).Format("Monday, January 2, 2006 at 3:04 PM MST")
+}
+
+// notificationTemplates has a "<kind>.subject" and "<kind>.body" template for each kind,
+// and "reminder_summary" for the reminder posted to the chat channel
+var notificationTemplates = template.Must(template.New("notifications").Parse(`
+{{define "assigned.subject"}}You're scheduled for {{.EventName}}{{end}}
+{{define "assigned.body"}}Hi {{.VolunteerName}},
+
+You have been scheduled for {{.EventName}} on {{.EventTime}}{{with .Location}} at {{.}}{{end}}.
+
+See you there!
+{{end}}
+
+{{define "event_changed.subject"}}{{.EventName}} has changed{{end}}
+{{define "event_changed.body"}}Hi {{.VolunteerName}},
+
+{{.EventName}}, which you are scheduled for, has changed.
+{{with .OldTime}}
+It was on {{.}}, it is now on {{$.EventTime}}.{{end}}{{with .OldLocation}}
+It was at {{.}}, it is now at {{or $.Location "no location"}}.{{end}}
+{{end}}
+
+{{define "event_cancelled.subject"}}{{.EventName}} is cancelled{{end}}
+{{define "event_cancelled.body"}}Hi {{.VolunteerName}},
+
+{{.EventName}} on {{.EventTime}}, which you were scheduled for, has been cancelled.
+{{end}}
+
+{{define "reminder.subject"}}Reminder: {{.EventName}} starts in {{.StartsIn}}{{end}}
+{{define "reminder.body"}}Hi {{.VolunteerName}},
+
+This is a reminder that {{.EventName}} starts in {{.StartsIn}}, on {{.EventTime}}{{with .Location}} at {{.}}{{end}}.
+{{with .Departments}}
+As head of {{.}}, please make sure your team is ready.
+{{end}}
+See you there!
+{{end}}
+
+{{define "reminder_summary.subject"}}Reminder: {{.EventName}} starts in {{.StartsIn}}{{end}}
+{{define "reminder_summary.body"}}{{.EventName}} starts on {{.EventTime}}{{with .Location}} at {{.}}{{end}}.
+{{.Scheduled}} volunteers are scheduled{{with .Departments}}, assigned departments: {{.}}{{end}}.
+{{end}}
+`))
+
+// renderNotification renders the subject and body of a notification from the templates with that name
+func renderNotification(name string, data notificationData) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := notificationTemplates.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return "", "", err
+	}
+	if err := notificationTemplates.ExecuteTemplate(&body, name+".body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()) + "\n", nil
+}
@@ -1,23 +1,196 @@
 package utils
 
 import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"os"
+	"sort"
+	"strings"
 
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	sub_model "sheduling-server/models/sub_models"
 )
 
-var GoogleOAuthConfig *oauth2.Config
+// oauthProviders holds every configured provider keyed by name
+var oauthProviders = map[sub_model.OAuthProvider]OAuthProvider{}
 
-func InitGoogleOAuth() {
-	GoogleOAuthConfig = &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+// allowedEmailDomains restricts which emails can be invited, empty allows any domain
+var allowedEmailDomains = []string{}
+
+// InitOAuthProviders registers the OAuth/OIDC providers configured through the environment
+// and the optional OAUTH_PROVIDERS_FILE (a JSON array of OAuthProviderConfig)
+//
+// Environment presets:
+//   - GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET, GOOGLE_REDIRECT_URL
+//   - MICROSOFT_CLIENT_ID, MICROSOFT_CLIENT_SECRET, MICROSOFT_REDIRECT_URL, MICROSOFT_TENANT_ID (default "common")
+//   - OIDC_NAME, OIDC_DISPLAY_NAME, OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL
+//
+// OAUTH_ALLOWED_EMAIL_DOMAINS is a comma separated list (e.g. "school.edu,org.school.edu")
+// limiting which emails can be invited and onboarded
+func InitOAuthProviders() {
+	allowedEmailDomains = []string{}
+	for _, domain := range strings.Split(os.Getenv("OAUTH_ALLOWED_EMAIL_DOMAINS"), ",") {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" {
+			allowedEmailDomains = append(allowedEmailDomains, domain)
+		}
+	}
+	if len(allowedEmailDomains) > 0 {
+		slog.Info("OAuth onboarding restricted to email domains", "domains", allowedEmailDomains)
+	}
+
+	configs := []OAuthProviderConfig{}
+
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		configs = append(configs, OAuthProviderConfig{
+			Name:         string(sub_model.Google),
+			Type:         "google",
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+		})
+	}
+
+	if clientID := os.Getenv("MICROSOFT_CLIENT_ID"); clientID != "" {
+		configs = append(configs, OAuthProviderConfig{
+			Name:         string(sub_model.Microsoft),
+			Type:         "microsoft",
+			Tenant:       os.Getenv("MICROSOFT_TENANT_ID"),
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("MICROSOFT_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("MICROSOFT_REDIRECT_URL"),
+		})
+	}
+
+	if clientID := os.Getenv("OIDC_CLIENT_ID"); clientID != "" {
+		configs = append(configs, OAuthProviderConfig{
+			Name:         os.Getenv("OIDC_NAME"),
+			Type:         "oidc",
+			DisplayName:  os.Getenv("OIDC_DISPLAY_NAME"),
+			Issuer:       os.Getenv("OIDC_ISSUER"),
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		})
+	}
+
+	if path := os.Getenv("OAUTH_PROVIDERS_FILE"); path != "" {
+		fileConfigs, err := loadOAuthProviderFile(path)
+		if err != nil {
+			slog.Warn("Failed to load OAuth providers", "path", path, "error", err)
+		}
+		configs = append(configs, fileConfigs...)
+	}
+
+	oauthProviders = map[sub_model.OAuthProvider]OAuthProvider{}
+	for _, config := range configs {
+		provider, err := newProviderFromConfig(config)
+		if err != nil {
+			slog.Warn("Skipping OAuth provider", "provider", config.Name, "error", err)
+			continue
+		}
+		RegisterOAuthProvider(provider)
+	}
+
+	slog.Info("OAuth providers enabled", "providers", OAuthProviderNames())
+}
+
+// IsEmailDomainAllowed checks an email against OAUTH_ALLOWED_EMAIL_DOMAINS
+func IsEmailDomainAllowed(email string) bool {
+	if len(allowedEmailDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range allowedEmailDomains {
+		if domain == allowed {
+			return true
+		}
 	}
+	return false
+}
+
+// RegisterOAuthProvider adds (or replaces) a provider in the registry
+func RegisterOAuthProvider(provider OAuthProvider) {
+	oauthProviders[provider.Name()] = provider
+}
+
+// GetOAuthProvider looks up a configured provider by name
+func GetOAuthProvider(name string) (OAuthProvider, bool) {
+	provider, ok := oauthProviders[sub_model.OAuthProvider(name)]
+	return provider, ok
+}
+
+// ListOAuthProviders returns all configured providers sorted by name
+func ListOAuthProviders() []OAuthProvider {
+	providers := make([]OAuthProvider, 0, len(oauthProviders))
+	for _, provider := range oauthProviders {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name() < providers[j].Name()
+	})
+	return providers
+}
+
+// OAuthProviderNames returns the names of all configured providers
+func OAuthProviderNames() []string {
+	names := []string{}
+	for _, provider := range ListOAuthProviders() {
+		names = append(names, string(provider.Name()))
+	}
+	return names
+}
+
+// newProviderFromConfig applies the google/microsoft presets before building the OIDC provider
+func newProviderFromConfig(config OAuthProviderConfig) (OAuthProvider, error) {
+	switch config.Type {
+	case "google":
+		if config.Issuer == "" {
+			config.Issuer = "https://accounts.google.com"
+		}
+		config.AdditionalIssuers = append(config.AdditionalIssuers, "accounts.google.com")
+		if config.DisplayName == "" {
+			config.DisplayName = "Google"
+		}
+	case "microsoft":
+		tenant := config.Tenant
+		if tenant == "" {
+			tenant = "common"
+		}
+		if config.Issuer == "" {
+			config.Issuer = fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0", tenant)
+		}
+		// Emails are managed by the organization when signing in to a specific tenant
+		if tenant != "common" && tenant != "organizations" && tenant != "consumers" {
+			config.AssumeEmailVerified = true
+		}
+		if config.DisplayName == "" {
+			config.DisplayName = "Microsoft"
+		}
+	case "oidc", "":
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", config.Type)
+	}
+
+	return NewOIDCProvider(config)
+}
+
+// loadOAuthProviderFile reads provider configs from a JSON file
+func loadOAuthProviderFile(path string) ([]OAuthProviderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []OAuthProviderConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid provider config: %w", err)
+	}
+
+	return configs, nil
 }
@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	sub_model "sheduling-server/models/sub_models"
+
+	"github.com/MicahParks/keyfunc"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+// OAuthUserInfo is the identity returned by a provider after a successful code exchange
+type OAuthUserInfo struct {
+	Subject       string // stable user ID at the provider ("sub" claim)
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthProvider is a third-party identity provider that can be used for login and account linking
+type OAuthProvider interface {
+	// Name is the provider key used in routes and stored on the linked accounts of AuthUser
+	Name() sub_model.OAuthProvider
+	// DisplayName is a human readable label for the login button
+	DisplayName() string
+	// AuthCodeURL builds the URL the user is redirected to for consent
+	AuthCodeURL(ctx context.Context, state string, opts ...oauth2.AuthCodeOption) (string, error)
+	// Exchange trades an authorization code for tokens
+	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
+	// UserInfo verifies the token response and extracts the user's identity
+	// nonce must match the nonce sent in the auth request, pass "" to skip the check
+	UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*OAuthUserInfo, error)
+}
+
+// OAuthProviderConfig configures a generic OpenID Connect provider
+type OAuthProviderConfig struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"` // "google", "microsoft" or "oidc"
+	DisplayName  string   `json:"displayName"`
+	Issuer       string   `json:"issuer"`
+	DiscoveryURL string   `json:"discoveryUrl,omitempty"` // defaults to {issuer}/.well-known/openid-configuration
+	Tenant       string   `json:"tenant,omitempty"`       // microsoft only, defaults to "common"
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes,omitempty"`
+	// AdditionalIssuers are extra accepted "iss" values (Google also issues "accounts.google.com")
+	AdditionalIssuers []string `json:"additionalIssuers,omitempty"`
+	// AssumeEmailVerified trusts the email claim even when the provider does not send email_verified
+	// (only enable this for single-tenant providers that manage their users' emails)
+	AssumeEmailVerified bool `json:"assumeEmailVerified,omitempty"`
+
+	// HTTPClient is used for discovery, JWKS and token requests (defaults to a client with a 10s timeout)
+	HTTPClient *http.Client `json:"-"`
+}
+
+// oidcDiscovery holds the fields we need from the provider's discovery document
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider implements OAuthProvider for any OpenID Connect compliant identity provider
+// Discovery and the JWKS are loaded lazily on first use and then cached
+type oidcProvider struct {
+	config OAuthProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	oauth     *oauth2.Config
+	jwks      *keyfunc.JWKS
+}
+
+// NewOIDCProvider creates a provider from an OpenID Connect configuration
+func NewOIDCProvider(config OAuthProviderConfig) (OAuthProvider, error) {
+	if config.Name == "" {
+		return nil, errors.New("oauth provider name is required")
+	}
+	if config.Issuer == "" && config.DiscoveryURL == "" {
+		return nil, fmt.Errorf("oauth provider %s: issuer or discoveryUrl is required", config.Name)
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("oauth provider %s: clientId is required", config.Name)
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &oidcProvider{config: config, client: client}, nil
+}
+
+func (p *oidcProvider) Name() sub_model.OAuthProvider {
+	return sub_model.OAuthProvider(p.config.Name)
+}
+
+func (p *oidcProvider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state string, opts ...oauth2.AuthCodeOption) (string, error) {
+	if err := p.init(ctx); err != nil {
+		return "", err
+	}
+	return p.oauth.AuthCodeURL(state, opts...), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	if err := p.init(ctx); err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	return p.oauth.Exchange(ctx, code, opts...)
+}
+
+func (p *oidcProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*OAuthUserInfo, error) {
+	if err := p.init(ctx); err != nil {
+		return nil, err
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, errors.New("provider did not return an id_token")
+	}
+
+	claims, err := p.verifyIDToken(rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &OAuthUserInfo{
+		Subject: stringClaim(claims, "sub"),
+		Email:   stringClaim(claims, "email"),
+		Name:    stringClaim(claims, "name"),
+	}
+	info.EmailVerified = boolClaim(claims, "email_verified") || boolClaim(claims, "xms_edov")
+
+	// Some providers (e.g. Microsoft work accounts) only put the email in preferred_username
+	if info.Email == "" && strings.Contains(stringClaim(claims, "preferred_username"), "@") {
+		info.Email = stringClaim(claims, "preferred_username")
+	}
+
+	// Fall back to the userinfo endpoint when the ID token is missing profile claims
+	if info.Email == "" && p.discovery.UserInfoEndpoint != "" {
+		if err := p.fetchUserInfo(ctx, token, info); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.Email == "" {
+		return nil, errors.New("provider did not return an email address")
+	}
+	if p.config.AssumeEmailVerified {
+		info.EmailVerified = true
+	}
+	info.Email = strings.ToLower(strings.TrimSpace(info.Email))
+
+	return info, nil
+}
+
+// init loads the discovery document and JWKS once
+func (p *oidcProvider) init(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return nil
+	}
+
+	discoveryURL := p.config.DiscoveryURL
+	if discoveryURL == "" {
+		discoveryURL = strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build discovery request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch discovery document for %s: %w", p.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("discovery document for %s returned status %d", p.config.Name, resp.StatusCode)
+	}
+
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return fmt.Errorf("failed to decode discovery document for %s: %w", p.config.Name, err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return fmt.Errorf("discovery document for %s is missing required endpoints", p.config.Name)
+	}
+	if discovery.Issuer == "" {
+		discovery.Issuer = p.config.Issuer
+	}
+
+	jwks, err := keyfunc.Get(discovery.JWKSURI, keyfunc.Options{
+		Client:            p.client,
+		RefreshInterval:   time.Hour,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshUnknownKID: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS for %s: %w", p.config.Name, err)
+	}
+
+	p.discovery = &discovery
+	p.jwks = jwks
+	p.oauth = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}
+
+	return nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *oidcProvider) verifyIDToken(rawIDToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(rawIDToken, claims, p.jwks.Keyfunc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid id_token")
+	}
+
+	// Multi-tenant issuers (Microsoft "common") use a {tenantid} placeholder in the discovery document
+	issuer := stringClaim(claims, "iss")
+	validIssuer := issuer == strings.ReplaceAll(p.discovery.Issuer, "{tenantid}", stringClaim(claims, "tid"))
+	for _, additional := range p.config.AdditionalIssuers {
+		validIssuer = validIssuer || issuer == additional
+	}
+	if !validIssuer {
+		return nil, fmt.Errorf("id_token issuer mismatch: %s", issuer)
+	}
+
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("id_token audience mismatch")
+	}
+	if _, hasExp := claims["exp"]; !hasExp {
+		return nil, errors.New("id_token has no expiry")
+	}
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(stringClaim(claims, "nonce")), []byte(nonce)) != 1 {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// fetchUserInfo fills missing profile fields from the userinfo endpoint
+func (p *oidcProvider) fetchUserInfo(ctx context.Context, token *oauth2.Token, info *OAuthUserInfo) error {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	resp, err := p.oauth.Client(ctx, token).Get(p.discovery.UserInfoEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to get user info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("userinfo endpoint returned status %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return fmt.Errorf("failed to decode user info: %w", err)
+	}
+
+	// The userinfo subject must match the ID token subject
+	if sub := stringClaim(claims, "sub"); sub != "" && sub != info.Subject {
+		return errors.New("userinfo subject does not match id_token")
+	}
+
+	info.Email = stringClaim(claims, "email")
+	info.EmailVerified = info.EmailVerified || boolClaim(claims, "email_verified")
+	if info.Name == "" {
+		info.Name = stringClaim(claims, "name")
+	}
+
+	return nil
+}
+
+func stringClaim(claims map[string]interface{}, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim, some providers send it as a string
+func boolClaim(claims map[string]interface{}, key string) bool {
+	switch value := claims[key].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"sheduling-server/models"
+	"sheduling-server/repository"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuthStateTTL is how long a user has to finish the provider's consent screen
+const OAuthStateTTL = 10 * time.Minute
+
+// NewOAuthState creates a login attempt with a fresh state, PKCE verifier and nonce
+func NewOAuthState(provider string, purpose models.OAuthStatePurpose, userID string) *models.OAuthState {
+	now := time.Now().UTC()
+	return &models.OAuthState{
+		ID:           GenerateRandomString(32),
+		Provider:     provider,
+		Purpose:      purpose,
+		UserID:       userID,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        GenerateRandomString(16),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(OAuthStateTTL),
+	}
+}
+
+// AuthURLOptions returns the PKCE challenge and nonce parameters for the auth request
+func AuthURLOptions(state *models.OAuthState) []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{
+		oauth2.AccessTypeOffline,
+		oauth2.S256ChallengeOption(state.CodeVerifier),
+		oauth2.SetAuthURLParam("nonce", state.Nonce),
+	}
+}
+
+// ValidateOAuthState checks that a consumed state belongs to this provider, purpose and user
+func ValidateOAuthState(state *models.OAuthState, provider string, purpose models.OAuthStatePurpose, userID string) error {
+	if time.Now().UTC().After(state.ExpiresAt) {
+		return errors.New("state_expired")
+	}
+	if state.Provider != provider {
+		return errors.New("state_provider_mismatch")
+	}
+	if state.Purpose != purpose {
+		return errors.New("state_purpose_mismatch")
+	}
+	if state.UserID != userID {
+		return errors.New("state_user_mismatch")
+	}
+	return nil
+}
+
+// StartOAuthStateCleanup periodically deletes login attempts that were never completed
+func StartOAuthStateCleanup(ctx context.Context, repo repository.OAuthStateRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := repo.DeleteExpiredStates(ctx, time.Now().UTC())
+				if err != nil {
+					slog.Warn("Failed to clean up expired OAuth states", "error", err)
+				} else if deleted > 0 {
+					slog.Info("Deleted expired OAuth states", "deleted", deleted)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,399 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"sheduling-server/models"
+	sub_model "sheduling-server/models/sub_models"
+	"sheduling-server/repository"
+)
+
+// reminderJobLease is how long a claimed reminder job is left to its instance before another may take it over
+const reminderJobLease = 5 * time.Minute
+
+// ReminderScheduler queues reminders before events to the scheduled volunteers and the heads of the assigned departments
+// Each reminder is claimed as a job in the database, so restarts and other instances never queue it twice
+type ReminderScheduler struct {
+	db       repository.Database
+	notifier *Notifier
+	offsets  []time.Duration // largest first
+	channels []string
+	interval time.Duration
+	stopChan chan bool
+}
+
+// ReminderSchedulerConfig provides configuration for the reminder scheduler
+type ReminderSchedulerConfig struct {
+	Offsets  []time.Duration // how long before an event reminders go out, 24 hours and 1 hour by default
+	Channels []string        // channels reminders go out on, every channel of the notifier when empty
+	Interval time.Duration   // how often upcoming events are checked, 5 minutes by default
+}
+
+// ReminderSchedulerConfigFromEnv reads the reminder configuration
+//
+//   - REMINDER_OFFSETS: comma separated durations before each event, "24h,1h" by default
+//   - REMINDER_CHANNELS: comma separated channels (email, webhook, chat), every configured one by default
+func ReminderSchedulerConfigFromEnv() (ReminderSchedulerConfig, error) {
+	config := ReminderSchedulerConfig{}
+	rawOffsets := strings.TrimSpace(os.Getenv("REMINDER_OFFSETS"))
+	if rawOffsets == "" {
+		rawOffsets = "24h,1h"
+	}
+	for _, raw := range strings.Split(rawOffsets, ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || offset <= 0 {
+			return config, fmt.Errorf("invalid REMINDER_OFFSETS: %q is not a positive duration", raw)
+		}
+		config.Offsets = append(config.Offsets, offset)
+	}
+	for _, raw := range strings.Split(os.Getenv("REMINDER_CHANNELS"), ",") {
+		channel := strings.ToLower(strings.TrimSpace(raw))
+		switch channel {
+		case "":
+		case models.NotificationEmail, models.NotificationWebhook, models.NotificationChat:
+			config.Channels = append(config.Channels, channel)
+		default:
+			return config, fmt.Errorf("invalid REMINDER_CHANNELS: unknown channel %q", channel)
+		}
+	}
+	return config, nil
+}
+
+// NewReminderScheduler creates a reminder scheduler, reminders are delivered by the notifier
+// Channels the notifier doesn't have are left out
+func NewReminderScheduler(db repository.Database, notifier *Notifier, config ReminderSchedulerConfig) *ReminderScheduler {
+	offsets := []time.Duration{}
+	for _, offset := range config.Offsets {
+		if offset > 0 {
+			offsets = append(offsets, offset)
+		}
+	}
+	if len(offsets) == 0 {
+		offsets = []time.Duration{24 * time.Hour, time.Hour}
+	}
+	slices.Sort(offsets)
+	slices.Reverse(offsets)
+	offsets = slices.Compact(offsets)
+
+	requested := len(config.Channels) > 0
+	if !requested {
+		config.Channels = []string{models.NotificationEmail, models.NotificationWebhook, models.NotificationChat}
+	}
+	channels := []string{}
+	for _, channel := range config.Channels {
+		if slices.Contains(channels, channel) {
+			continue
+		}
+		if notifier.HasChannel(channel) {
+			channels = append(channels, channel)
+		} else if requested {
+			slog.Warn("Reminder channel is not configured, reminders won't go out on it", "channel", channel)
+		}
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Minute
+	}
+
+	return &ReminderScheduler{
+		db:       db,
+		notifier: notifier,
+		offsets:  offsets,
+		channels: channels,
+		interval: config.Interval,
+		stopChan: make(chan bool),
+	}
+}
+
+// Start checks for due reminders in the background until Stop
+func (rs *ReminderScheduler) Start(ctx context.Context) {
+	if len(rs.channels) == 0 {
+		slog.Info("No notification channel for reminders, event reminders are off")
+		return
+	}
+	offsets := make([]string, len(rs.offsets))
+	for i, offset := range rs.offsets {
+		offsets[i] = offset.String()
+	}
+	slog.Info("Starting reminder scheduler", "offsets", strings.Join(offsets, ","), "channels", strings.Join(rs.channels, ","), "interval", rs.interval.String())
+
+	go func() {
+		ticker := time.NewTicker(rs.interval)
+		defer ticker.Stop()
+
+		for {
+			rs.run(ctx)
+			select {
+			case <-ticker.C:
+			case <-rs.stopChan:
+				slog.Info("Reminder scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the reminder scheduler, reminders that came due meanwhile are queued after the next start
+func (rs *ReminderScheduler) Stop() {
+	if len(rs.channels) == 0 {
+		return
+	}
+	slog.Info("Stopping reminder scheduler")
+	rs.stopChan <- true
+}
+
+// run queues the due reminders of every upcoming event
+func (rs *ReminderScheduler) run(ctx context.Context) {
+	now := time.Now().UTC()
+	until := now.Add(rs.offsets[0])
+	disabled := false
+	page, err := rs.db.EventSchedules().QueryEvents(ctx, repository.EventQuery{
+		IsDisabled: &disabled,
+		From:       &now,
+		To:         &until,
+	})
+	if err != nil {
+		slog.Error("Failed to look up upcoming events for reminders", "error", err)
+		return
+	}
+
+	for _, event := range page.Items {
+		offset, ok := rs.dueOffset(event.TimeAndDate.Sub(now))
+		if !ok {
+			continue
+		}
+		if err := rs.remind(ctx, event, offset, now); err != nil {
+			slog.Error("Failed to queue event reminders", "eventId", event.ID, "offset", offset.String(), "error", err)
+		}
+	}
+}
+
+// dueOffset is the smallest offset already reached, false once that was more than one interval ago
+// An offset is only sent in the run that reaches it, so an event added or moved after an offset passed,
+// or a run missed while no instance was running, gets no reminder for it rather than a late one
+func (rs *ReminderScheduler) dueOffset(startsIn time.Duration) (time.Duration, bool) {
+	due := rs.offsets[0]
+	for _, offset := range rs.offsets {
+		if startsIn <= offset {
+			due = offset
+		}
+	}
+	return due, startsIn > due-rs.interval
+}
+
+// remind claims the reminder job of an event and offset and queues its notifications
+// A job that fails is taken over by a later run once its lease passes, notifications it already queued are not queued again
+func (rs *ReminderScheduler) remind(ctx context.Context, event *models.EventSchedule, offset time.Duration, now time.Time) error {
+	job := &models.ReminderJob{
+		ID:        models.ReminderJobID(event.ID, event.TimeAndDate, offset),
+		EventID:   event.ID,
+		EventTime: event.TimeAndDate,
+		Offset:    offset.String(),
+	}
+	claimed, err := rs.db.ReminderJobs().ClaimReminderJob(ctx, job, now, reminderJobLease)
+	if err != nil || !claimed {
+		return err
+	}
+
+	queued, err := rs.queueReminders(ctx, job, event)
+	if err != nil {
+		return err
+	}
+	if err := rs.db.ReminderJobs().CompleteReminderJob(ctx, job.ID, queued); err != nil {
+		return err
+	}
+	if queued > 0 {
+		rs.notifier.wakeUp()
+	}
+	slog.Info("Queued event reminders", "eventId", event.ID, "offset", job.Offset, "queued", queued)
+	return nil
+}
+
+// queueReminders stores the reminders of a job, each under an ID derived from the job so none is stored twice
+// Email and webhook reminders go to each volunteer, the chat channel gets one summary of the event
+func (rs *ReminderScheduler) queueReminders(ctx context.Context, job *models.ReminderJob, event *models.EventSchedule) (int, error) {
+	recipients, departments, err := rs.recipients(ctx, event)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	queued := 0
+	queue := func(notification *models.Notification) error {
+		notification.Kind = models.NotifyReminder
+		notification.EventID = event.ID
+		if notification.Status == "" {
+			notification.Status = models.NotificationPending
+		}
+		notification.NextAttemptAt = now
+		notification.CreatedAt = now
+		created, err := rs.db.Notifications().CreateNotificationOnce(ctx, notification)
+		if created {
+			queued++
+		}
+		return err
+	}
+
+	data := rs.notifier.eventData(event)
+	data.StartsIn = formatStartsIn(roundStartsIn(event.TimeAndDate.Sub(now)))
+
+	for _, recipient := range recipients {
+		volunteerData := data
+		volunteerData.VolunteerName = recipient.volunteer.Name
+		volunteerData.Departments = strings.Join(recipient.departments, ", ")
+		subject, body, err := renderNotification(string(models.NotifyReminder), volunteerData)
+		if err != nil {
+			return queued, err
+		}
+
+		for _, channel := range rs.channels {
+			if channel == models.NotificationChat {
+				continue
+			}
+			notification := &models.Notification{
+				ID:          fmt.Sprintf("%s_%s_%s", job.ID, channel, recipient.volunteer.ID),
+				Channel:     channel,
+				VolunteerID: recipient.volunteer.ID,
+				Subject:     subject,
+				Body:        body,
+			}
+			if channel == models.NotificationEmail {
+				notification.Recipient = recipient.volunteer.Email
+				skipEmail(notification, recipient.volunteer)
+			}
+			if err := queue(notification); err != nil {
+				return queued, err
+			}
+		}
+	}
+
+	if slices.Contains(rs.channels, models.NotificationChat) {
+		summary := data
+		summary.Departments = strings.Join(departments, ", ")
+		for _, recipient := range recipients {
+			if recipient.scheduled {
+				summary.Scheduled++
+			}
+		}
+		subject, body, err := renderNotification("reminder_summary", summary)
+		if err != nil {
+			return queued, err
+		}
+		err = queue(&models.Notification{
+			ID:      fmt.Sprintf("%s_%s", job.ID, models.NotificationChat),
+			Channel: models.NotificationChat,
+			Subject: subject,
+			Body:    body,
+		})
+		if err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// reminderRecipient is a volunteer to remind
+type reminderRecipient struct {
+	volunteer   *models.VolunteerModel
+	scheduled   bool     // scheduled for the event directly
+	departments []string // names of the assigned departments they head
+}
+
+// recipients lists the volunteers scheduled for an event and the heads of its assigned departments,
+// along with the names of the assigned departments
+func (rs *ReminderScheduler) recipients(ctx context.Context, event *models.EventSchedule) ([]*reminderRecipient, []string, error) {
+	volunteerIDs := []string{}
+	byID := map[string]*reminderRecipient{}
+	add := func(volunteerID string) *reminderRecipient {
+		recipient, ok := byID[volunteerID]
+		if !ok {
+			recipient = &reminderRecipient{}
+			byID[volunteerID] = recipient
+			volunteerIDs = append(volunteerIDs, volunteerID)
+		}
+		return recipient
+	}
+
+	scheduled := append([]string{}, event.ScheduledVolunteers...)
+	scheduled = append(scheduled, event.VoluntaryVolunteers...)
+	for _, status := range event.Statuses {
+		scheduled = append(scheduled, status.VolunteerID)
+	}
+	for _, volunteerID := range scheduled {
+		if volunteerID != "" {
+			add(volunteerID).scheduled = true
+		}
+	}
+
+	departments := []string{}
+	for _, departmentID := range event.AssignedGroups {
+		dept, err := rs.db.Departments().GetByID(ctx, departmentID)
+		if err != nil {
+			slog.WarnContext(ctx, "Department to remind not found", "departmentId", departmentID, "error", err)
+			continue
+		}
+		if dept.IsDisabled {
+			continue
+		}
+		departments = append(departments, dept.DepartmentName)
+		for _, member := range dept.VolunteerMembers {
+			if member.MembershipType == sub_model.HEAD && member.VolunteerID != "" {
+				recipient := add(member.VolunteerID)
+				recipient.departments = append(recipient.departments, dept.DepartmentName)
+			}
+		}
+	}
+
+	recipients := []*reminderRecipient{}
+	for _, volunteerID := range volunteerIDs {
+		volunteer, err := rs.db.Volunteers().GetVolunteerByID(ctx, volunteerID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, err
+			}
+			slog.WarnContext(ctx, "Volunteer to remind not found", "volunteerId", volunteerID, "error", err)
+			continue
+		}
+		if volunteer.IsDisabled {
+			continue
+		}
+		recipient := byID[volunteerID]
+		recipient.volunteer = volunteer
+		recipients = append(recipients, recipient)
+	}
+	return recipients, departments, nil
+}
+
+// roundStartsIn rounds the time left before an event to whole hours from 2 hours on and to minutes below
+func roundStartsIn(startsIn time.Duration) time.Duration {
+	if startsIn >= 2*time.Hour {
+		return startsIn.Round(time.Hour)
+	}
+	return max(startsIn.Round(time.Minute), time.Minute)
+}
+
+// formatStartsIn reads an offset like "24 hours", "1 hour" or "90 minutes"
+func formatStartsIn(offset time.Duration) string {
+	count, unit := int64(offset/time.Minute), "minute"
+	switch {
+	case offset >= 24*time.Hour && offset%(24*time.Hour) == 0:
+		count, unit = int64(offset/(24*time.Hour)), "day"
+		if count == 1 {
+			count, unit = 24, "hour"
+		}
+	case offset >= time.Hour && offset%time.Hour == 0:
+		count, unit = int64(offset/time.Hour), "hour"
+	case offset < time.Minute:
+		return offset.String()
+	}
+	if count != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", count, unit)
+}
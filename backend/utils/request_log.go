@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// RequestInfo identifies the HTTP request an action came from, so logs and audit entries can be tied together
+type RequestInfo struct {
+	ID        string
+	ClientIP  string
+	UserAgent string
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo stores the request info in the request context
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request info if the context belongs to an HTTP request
+func RequestInfoFromContext(ctx context.Context) (*RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info, ok && info != nil
+}
+
+// InitLogging sets the default slog logger from the environment, the standard log package writes through it too
+//
+//   - LOG_LEVEL: debug, info, warn or error, defaults to info
+//   - LOG_FORMAT: json or text, defaults to json
+func InitLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(os.Getenv("LOG_LEVEL")))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, options)
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		handler = slog.NewTextHandler(os.Stdout, options)
+	}
+	slog.SetDefault(slog.New(&requestLogHandler{Handler: handler}))
+}
+
+// requestLogHandler adds the request ID to every record logged with a request context
+type requestLogHandler struct {
+	slog.Handler
+}
+
+func (h *requestLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info, ok := RequestInfoFromContext(ctx); ok {
+		record.AddAttrs(slog.String("requestId", info.ID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *requestLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestLogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestLogHandler) WithGroup(name string) slog.Handler {
+	return &requestLogHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -3,7 +3,7 @@ package utils
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"sheduling-server/models"
@@ -16,7 +16,10 @@ import (
 // RetentionScheduler manages automatic log archival based on retention policies
 type RetentionScheduler struct {
 	repo           repository.LogRepository
+	bundles        repository.LogBundleRepository
+	rules          repository.RetentionRuleRepository
 	retentionDays  int
+	purgeAfterDays int
 	ticker         *time.Ticker
 	stopChan       chan bool
 	runImmediately bool
@@ -24,8 +27,16 @@ type RetentionScheduler struct {
 
 // RetentionSchedulerConfig provides configuration for the retention scheduler
 type RetentionSchedulerConfig struct {
-	RetentionDays  int  // Number of days to keep logs before archiving
+	RetentionDays  int  // Number of days to keep logs before archiving, for logs no retention rule matches
 	RunImmediately bool // If true, runs archival immediately on start (for testing)
+
+	// Retention by category, type and severity, editable by admins, only RetentionDays applies when nil
+	Rules repository.RetentionRuleRepository
+
+	// Archived logs older than PurgeAfterDays are moved to cold storage bundles after each archival
+	// Purging is off when PurgeAfterDays is 0 or Bundles is nil
+	PurgeAfterDays int
+	Bundles        repository.LogBundleRepository
 }
 
 // NewRetentionScheduler creates a new retention scheduler instance
@@ -36,7 +47,10 @@ func NewRetentionScheduler(repo repository.LogRepository, config RetentionSchedu
 
 	return &RetentionScheduler{
 		repo:           repo,
+		bundles:        config.Bundles,
+		rules:          config.Rules,
 		retentionDays:  config.RetentionDays,
+		purgeAfterDays: config.PurgeAfterDays,
 		stopChan:       make(chan bool),
 		runImmediately: config.RunImmediately,
 	}
@@ -44,7 +58,7 @@ func NewRetentionScheduler(repo repository.LogRepository, config RetentionSchedu
 
 // Start begins the retention scheduler in the background
 func (rs *RetentionScheduler) Start(ctx context.Context) {
-	log.Printf("Starting retention scheduler (retention period: %d days)", rs.retentionDays)
+	slog.Info("Starting retention scheduler", "retentionDays", rs.retentionDays, "purgeAfterDays", rs.purgeAfterDays)
 
 	// If configured, run immediately for testing purposes
 	if rs.runImmediately {
@@ -72,18 +86,18 @@ func (rs *RetentionScheduler) Start(ctx context.Context) {
 			case <-rs.ticker.C:
 				rs.runArchival(ctx)
 			case <-rs.stopChan:
-				log.Println("Retention scheduler stopped")
+				slog.Info("Retention scheduler stopped")
 				return
 			}
 		}
 	}()
 
-	log.Printf("Retention scheduler will run daily at midnight (next run in %v)", timeUntilMidnight)
+	slog.Info("Retention scheduler will run daily at midnight", "nextRunIn", timeUntilMidnight.String())
 }
 
 // Stop gracefully stops the retention scheduler
 func (rs *RetentionScheduler) Stop() {
-	log.Println("Stopping retention scheduler...")
+	slog.Info("Stopping retention scheduler")
 
 	if rs.ticker != nil {
 		rs.ticker.Stop()
@@ -94,19 +108,12 @@ func (rs *RetentionScheduler) Stop() {
 
 // runArchival performs the log archival process
 func (rs *RetentionScheduler) runArchival(ctx context.Context) {
-	log.Println("=== Starting automatic log archival ===")
+	slog.Info("Starting automatic log archival")
 	startTime := time.Now().UTC()
 
-	// Calculate cutoff date
-	cutoffDate := time.Now().UTC().AddDate(0, 0, -rs.retentionDays)
-	cutoffDateStr := cutoffDate.Format("2006-01-02")
-
-	log.Printf("Archiving logs older than %s (%d days)", cutoffDateStr, rs.retentionDays)
-
-	// Archive old logs
-	archivedCount, err := rs.repo.ArchiveLogsOlderThan(ctx, cutoffDateStr)
+	report, err := rs.ApplyRetention(ctx, false)
 	if err != nil {
-		log.Printf("ERROR: Failed to archive logs: %v", err)
+		slog.Error("Failed to archive logs", "error", err)
 
 		// Log the system error
 		errorLog := &models.SystemLog{
@@ -119,20 +126,24 @@ func (rs *RetentionScheduler) runArchival(ctx context.Context) {
 			Metadata: map[string]interface{}{
 				"message":                    fmt.Sprintf("Automatic log archival failed: %v", err),
 				sub_model.META_ERROR_MESSAGE: err.Error(),
-				"cutoff_date":                cutoffDateStr,
 				"retention_days":             rs.retentionDays,
 			},
 		}
 
 		if createErr := rs.repo.CreateLog(ctx, errorLog); createErr != nil {
-			log.Printf("ERROR: Failed to log archival error: %v", createErr)
+			slog.Error("Failed to log archival error", "error", createErr)
 		}
 
 		return
 	}
 
 	duration := time.Since(startTime)
-	log.Printf("=== Log archival completed: %d logs archived in %v ===", archivedCount, duration)
+	slog.Info("Log archival completed", "archived", report.TotalArchived, "durationMs", duration.Milliseconds())
+
+	archivedByRule := map[string]interface{}{}
+	for _, result := range report.Rules {
+		archivedByRule[result.Rule.MatchKey()] = result.Archived
+	}
 
 	// Log the successful archival as a configuration change
 	successLog := &models.SystemLog{
@@ -143,32 +154,117 @@ func (rs *RetentionScheduler) runArchival(ctx context.Context) {
 		Category:     "System",
 		Severity:     sub_model.SEVERITY_INFO,
 		Metadata: map[string]interface{}{
-			"message":        fmt.Sprintf("Automatic log archival completed: %d logs archived", archivedCount),
-			"archived_count": archivedCount,
-			"cutoff_date":    cutoffDateStr,
-			"retention_days": rs.retentionDays,
-			"duration_ms":    duration.Milliseconds(),
+			"message":          fmt.Sprintf("Automatic log archival completed: %d logs archived", report.TotalArchived),
+			"archived_count":   report.TotalArchived,
+			"archived_by_rule": archivedByRule,
+			"retention_days":   rs.retentionDays,
+			"duration_ms":      duration.Milliseconds(),
 		},
 	}
 
 	if err := rs.repo.CreateLog(ctx, successLog); err != nil {
-		log.Printf("ERROR: Failed to log archival success: %v", err)
+		slog.Error("Failed to log archival success", "error", err)
+	}
+
+	if rs.bundles != nil && rs.purgeAfterDays > 0 {
+		rs.runPurge(ctx)
 	}
 }
 
+// runPurge moves logs archived longer than the purge period to cold storage
+func (rs *RetentionScheduler) runPurge(ctx context.Context) {
+	archivedBefore := time.Now().UTC().AddDate(0, 0, -rs.purgeAfterDays)
+	slog.Info("Purging archived logs to cold storage", "archivedBefore", archivedBefore, "purgeAfterDays", rs.purgeAfterDays)
+
+	bundles, err := PurgeArchivedLogs(ctx, rs.repo, rs.bundles, archivedBefore)
+
+	// Every bundle written is recorded, also when a later one failed
+	for _, bundle := range bundles {
+		purgeLog := &models.SystemLog{
+			ID:           uuid.New().String(),
+			Type:         sub_model.LOGS_PURGED,
+			TimeDetected: time.Now().UTC(),
+			LastUpdated:  time.Now().UTC(),
+			Category:     sub_model.GetLogTypeCategory(sub_model.LOGS_PURGED),
+			Severity:     sub_model.SEVERITY_INFO,
+			Metadata: map[string]interface{}{
+				sub_model.META_BUNDLE_ID:       bundle.ID,
+				sub_model.META_BUNDLE_STORE:    bundle.Store,
+				sub_model.META_BUNDLE_SHA256:   bundle.SHA256,
+				sub_model.META_RECORD_COUNT:    bundle.LogCount,
+				sub_model.META_ARCHIVED_BEFORE: archivedBefore.Format(time.RFC3339),
+				"purge_after_days":             rs.purgeAfterDays,
+			},
+		}
+		if err := rs.repo.CreateLog(ctx, purgeLog); err != nil {
+			slog.Error("Failed to log purge of bundle", "bundleId", bundle.ID, "error", err)
+		}
+	}
+
+	if err != nil {
+		slog.Error("Failed to purge archived logs", "error", err)
+
+		errorLog := &models.SystemLog{
+			ID:           uuid.New().String(),
+			Type:         sub_model.SYSTEM_ERROR,
+			TimeDetected: time.Now().UTC(),
+			LastUpdated:  time.Now().UTC(),
+			Category:     "System",
+			Severity:     sub_model.SEVERITY_ERROR,
+			Metadata: map[string]interface{}{
+				"message":                      fmt.Sprintf("Automatic log purge failed: %v", err),
+				sub_model.META_ERROR_MESSAGE:   err.Error(),
+				sub_model.META_ARCHIVED_BEFORE: archivedBefore.Format(time.RFC3339),
+				"bundles_written":              len(bundles),
+			},
+		}
+		if createErr := rs.repo.CreateLog(ctx, errorLog); createErr != nil {
+			slog.Error("Failed to log purge error", "error", createErr)
+		}
+		return
+	}
+
+	slog.Info("Log purge completed", "bundles", len(bundles))
+}
+
+// RetentionDays returns the retention for logs no rule matches
+func (rs *RetentionScheduler) RetentionDays() int {
+	return rs.retentionDays
+}
+
+// ApplyRetention archives the logs past the retention of the rule they follow, a dry run only reports them
+// Logs no rule matches keep the default retention, unless a rule without category, type and severity replaces it
+func (rs *RetentionScheduler) ApplyRetention(ctx context.Context, dryRun bool) (*repository.RetentionReport, error) {
+	rules := []*models.RetentionRule{}
+	if rs.rules != nil {
+		stored, err := rs.rules.ListRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rules = stored
+	}
+
+	hasDefault := false
+	for _, rule := range rules {
+		hasDefault = hasDefault || rule.Specificity() == 0
+	}
+	if !hasDefault {
+		rules = append(rules, &models.RetentionRule{RetentionDays: rs.retentionDays})
+	}
+
+	return rs.repo.ApplyRetentionRules(ctx, rules, time.Now().UTC(), dryRun)
+}
+
 // RunManualArchival allows manual triggering of the archival process (useful for testing or admin actions)
 func (rs *RetentionScheduler) RunManualArchival(ctx context.Context) (int, error) {
-	log.Println("Manual log archival triggered")
-
-	cutoffDate := time.Now().UTC().AddDate(0, 0, -rs.retentionDays)
-	cutoffDateStr := cutoffDate.Format("2006-01-02")
-	archivedCount, err := rs.repo.ArchiveLogsOlderThan(ctx, cutoffDateStr)
+	slog.InfoContext(ctx, "Manual log archival triggered")
 
+	report, err := rs.ApplyRetention(ctx, false)
 	if err != nil {
-		log.Printf("ERROR: Manual archival failed: %v", err)
+		slog.ErrorContext(ctx, "Manual archival failed", "error", err)
 		return 0, err
 	}
 
-	log.Printf("Manual archival completed: %d logs archived", archivedCount)
-	return archivedCount, nil
+	slog.InfoContext(ctx, "Manual archival completed", "archived", report.TotalArchived)
+	return report.TotalArchived, nil
 }
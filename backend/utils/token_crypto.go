@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	sub_model "sheduling-server/models/sub_models"
+)
+
+// ErrNoTokenKey is returned when OAuth tokens need encrypting but no key is configured
+var ErrNoTokenKey = errors.New("no token encryption key configured")
+
+// tokenKeys holds the key encryption keys by ID, activeTokenKeyID is used for new records
+var (
+	tokenKeys        = map[string][]byte{}
+	activeTokenKeyID string
+)
+
+// oauthTokenSecrets is the plaintext stored inside an EncryptedSecret
+type oauthTokenSecrets struct {
+	AccessToken  string `json:"a"`
+	RefreshToken string `json:"r"`
+}
+
+// InitTokenEncryption loads the OAuth token encryption keys from the environment
+//
+//   - TOKEN_ENCRYPTION_KEYS: comma separated "keyId:base64Key" pairs, keys must be 32 bytes
+//     (keep old keys listed after rotating so existing records can still be decrypted)
+//   - TOKEN_ENCRYPTION_KEY_ID: key used for new records, defaults to the last key listed
+func InitTokenEncryption() error {
+	keys := map[string][]byte{}
+	lastKeyID := ""
+
+	for _, entry := range strings.Split(os.Getenv("TOKEN_ENCRYPTION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyID, encoded, found := strings.Cut(entry, ":")
+		if !found || keyID == "" {
+			return fmt.Errorf("invalid TOKEN_ENCRYPTION_KEYS entry, expected keyId:base64Key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid base64 for token key %s: %v", keyID, err)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("token key %s must be 32 bytes, got %d", keyID, len(key))
+		}
+
+		keys[keyID] = key
+		lastKeyID = keyID
+	}
+
+	activeKeyID := os.Getenv("TOKEN_ENCRYPTION_KEY_ID")
+	if activeKeyID == "" {
+		activeKeyID = lastKeyID
+	}
+	if activeKeyID != "" {
+		if _, ok := keys[activeKeyID]; !ok {
+			return fmt.Errorf("TOKEN_ENCRYPTION_KEY_ID %s is not in TOKEN_ENCRYPTION_KEYS", activeKeyID)
+		}
+	}
+
+	tokenKeys = keys
+	activeTokenKeyID = activeKeyID
+
+	if activeTokenKeyID == "" {
+		slog.Warn("TOKEN_ENCRYPTION_KEYS not set, OAuth access/refresh tokens will not be stored")
+	} else {
+		slog.Info("OAuth token encryption enabled", "activeKey", activeTokenKeyID, "keys", len(tokenKeys))
+	}
+
+	return nil
+}
+
+// ActiveTokenKeyID returns the key ID used for newly encrypted tokens
+func ActiveTokenKeyID() string {
+	return activeTokenKeyID
+}
+
+// SealOAuthToken encrypts the plaintext tokens into Secrets under the active key and clears them
+// Tokens without plaintext are left untouched so existing Secrets are kept
+func SealOAuthToken(token *sub_model.OAuthToken) error {
+	if token.AccessToken == "" && token.RefreshToken == "" {
+		return nil
+	}
+
+	plaintext, err := json.Marshal(oauthTokenSecrets{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	secret, err := encryptSecret(plaintext)
+	if err != nil {
+		return err
+	}
+
+	token.Secrets = secret
+	token.AccessToken = ""
+	token.RefreshToken = ""
+	return nil
+}
+
+// OpenOAuthToken decrypts Secrets into the plaintext token fields
+// Records that were stored before encryption already have plaintext and are returned as is
+func OpenOAuthToken(token *sub_model.OAuthToken) error {
+	if token.Secrets == nil {
+		return nil
+	}
+
+	plaintext, err := decryptSecret(token.Secrets)
+	if err != nil {
+		return err
+	}
+
+	var secrets oauthTokenSecrets
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("failed to decode token secrets: %v", err)
+	}
+
+	token.AccessToken = secrets.AccessToken
+	token.RefreshToken = secrets.RefreshToken
+	return nil
+}
+
+// RewrapSecret re-encrypts the data key under the active key without touching the ciphertext
+// Returns false if the secret already uses the active key
+func RewrapSecret(secret *sub_model.EncryptedSecret) (bool, error) {
+	if activeTokenKeyID == "" {
+		return false, ErrNoTokenKey
+	}
+	if secret.KeyID == activeTokenKeyID {
+		return false, nil
+	}
+
+	dataKey, err := unwrapDataKey(secret)
+	if err != nil {
+		return false, err
+	}
+
+	keyNonce, wrappedKey, err := sealAESGCM(tokenKeys[activeTokenKeyID], dataKey, []byte(activeTokenKeyID))
+	if err != nil {
+		return false, err
+	}
+
+	secret.KeyID = activeTokenKeyID
+	secret.KeyNonce = keyNonce
+	secret.DataKey = wrappedKey
+	return true, nil
+}
+
+// encryptSecret encrypts plaintext with a fresh data key wrapped by the active key
+func encryptSecret(plaintext []byte) (*sub_model.EncryptedSecret, error) {
+	if activeTokenKeyID == "" {
+		return nil, ErrNoTokenKey
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+
+	nonce, ciphertext, err := sealAESGCM(dataKey, plaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key ID is bound as additional data so a wrapped key can't be relabeled
+	keyNonce, wrappedKey, err := sealAESGCM(tokenKeys[activeTokenKeyID], dataKey, []byte(activeTokenKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sub_model.EncryptedSecret{
+		KeyID:      activeTokenKeyID,
+		DataKey:    wrappedKey,
+		KeyNonce:   keyNonce,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// decryptSecret unwraps the data key and decrypts the ciphertext
+func decryptSecret(secret *sub_model.EncryptedSecret) ([]byte, error) {
+	dataKey, err := unwrapDataKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := openAESGCM(dataKey, secret.Nonce, secret.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+	return plaintext, nil
+}
+
+func unwrapDataKey(secret *sub_model.EncryptedSecret) ([]byte, error) {
+	kek, ok := tokenKeys[secret.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("token encryption key %s is not configured", secret.KeyID)
+	}
+
+	dataKey, err := openAESGCM(kek, secret.KeyNonce, secret.DataKey, []byte(secret.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	return dataKey, nil
+}
+
+func sealAESGCM(key, plaintext, additionalData []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func openAESGCM(key, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
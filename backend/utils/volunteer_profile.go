@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// Phone numbers can have 7 to 15 digits (E.164)
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// NormalizeStudentID returns the key a student or employee ID is unique by
+// Case, spaces and punctuation are ignored, so "2021-00123" and "202100123" are the same ID
+func NormalizeStudentID(id string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(id) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NormalizeEmail trims and lowercases an email and checks it is a plain address
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", errors.New("invalid email address")
+	}
+	return email, nil
+}
+
+// NormalizePhone removes formatting from a phone number, keeping a leading +
+// Spaces, dashes, dots and parentheses are allowed as separators
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return "", nil
+	}
+
+	var b strings.Builder
+	digits := 0
+	for i, r := range phone {
+		switch {
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+			digits++
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", errors.New("invalid phone number")
+		}
+	}
+	if digits < minPhoneDigits || digits > maxPhoneDigits {
+		return "", errors.New("invalid phone number")
+	}
+	return b.String(), nil
+}
+
+// NormalizeLabels trims skills or tags and drops empty and repeated ones, keeping the first spelling
+func NormalizeLabels(labels []string) []string {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		label = strings.Join(strings.Fields(label), " ")
+		key := strings.ToLower(label)
+		if label == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, label)
+	}
+	return result
+}